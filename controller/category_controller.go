@@ -0,0 +1,158 @@
+package controller
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/malikfajr/eq-store/entity"
+	"github.com/malikfajr/eq-store/exception"
+	"github.com/malikfajr/eq-store/service"
+)
+
+type CategoryController interface {
+	Create(w http.ResponseWriter, r *http.Request)
+	GetAll(w http.ResponseWriter, r *http.Request)
+	GetOne(w http.ResponseWriter, r *http.Request)
+	Update(w http.ResponseWriter, r *http.Request)
+	Delete(w http.ResponseWriter, r *http.Request)
+}
+
+type categoryController struct {
+	categoryService service.CategoryService
+	validate        *validator.Validate
+}
+
+func NewCategoryController(validate *validator.Validate, service service.CategoryService) CategoryController {
+	return &categoryController{
+		validate:        validate,
+		categoryService: service,
+	}
+}
+
+func (c *categoryController) Create(w http.ResponseWriter, r *http.Request) {
+	body := &entity.CategoryInsertUpdateRequest{}
+
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		e := exception.NewBadRequest("request doesn’t pass validation")
+		e.Send(w)
+		return
+	}
+
+	if err := c.validate.Struct(body); err != nil {
+		e := exception.NewBadRequest("request doesn’t pass validation")
+		e.Send(w)
+		return
+	}
+
+	category, err := c.categoryService.Create(r.Context(), body)
+	if err != nil {
+		if e, ok := err.(*exception.CustomError); ok {
+			e.Send(w)
+			return
+		}
+		panic(err)
+	}
+
+	success := &successResponse{
+		Message: "success",
+		Data:    category,
+	}
+
+	success.Send(w, http.StatusCreated)
+}
+
+func (c *categoryController) GetAll(w http.ResponseWriter, r *http.Request) {
+	page, err := parsePageParams(r)
+	if err != nil {
+		err.(*exception.CustomError).Send(w)
+		return
+	}
+
+	categories, meta, err := c.categoryService.FindAll(r.Context(), &page)
+	if err != nil {
+		if e, ok := err.(*exception.CustomError); ok {
+			e.Send(w)
+			return
+		}
+		panic(err)
+	}
+
+	success := &successResponse{
+		Message: "success",
+		Data:    categories,
+		Meta:    meta,
+	}
+
+	success.Send(w, http.StatusOK)
+}
+
+func (c *categoryController) GetOne(w http.ResponseWriter, r *http.Request) {
+	category, err := c.categoryService.FindOne(r.Context(), r.PathValue("id"))
+	if err != nil {
+		if e, ok := err.(*exception.CustomError); ok {
+			e.Send(w)
+			return
+		}
+		panic(err)
+	}
+
+	success := &successResponse{
+		Message: "success",
+		Data:    category,
+	}
+
+	success.Send(w, http.StatusOK)
+}
+
+func (c *categoryController) Update(w http.ResponseWriter, r *http.Request) {
+	ID := r.PathValue("id")
+	body := &entity.CategoryInsertUpdateRequest{}
+
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		e := exception.NewBadRequest("request doesn’t pass validation")
+		e.Send(w)
+		return
+	}
+
+	if err := c.validate.Struct(body); err != nil {
+		e := exception.NewBadRequest("request doesn’t pass validation")
+		e.Send(w)
+		return
+	}
+
+	category, err := c.categoryService.Update(r.Context(), ID, body)
+	if err != nil {
+		if e, ok := err.(*exception.CustomError); ok {
+			e.Send(w)
+			return
+		}
+		panic(err)
+	}
+
+	success := &successResponse{
+		Message: "success",
+		Data:    category,
+	}
+
+	success.Send(w, http.StatusOK)
+}
+
+func (c *categoryController) Delete(w http.ResponseWriter, r *http.Request) {
+	ID := r.PathValue("id")
+
+	if err := c.categoryService.Delete(r.Context(), ID); err != nil {
+		if e, ok := err.(*exception.CustomError); ok {
+			e.Send(w)
+			return
+		}
+		panic(err)
+	}
+
+	success := &successResponse{
+		Message: "Delete category success",
+		Data:    []string{},
+	}
+
+	success.Send(w, http.StatusOK)
+}
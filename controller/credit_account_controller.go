@@ -0,0 +1,152 @@
+package controller
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/malikfajr/eq-store/entity"
+	"github.com/malikfajr/eq-store/exception"
+	"github.com/malikfajr/eq-store/middleware"
+	"github.com/malikfajr/eq-store/service"
+)
+
+type CreditAccountController interface {
+	Save(w http.ResponseWriter, r *http.Request)
+	GetOne(w http.ResponseWriter, r *http.Request)
+	Pay(w http.ResponseWriter, r *http.Request)
+	GetStatement(w http.ResponseWriter, r *http.Request)
+	Aging(w http.ResponseWriter, r *http.Request)
+}
+
+type creditAccountController struct {
+	creditAccountService service.CreditAccountService
+	validate             *validator.Validate
+}
+
+func NewCreditAccountController(validate *validator.Validate, service service.CreditAccountService) CreditAccountController {
+	return &creditAccountController{
+		validate:             validate,
+		creditAccountService: service,
+	}
+}
+
+// Save opens the credit account of a customer or changes its limit.
+func (c *creditAccountController) Save(w http.ResponseWriter, r *http.Request) {
+	body := &entity.CreditAccountRequest{}
+
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		e := exception.NewBadRequest("request doesn’t pass validation")
+		e.Send(w)
+		return
+	}
+
+	if err := c.validate.Struct(body); err != nil {
+		e := exception.NewBadRequest("request doesn’t pass validation")
+		e.Send(w)
+		return
+	}
+
+	account, err := c.creditAccountService.Save(r.Context(), middleware.GetStaffId(r.Context()), r.PathValue("id"), body)
+	c.sendAccount(w, account, nil, err)
+}
+
+func (c *creditAccountController) GetOne(w http.ResponseWriter, r *http.Request) {
+	params := &entity.AccountQueryParams{}
+
+	page, err := parsePageParams(r)
+	if err != nil {
+		err.(*exception.CustomError).Send(w)
+		return
+	}
+	params.PageParams = page
+
+	if createdAt := r.URL.Query().Get("createdAt"); createdAt == "asc" || createdAt == "desc" {
+		params.CreatedAt = createdAt
+	}
+
+	account, meta, err := c.creditAccountService.FindOne(r.Context(), r.PathValue("id"), params)
+	c.sendAccount(w, account, meta, err)
+}
+
+func (c *creditAccountController) Pay(w http.ResponseWriter, r *http.Request) {
+	body := &entity.AccountPaymentRequest{}
+
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		e := exception.NewBadRequest("request doesn’t pass validation")
+		e.Send(w)
+		return
+	}
+
+	if err := c.validate.Struct(body); err != nil {
+		e := exception.NewBadRequest("request doesn’t pass validation")
+		e.Send(w)
+		return
+	}
+
+	account, err := c.creditAccountService.Pay(r.Context(), middleware.GetStaffId(r.Context()), r.PathValue("id"), body)
+	c.sendAccount(w, account, nil, err)
+}
+
+// GetStatement returns the statement of a month, month=YYYY-MM, the current
+// month when left out.
+func (c *creditAccountController) GetStatement(w http.ResponseWriter, r *http.Request) {
+	month := r.URL.Query().Get("month")
+	if month == "" {
+		month = time.Now().UTC().Format("2006-01")
+	}
+
+	statement, err := c.creditAccountService.Statement(r.Context(), r.PathValue("id"), month)
+	if err != nil {
+		if e, ok := err.(*exception.CustomError); ok {
+			e.Send(w)
+			return
+		}
+		panic(err)
+	}
+
+	success := &successResponse{
+		Message: "success",
+		Data:    statement,
+	}
+
+	success.Send(w, http.StatusOK)
+}
+
+// Aging reports what every customer owes by the age of the charges, asOf is
+// an RFC3339 time and defaults to now.
+func (c *creditAccountController) Aging(w http.ResponseWriter, r *http.Request) {
+	asOf := time.Now().UTC()
+	if t, err := time.Parse(time.RFC3339, r.URL.Query().Get("asOf")); err == nil {
+		asOf = t.UTC()
+	}
+
+	success := &successResponse{
+		Message: "success",
+		Data:    c.creditAccountService.Aging(r.Context(), asOf),
+	}
+
+	success.Send(w, http.StatusOK)
+}
+
+func (c *creditAccountController) sendAccount(w http.ResponseWriter, account *entity.CreditAccount, meta *entity.PageMeta, err error) {
+	if err != nil {
+		if e, ok := err.(*exception.CustomError); ok {
+			e.Send(w)
+			return
+		}
+		panic(err)
+	}
+
+	success := &successResponse{
+		Message: "success",
+		Data:    account,
+	}
+
+	if meta != nil {
+		success.Meta = meta
+	}
+
+	success.Send(w, http.StatusOK)
+}
@@ -2,17 +2,33 @@ package controller
 
 import (
 	"encoding/json"
+	"io"
 	"net/http"
+	"strconv"
 
 	"github.com/go-playground/validator/v10"
 	"github.com/malikfajr/eq-store/entity"
 	"github.com/malikfajr/eq-store/exception"
+	"github.com/malikfajr/eq-store/middleware"
+	"github.com/malikfajr/eq-store/pkg"
 	"github.com/malikfajr/eq-store/service"
 )
 
 type CustomerController interface {
 	GetAll(w http.ResponseWriter, r *http.Request)
 	Create(w http.ResponseWriter, r *http.Request)
+	GetOne(w http.ResponseWriter, r *http.Request)
+	Update(w http.ResponseWriter, r *http.Request)
+	Patch(w http.ResponseWriter, r *http.Request)
+	Delete(w http.ResponseWriter, r *http.Request)
+	GetTransactions(w http.ResponseWriter, r *http.Request)
+	GetSummary(w http.ResponseWriter, r *http.Request)
+	GetDuplicates(w http.ResponseWriter, r *http.Request)
+	Merge(w http.ResponseWriter, r *http.Request)
+	Export(w http.ResponseWriter, r *http.Request)
+	Erase(w http.ResponseWriter, r *http.Request)
+	GetTags(w http.ResponseWriter, r *http.Request)
+	SetTags(w http.ResponseWriter, r *http.Request)
 }
 
 type customerController struct {
@@ -71,11 +87,380 @@ func (c *customerController) GetAll(w http.ResponseWriter, r *http.Request) {
 		params.PhoneNumber = phone
 	}
 
-	customers := c.customerService.FindMany(r.Context(), params)
+	if createdAt := r.URL.Query().Get("createdAt"); createdAt == "asc" || createdAt == "desc" {
+		params.CreatedAt = createdAt
+	}
+
+	if tag := r.URL.Query().Get("tag"); tag != "" {
+		params.Tag = tag
+	}
+
+	if segmentId := r.URL.Query().Get("segmentId"); segmentId != "" {
+		params.SegmentId = segmentId
+	}
+
+	// sortBy=name lists customers alphabetically, order=desc reverses it
+	order := r.URL.Query().Get("order")
+	switch r.URL.Query().Get("sortBy") {
+	case "name":
+		params.SortName = "asc"
+		if order == "desc" {
+			params.SortName = "desc"
+		}
+	case "createdAt":
+		if order == "asc" || order == "desc" {
+			params.CreatedAt = order
+		}
+	}
+
+	page, err := parsePageParams(r)
+	if err != nil {
+		err.(*exception.CustomError).Send(w)
+		return
+	}
+	params.PageParams = page
+
+	customers, meta, err := c.customerService.FindMany(r.Context(), params)
+	if err != nil {
+		if e, ok := err.(*exception.CustomError); ok {
+			e.Send(w)
+			return
+		}
+		panic(err)
+	}
 
 	success := &successResponse{
 		Message: "success",
 		Data:    customers,
+		Meta:    meta,
+	}
+
+	success.Send(w, http.StatusOK)
+}
+
+func (c *customerController) GetOne(w http.ResponseWriter, r *http.Request) {
+	customer, err := c.customerService.FindOne(r.Context(), r.PathValue("id"))
+	if err != nil {
+		if e, ok := err.(*exception.CustomError); ok {
+			e.Send(w)
+			return
+		}
+		panic(err)
+	}
+
+	success := &successResponse{
+		Message: "success",
+		Data:    customer,
+	}
+
+	success.Send(w, http.StatusOK)
+}
+
+func (c *customerController) Update(w http.ResponseWriter, r *http.Request) {
+	body := &entity.CustomerInsertUpdateRequest{}
+
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		e := exception.NewBadRequest("request doesn’t pass validation")
+		e.Send(w)
+		return
+	}
+
+	c.update(w, r, body)
+}
+
+// Patch applies a JSON Merge Patch to the customer, fields missing from the
+// patch keep their value.
+func (c *customerController) Patch(w http.ResponseWriter, r *http.Request) {
+	patch, err := io.ReadAll(r.Body)
+	if err != nil {
+		e := exception.NewBadRequest("request doesn’t pass validation")
+		e.Send(w)
+		return
+	}
+
+	customer, err := c.customerService.FindOne(r.Context(), r.PathValue("id"))
+	if err != nil {
+		if e, ok := err.(*exception.CustomError); ok {
+			e.Send(w)
+			return
+		}
+		panic(err)
+	}
+
+	current, err := json.Marshal(entity.CustomerInsertUpdateRequest{
+		Name:        customer.Name,
+		PhoneNumber: customer.PhoneNumber,
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	merged, err := pkg.MergePatch(current, patch)
+	if err != nil {
+		e := exception.NewBadRequest("request doesn’t pass validation")
+		e.Send(w)
+		return
+	}
+
+	body := &entity.CustomerInsertUpdateRequest{}
+	if err := json.Unmarshal(merged, body); err != nil {
+		e := exception.NewBadRequest("request doesn’t pass validation")
+		e.Send(w)
+		return
+	}
+
+	c.update(w, r, body)
+}
+
+func (c *customerController) update(w http.ResponseWriter, r *http.Request, body *entity.CustomerInsertUpdateRequest) {
+	if err := c.validate.Struct(body); err != nil {
+		e := exception.NewBadRequest("request doesn’t pass validation")
+		e.Send(w)
+		return
+	}
+
+	customer, err := c.customerService.Update(r.Context(), r.PathValue("id"), body)
+	if err != nil {
+		if e, ok := err.(*exception.CustomError); ok {
+			e.Send(w)
+			return
+		}
+		panic(err)
+	}
+
+	success := &successResponse{
+		Message: "success",
+		Data:    customer,
+	}
+
+	success.Send(w, http.StatusOK)
+}
+
+func (c *customerController) Delete(w http.ResponseWriter, r *http.Request) {
+	if err := c.customerService.Delete(r.Context(), r.PathValue("id")); err != nil {
+		if e, ok := err.(*exception.CustomError); ok {
+			e.Send(w)
+			return
+		}
+		panic(err)
+	}
+
+	success := &successResponse{
+		Message: "Delete customer success",
+		Data:    []string{},
+	}
+
+	success.Send(w, http.StatusOK)
+}
+
+func (c *customerController) GetTransactions(w http.ResponseWriter, r *http.Request) {
+	params := &entity.TransactionQueryParams{}
+
+	page, err := parsePageParams(r)
+	if err != nil {
+		err.(*exception.CustomError).Send(w)
+		return
+	}
+	params.PageParams = page
+
+	if createdAt := r.URL.Query().Get("createdAt"); createdAt == "asc" || createdAt == "desc" {
+		params.CreatedAt = createdAt
+	}
+
+	transactions, meta, err := c.customerService.FindTransactions(r.Context(), r.PathValue("id"), params)
+	if err != nil {
+		if e, ok := err.(*exception.CustomError); ok {
+			e.Send(w)
+			return
+		}
+		panic(err)
+	}
+
+	success := &successResponse{
+		Message: "success",
+		Data:    transactions,
+		Meta:    meta,
+	}
+
+	success.Send(w, http.StatusOK)
+}
+
+func (c *customerController) GetSummary(w http.ResponseWriter, r *http.Request) {
+	summary, err := c.customerService.Summary(r.Context(), r.PathValue("id"))
+	if err != nil {
+		if e, ok := err.(*exception.CustomError); ok {
+			e.Send(w)
+			return
+		}
+		panic(err)
+	}
+
+	success := &successResponse{
+		Message: "success",
+		Data:    summary,
+	}
+
+	success.Send(w, http.StatusOK)
+}
+
+// GetDuplicates lists pairs of customers that may be the same person, they
+// share a normalized phone number or their names are at least minSimilarity
+// (default 0.6) alike.
+func (c *customerController) GetDuplicates(w http.ResponseWriter, r *http.Request) {
+	params := &entity.DuplicateQueryParams{MinSimilarity: 0.6}
+
+	if similarity := r.URL.Query().Get("minSimilarity"); similarity != "" {
+		n, err := strconv.ParseFloat(similarity, 64)
+		if err != nil || n < 0.3 || n > 1 {
+			e := exception.NewBadRequest("minSimilarity must be between 0.3 and 1")
+			e.Send(w)
+			return
+		}
+		params.MinSimilarity = n
+	}
+
+	page, err := parsePageParams(r)
+	if err != nil {
+		err.(*exception.CustomError).Send(w)
+		return
+	}
+	params.PageParams = page
+
+	duplicates, meta, err := c.customerService.FindDuplicates(r.Context(), params)
+	if err != nil {
+		if e, ok := err.(*exception.CustomError); ok {
+			e.Send(w)
+			return
+		}
+		panic(err)
+	}
+
+	success := &successResponse{
+		Message: "success",
+		Data:    duplicates,
+		Meta:    meta,
+	}
+
+	success.Send(w, http.StatusOK)
+}
+
+// Merge folds the duplicates in the body into customer id.
+func (c *customerController) Merge(w http.ResponseWriter, r *http.Request) {
+	body := &entity.CustomerMergeRequest{}
+
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		e := exception.NewBadRequest("request doesn’t pass validation")
+		e.Send(w)
+		return
+	}
+
+	if err := c.validate.Struct(body); err != nil {
+		e := exception.NewBadRequest("request doesn’t pass validation")
+		e.Send(w)
+		return
+	}
+
+	merges, err := c.customerService.Merge(r.Context(), middleware.GetStaffId(r.Context()), r.PathValue("id"), body)
+	if err != nil {
+		if e, ok := err.(*exception.CustomError); ok {
+			e.Send(w)
+			return
+		}
+		panic(err)
+	}
+
+	success := &successResponse{
+		Message: "success",
+		Data:    merges,
+	}
+
+	success.Send(w, http.StatusOK)
+}
+
+// Export sends everything stored about a customer as a JSON file.
+func (c *customerController) Export(w http.ResponseWriter, r *http.Request) {
+	export, err := c.customerService.Export(r.Context(), middleware.GetStaffId(r.Context()), r.PathValue("id"))
+	if err != nil {
+		if e, ok := err.(*exception.CustomError); ok {
+			e.Send(w)
+			return
+		}
+		panic(err)
+	}
+
+	success := &successResponse{
+		Message: "success",
+		Data:    export,
+	}
+
+	w.Header().Set("Content-Disposition", `attachment; filename="customer-`+export.Customer.UserId+`.json"`)
+	success.Send(w, http.StatusOK)
+}
+
+func (c *customerController) Erase(w http.ResponseWriter, r *http.Request) {
+	err := c.customerService.Erase(r.Context(), middleware.GetStaffId(r.Context()), r.PathValue("id"))
+	if err != nil {
+		if e, ok := err.(*exception.CustomError); ok {
+			e.Send(w)
+			return
+		}
+		panic(err)
+	}
+
+	success := &successResponse{
+		Message: "Erase customer success",
+		Data:    []string{},
+	}
+
+	success.Send(w, http.StatusOK)
+}
+
+func (c *customerController) GetTags(w http.ResponseWriter, r *http.Request) {
+	tags, err := c.customerService.FindTags(r.Context(), r.PathValue("id"))
+	if err != nil {
+		if e, ok := err.(*exception.CustomError); ok {
+			e.Send(w)
+			return
+		}
+		panic(err)
+	}
+
+	success := &successResponse{
+		Message: "success",
+		Data:    tags,
+	}
+
+	success.Send(w, http.StatusOK)
+}
+
+// SetTags replaces the tags of a customer with the ones in the body.
+func (c *customerController) SetTags(w http.ResponseWriter, r *http.Request) {
+	body := &entity.CustomerTagsRequest{}
+
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		e := exception.NewBadRequest("request doesn’t pass validation")
+		e.Send(w)
+		return
+	}
+
+	if err := c.validate.Struct(body); err != nil {
+		e := exception.NewBadRequest("request doesn’t pass validation")
+		e.Send(w)
+		return
+	}
+
+	tags, err := c.customerService.SetTags(r.Context(), r.PathValue("id"), body)
+	if err != nil {
+		if e, ok := err.(*exception.CustomError); ok {
+			e.Send(w)
+			return
+		}
+		panic(err)
+	}
+
+	success := &successResponse{
+		Message: "success",
+		Data:    tags,
 	}
 
 	success.Send(w, http.StatusOK)
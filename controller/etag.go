@@ -0,0 +1,35 @@
+package controller
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/malikfajr/eq-store/exception"
+)
+
+// setETag sends a row version as the entity tag of the response.
+func setETag(w http.ResponseWriter, version int) {
+	w.Header().Set("ETag", `"`+strconv.Itoa(version)+`"`)
+	w.Header().Set("Access-Control-Expose-Headers", "ETag")
+}
+
+// parseIfMatch returns the version required by the If-Match header, or nil when
+// the header is missing or "*". Weak tags are accepted, only the first tag of
+// a list is used.
+func parseIfMatch(r *http.Request) (*int, error) {
+	header := strings.TrimSpace(r.Header.Get("If-Match"))
+	if header == "" || header == "*" {
+		return nil, nil
+	}
+
+	tag := strings.TrimSpace(strings.Split(header, ",")[0])
+	tag = strings.Trim(strings.TrimPrefix(tag, "W/"), `"`)
+
+	version, err := strconv.Atoi(tag)
+	if err != nil {
+		return nil, exception.NewPreconditionFailed("If-Match does not match the current version")
+	}
+
+	return &version, nil
+}
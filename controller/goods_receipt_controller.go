@@ -0,0 +1,112 @@
+package controller
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/malikfajr/eq-store/entity"
+	"github.com/malikfajr/eq-store/exception"
+	"github.com/malikfajr/eq-store/middleware"
+	"github.com/malikfajr/eq-store/service"
+)
+
+type GoodsReceiptController interface {
+	Create(w http.ResponseWriter, r *http.Request)
+	GetOne(w http.ResponseWriter, r *http.Request)
+	GetAll(w http.ResponseWriter, r *http.Request)
+}
+
+type goodsReceiptController struct {
+	goodsReceiptService service.GoodsReceiptService
+	validate            *validator.Validate
+}
+
+func NewGoodsReceiptController(validate *validator.Validate, service service.GoodsReceiptService) GoodsReceiptController {
+	return &goodsReceiptController{
+		validate:            validate,
+		goodsReceiptService: service,
+	}
+}
+
+func (g *goodsReceiptController) Create(w http.ResponseWriter, r *http.Request) {
+	body := &entity.GoodsReceiptInsertRequest{}
+
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		e := exception.NewBadRequest("request doesn’t pass validation")
+		e.Send(w)
+		return
+	}
+
+	if err := g.validate.Struct(body); err != nil {
+		e := exception.NewBadRequest("request doesn’t pass validation")
+		e.Send(w)
+		return
+	}
+
+	receipt, err := g.goodsReceiptService.Create(r.Context(), middleware.GetStaffId(r.Context()), body)
+	if err != nil {
+		if e, ok := err.(*exception.CustomError); ok {
+			e.Send(w)
+			return
+		}
+		panic(err)
+	}
+
+	success := &successResponse{
+		Message: "success",
+		Data:    receipt,
+	}
+
+	success.Send(w, http.StatusCreated)
+}
+
+func (g *goodsReceiptController) GetOne(w http.ResponseWriter, r *http.Request) {
+	receipt, err := g.goodsReceiptService.FindOne(r.Context(), r.PathValue("id"))
+	if err != nil {
+		if e, ok := err.(*exception.CustomError); ok {
+			e.Send(w)
+			return
+		}
+		panic(err)
+	}
+
+	success := &successResponse{
+		Message: "success",
+		Data:    receipt,
+	}
+
+	success.Send(w, http.StatusOK)
+}
+
+func (g *goodsReceiptController) GetAll(w http.ResponseWriter, r *http.Request) {
+	params := &entity.GoodsReceiptQueryParams{}
+
+	page, err := parsePageParams(r)
+	if err != nil {
+		err.(*exception.CustomError).Send(w)
+		return
+	}
+	params.PageParams = page
+
+	if locationId := r.URL.Query().Get("locationId"); g.validate.Var(locationId, "required,uuid") == nil {
+		params.LocationId = locationId
+	}
+
+	receipts, meta, err := g.goodsReceiptService.FindMany(r.Context(), params)
+	if err != nil {
+		if e, ok := err.(*exception.CustomError); ok {
+			e.Send(w)
+			return
+		}
+		panic(err)
+	}
+
+	success := &successResponse{
+		Message: "success",
+		Data:    receipts,
+		Meta:    meta,
+	}
+
+	success.Send(w, http.StatusOK)
+}
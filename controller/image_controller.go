@@ -0,0 +1,111 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/malikfajr/eq-store/exception"
+	"github.com/malikfajr/eq-store/middleware"
+	"github.com/malikfajr/eq-store/pkg"
+	"github.com/malikfajr/eq-store/service"
+)
+
+type ImageController interface {
+	Upload(w http.ResponseWriter, r *http.Request)
+	Serve(w http.ResponseWriter, r *http.Request)
+}
+
+type imageController struct {
+	imageService service.ImageService
+}
+
+func NewImageController(imageService service.ImageService) ImageController {
+	return &imageController{
+		imageService: imageService,
+	}
+}
+
+// Upload expects a multipart form with the file in the "image" field.
+func (i *imageController) Upload(w http.ResponseWriter, r *http.Request) {
+	version, err := parseIfMatch(r)
+	if err != nil {
+		err.(*exception.CustomError).Send(w)
+		return
+	}
+
+	tooLarge := exception.NewRequestEntityTooLarge(fmt.Sprintf("image must not be larger than %d MB", pkg.MaxImageSize>>20))
+
+	// leave room for the multipart headers around the file
+	r.Body = http.MaxBytesReader(w, r.Body, pkg.MaxImageSize+64<<10)
+
+	file, _, err := r.FormFile("image")
+	if err != nil {
+		var maxBytesError *http.MaxBytesError
+		if errors.As(err, &maxBytesError) {
+			tooLarge.Send(w)
+			return
+		}
+
+		e := exception.NewBadRequest("request doesn’t pass validation")
+		e.Send(w)
+		return
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(io.LimitReader(file, pkg.MaxImageSize+1))
+	if err != nil {
+		e := exception.NewBadRequest("request doesn’t pass validation")
+		e.Send(w)
+		return
+	}
+
+	if len(data) > pkg.MaxImageSize {
+		tooLarge.Send(w)
+		return
+	}
+
+	product, err := i.imageService.Upload(r.Context(), middleware.GetStaffId(r.Context()), r.PathValue("id"), data, version)
+	if err != nil {
+		if e, ok := err.(*exception.CustomError); ok {
+			e.Send(w)
+			return
+		}
+		panic(err)
+	}
+
+	setETag(w, product.Version)
+
+	success := &successResponse{
+		Message: "success",
+		Data:    product,
+	}
+
+	success.Send(w, http.StatusCreated)
+}
+
+func (i *imageController) Serve(w http.ResponseWriter, r *http.Request) {
+	body, contentType, err := i.imageService.Open(r.Context(), r.PathValue("key"))
+	if err != nil {
+		if e, ok := err.(*exception.CustomError); ok {
+			e.Send(w)
+			return
+		}
+		panic(err)
+	}
+	defer body.Close()
+
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+
+	// keys are never reused, a stored image does not change
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.WriteHeader(http.StatusOK)
+
+	io.Copy(w, body)
+}
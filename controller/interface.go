@@ -8,6 +8,7 @@ import (
 type successResponse struct {
 	Message string      `json:"message"`
 	Data    interface{} `json:"data"`
+	Meta    interface{} `json:"meta,omitempty"`
 }
 
 func (s *successResponse) Send(w http.ResponseWriter, statusCode int) {
@@ -0,0 +1,139 @@
+package controller
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/malikfajr/eq-store/entity"
+	"github.com/malikfajr/eq-store/exception"
+	"github.com/malikfajr/eq-store/service"
+)
+
+type LocationController interface {
+	Create(w http.ResponseWriter, r *http.Request)
+	GetAll(w http.ResponseWriter, r *http.Request)
+	Update(w http.ResponseWriter, r *http.Request)
+	Delete(w http.ResponseWriter, r *http.Request)
+}
+
+type locationController struct {
+	locationService service.LocationService
+	validate        *validator.Validate
+}
+
+func NewLocationController(validate *validator.Validate, service service.LocationService) LocationController {
+	return &locationController{
+		validate:        validate,
+		locationService: service,
+	}
+}
+
+func (l *locationController) Create(w http.ResponseWriter, r *http.Request) {
+	body := &entity.LocationInsertUpdateRequest{}
+
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		e := exception.NewBadRequest("request doesn’t pass validation")
+		e.Send(w)
+		return
+	}
+
+	if err := l.validate.Struct(body); err != nil {
+		e := exception.NewBadRequest("request doesn’t pass validation")
+		e.Send(w)
+		return
+	}
+
+	location, err := l.locationService.Create(r.Context(), body)
+	if err != nil {
+		if e, ok := err.(*exception.CustomError); ok {
+			e.Send(w)
+			return
+		}
+		panic(err)
+	}
+
+	success := &successResponse{
+		Message: "success",
+		Data:    location,
+	}
+
+	success.Send(w, http.StatusCreated)
+}
+
+func (l *locationController) GetAll(w http.ResponseWriter, r *http.Request) {
+	page, err := parsePageParams(r)
+	if err != nil {
+		err.(*exception.CustomError).Send(w)
+		return
+	}
+
+	locations, meta, err := l.locationService.FindAll(r.Context(), &page)
+	if err != nil {
+		if e, ok := err.(*exception.CustomError); ok {
+			e.Send(w)
+			return
+		}
+		panic(err)
+	}
+
+	success := &successResponse{
+		Message: "success",
+		Data:    locations,
+		Meta:    meta,
+	}
+
+	success.Send(w, http.StatusOK)
+}
+
+func (l *locationController) Update(w http.ResponseWriter, r *http.Request) {
+	ID := r.PathValue("id")
+	body := &entity.LocationInsertUpdateRequest{}
+
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		e := exception.NewBadRequest("request doesn’t pass validation")
+		e.Send(w)
+		return
+	}
+
+	if err := l.validate.Struct(body); err != nil {
+		e := exception.NewBadRequest("request doesn’t pass validation")
+		e.Send(w)
+		return
+	}
+
+	location, err := l.locationService.Update(r.Context(), ID, body)
+	if err != nil {
+		if e, ok := err.(*exception.CustomError); ok {
+			e.Send(w)
+			return
+		}
+		panic(err)
+	}
+
+	success := &successResponse{
+		Message: "success",
+		Data:    location,
+	}
+
+	success.Send(w, http.StatusOK)
+}
+
+func (l *locationController) Delete(w http.ResponseWriter, r *http.Request) {
+	ID := r.PathValue("id")
+
+	if err := l.locationService.Delete(r.Context(), ID); err != nil {
+		if e, ok := err.(*exception.CustomError); ok {
+			e.Send(w)
+			return
+		}
+		panic(err)
+	}
+
+	success := &successResponse{
+		Message: "Delete location success",
+		Data:    []string{},
+	}
+
+	success.Send(w, http.StatusOK)
+}
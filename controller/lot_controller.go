@@ -0,0 +1,90 @@
+package controller
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/malikfajr/eq-store/entity"
+	"github.com/malikfajr/eq-store/exception"
+	"github.com/malikfajr/eq-store/service"
+)
+
+type LotController interface {
+	GetByProduct(w http.ResponseWriter, r *http.Request)
+	GetExpiring(w http.ResponseWriter, r *http.Request)
+}
+
+type lotController struct {
+	lotService service.LotService
+	validate   *validator.Validate
+}
+
+func NewLotController(validate *validator.Validate, service service.LotService) LotController {
+	return &lotController{
+		validate:   validate,
+		lotService: service,
+	}
+}
+
+func (l *lotController) GetByProduct(w http.ResponseWriter, r *http.Request) {
+	lots, err := l.lotService.FindByProduct(r.Context(), r.PathValue("id"))
+	if err != nil {
+		if e, ok := err.(*exception.CustomError); ok {
+			e.Send(w)
+			return
+		}
+		panic(err)
+	}
+
+	success := &successResponse{
+		Message: "success",
+		Data:    lots,
+	}
+
+	success.Send(w, http.StatusOK)
+}
+
+// GetExpiring lists lots expiring within days (default 30), already expired
+// lots are listed first.
+func (l *lotController) GetExpiring(w http.ResponseWriter, r *http.Request) {
+	params := &entity.ExpiringLotQueryParams{Days: 30}
+
+	if days := r.URL.Query().Get("days"); days != "" {
+		n, err := strconv.Atoi(days)
+		if err != nil || n < 0 || n > 365 {
+			e := exception.NewBadRequest("days must be between 0 and 365")
+			e.Send(w)
+			return
+		}
+		params.Days = n
+	}
+
+	if locationId := r.URL.Query().Get("locationId"); l.validate.Var(locationId, "required,uuid") == nil {
+		params.LocationId = locationId
+	}
+
+	page, err := parsePageParams(r)
+	if err != nil {
+		err.(*exception.CustomError).Send(w)
+		return
+	}
+	params.PageParams = page
+
+	lots, meta, err := l.lotService.FindExpiring(r.Context(), params)
+	if err != nil {
+		if e, ok := err.(*exception.CustomError); ok {
+			e.Send(w)
+			return
+		}
+		panic(err)
+	}
+
+	success := &successResponse{
+		Message: "success",
+		Data:    lots,
+		Meta:    meta,
+	}
+
+	success.Send(w, http.StatusOK)
+}
@@ -0,0 +1,104 @@
+package controller
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/malikfajr/eq-store/entity"
+	"github.com/malikfajr/eq-store/exception"
+	"github.com/malikfajr/eq-store/service"
+)
+
+type LoyaltyController interface {
+	GetSettings(w http.ResponseWriter, r *http.Request)
+	UpdateSettings(w http.ResponseWriter, r *http.Request)
+	GetPoints(w http.ResponseWriter, r *http.Request)
+}
+
+type loyaltyController struct {
+	loyaltyService service.LoyaltyService
+	validate       *validator.Validate
+}
+
+func NewLoyaltyController(validate *validator.Validate, service service.LoyaltyService) LoyaltyController {
+	return &loyaltyController{
+		validate:       validate,
+		loyaltyService: service,
+	}
+}
+
+func (l *loyaltyController) GetSettings(w http.ResponseWriter, r *http.Request) {
+	success := &successResponse{
+		Message: "success",
+		Data:    l.loyaltyService.GetSettings(r.Context()),
+	}
+
+	success.Send(w, http.StatusOK)
+}
+
+func (l *loyaltyController) UpdateSettings(w http.ResponseWriter, r *http.Request) {
+	body := &entity.LoyaltySettings{}
+
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		e := exception.NewBadRequest("request doesn’t pass validation")
+		e.Send(w)
+		return
+	}
+
+	if err := l.validate.Struct(body); err != nil {
+		e := exception.NewBadRequest("request doesn’t pass validation")
+		e.Send(w)
+		return
+	}
+
+	settings, err := l.loyaltyService.UpdateSettings(r.Context(), body)
+	if err != nil {
+		if e, ok := err.(*exception.CustomError); ok {
+			e.Send(w)
+			return
+		}
+		panic(err)
+	}
+
+	success := &successResponse{
+		Message: "success",
+		Data:    settings,
+	}
+
+	success.Send(w, http.StatusOK)
+}
+
+// GetPoints returns the points balance of a customer, the lots it is made of
+// and a page of the ledger, newest first.
+func (l *loyaltyController) GetPoints(w http.ResponseWriter, r *http.Request) {
+	params := &entity.PointQueryParams{}
+
+	page, err := parsePageParams(r)
+	if err != nil {
+		err.(*exception.CustomError).Send(w)
+		return
+	}
+	params.PageParams = page
+
+	if createdAt := r.URL.Query().Get("createdAt"); createdAt == "asc" || createdAt == "desc" {
+		params.CreatedAt = createdAt
+	}
+
+	points, meta, err := l.loyaltyService.FindPoints(r.Context(), r.PathValue("id"), params)
+	if err != nil {
+		if e, ok := err.(*exception.CustomError); ok {
+			e.Send(w)
+			return
+		}
+		panic(err)
+	}
+
+	success := &successResponse{
+		Message: "success",
+		Data:    points,
+		Meta:    meta,
+	}
+
+	success.Send(w, http.StatusOK)
+}
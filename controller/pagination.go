@@ -0,0 +1,42 @@
+package controller
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/malikfajr/eq-store/entity"
+	"github.com/malikfajr/eq-store/exception"
+	"github.com/malikfajr/eq-store/pkg"
+)
+
+// maxPageLimit is the most rows a page returns, a larger limit is cut to it.
+const maxPageLimit = 100
+
+// parsePageParams reads limit, offset, cursor and withTotal from the query
+// string. An invalid limit or offset falls back to the default, an invalid
+// cursor is rejected.
+func parsePageParams(r *http.Request) (entity.PageParams, error) {
+	page := entity.PageParams{Limit: 5}
+
+	if n, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && n >= 0 {
+		page.Limit = min(n, maxPageLimit)
+	}
+
+	if n, err := strconv.Atoi(r.URL.Query().Get("offset")); err == nil && n >= 0 {
+		page.Offset = n
+	}
+
+	if cursor := r.URL.Query().Get("cursor"); cursor != "" {
+		decoded, err := pkg.DecodeCursor(cursor)
+		if err != nil {
+			return page, exception.NewBadRequest("invalid cursor")
+		}
+		page.Cursor = decoded
+	}
+
+	if withTotal, err := strconv.ParseBool(r.URL.Query().Get("withTotal")); err == nil {
+		page.WithTotal = withTotal
+	}
+
+	return page, nil
+}
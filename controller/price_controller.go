@@ -0,0 +1,140 @@
+package controller
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/malikfajr/eq-store/entity"
+	"github.com/malikfajr/eq-store/exception"
+	"github.com/malikfajr/eq-store/middleware"
+	"github.com/malikfajr/eq-store/service"
+)
+
+type PriceController interface {
+	GetHistory(w http.ResponseWriter, r *http.Request)
+	CreateSchedule(w http.ResponseWriter, r *http.Request)
+	GetSchedules(w http.ResponseWriter, r *http.Request)
+	CancelSchedule(w http.ResponseWriter, r *http.Request)
+}
+
+type priceController struct {
+	priceService service.PriceService
+	validate     *validator.Validate
+}
+
+func NewPriceController(validate *validator.Validate, service service.PriceService) PriceController {
+	return &priceController{
+		validate:     validate,
+		priceService: service,
+	}
+}
+
+// GetHistory lists the price changes of a product, newest first. The price on
+// a given day is the first entry with to set to the end of that day.
+func (p *priceController) GetHistory(w http.ResponseWriter, r *http.Request) {
+	params := &entity.PriceHistoryQueryParams{}
+
+	page, err := parsePageParams(r)
+	if err != nil {
+		err.(*exception.CustomError).Send(w)
+		return
+	}
+	params.PageParams = page
+
+	if from, err := time.Parse(time.RFC3339, r.URL.Query().Get("from")); err == nil {
+		from = from.UTC()
+		params.From = &from
+	}
+
+	if to, err := time.Parse(time.RFC3339, r.URL.Query().Get("to")); err == nil {
+		to = to.UTC()
+		params.To = &to
+	}
+
+	history, meta, err := p.priceService.GetHistory(r.Context(), r.PathValue("id"), params)
+	if err != nil {
+		if e, ok := err.(*exception.CustomError); ok {
+			e.Send(w)
+			return
+		}
+		panic(err)
+	}
+
+	success := &successResponse{
+		Message: "success",
+		Data:    history,
+		Meta:    meta,
+	}
+
+	success.Send(w, http.StatusOK)
+}
+
+func (p *priceController) CreateSchedule(w http.ResponseWriter, r *http.Request) {
+	body := &entity.PriceScheduleInsertRequest{}
+
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		e := exception.NewBadRequest("request doesn’t pass validation")
+		e.Send(w)
+		return
+	}
+
+	if err := p.validate.Struct(body); err != nil {
+		e := exception.NewBadRequest("request doesn’t pass validation")
+		e.Send(w)
+		return
+	}
+
+	schedule, err := p.priceService.CreateSchedule(r.Context(), middleware.GetStaffId(r.Context()), r.PathValue("id"), body)
+	if err != nil {
+		if e, ok := err.(*exception.CustomError); ok {
+			e.Send(w)
+			return
+		}
+		panic(err)
+	}
+
+	success := &successResponse{
+		Message: "success",
+		Data:    schedule,
+	}
+
+	success.Send(w, http.StatusCreated)
+}
+
+func (p *priceController) GetSchedules(w http.ResponseWriter, r *http.Request) {
+	schedules, err := p.priceService.GetSchedules(r.Context(), r.PathValue("id"))
+	if err != nil {
+		if e, ok := err.(*exception.CustomError); ok {
+			e.Send(w)
+			return
+		}
+		panic(err)
+	}
+
+	success := &successResponse{
+		Message: "success",
+		Data:    schedules,
+	}
+
+	success.Send(w, http.StatusOK)
+}
+
+func (p *priceController) CancelSchedule(w http.ResponseWriter, r *http.Request) {
+	err := p.priceService.CancelSchedule(r.Context(), r.PathValue("id"), r.PathValue("scheduleId"))
+	if err != nil {
+		if e, ok := err.(*exception.CustomError); ok {
+			e.Send(w)
+			return
+		}
+		panic(err)
+	}
+
+	success := &successResponse{
+		Message: "success",
+		Data:    []string{},
+	}
+
+	success.Send(w, http.StatusOK)
+}
@@ -2,23 +2,42 @@ package controller
 
 import (
 	"encoding/json"
+	"io"
 	"log"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/go-playground/validator/v10"
 	"github.com/malikfajr/eq-store/entity"
 	"github.com/malikfajr/eq-store/exception"
+	"github.com/malikfajr/eq-store/middleware"
+	"github.com/malikfajr/eq-store/pkg"
 	"github.com/malikfajr/eq-store/service"
 )
 
 type ProductController interface {
 	Create(w http.ResponseWriter, r *http.Request)
 	GetAll(w http.ResponseWriter, r *http.Request)
+	GetOne(w http.ResponseWriter, r *http.Request)
 	Update(w http.ResponseWriter, r *http.Request)
+	Patch(w http.ResponseWriter, r *http.Request)
 	Delete(w http.ResponseWriter, r *http.Request)
+	GetDeleted(w http.ResponseWriter, r *http.Request)
+	Restore(w http.ResponseWriter, r *http.Request)
+	Purge(w http.ResponseWriter, r *http.Request)
 	FindSku(w http.ResponseWriter, r *http.Request)
+	GetStock(w http.ResponseWriter, r *http.Request)
+	UpdateStock(w http.ResponseWriter, r *http.Request)
+	GetVariants(w http.ResponseWriter, r *http.Request)
+	CreateVariant(w http.ResponseWriter, r *http.Request)
+	UpdateVariant(w http.ResponseWriter, r *http.Request)
+	DeleteVariant(w http.ResponseWriter, r *http.Request)
+	GetComponents(w http.ResponseWriter, r *http.Request)
+	UpdateComponents(w http.ResponseWriter, r *http.Request)
+	Search(w http.ResponseWriter, r *http.Request)
+	Suggest(w http.ResponseWriter, r *http.Request)
 }
 
 type productController struct {
@@ -48,7 +67,7 @@ func (p *productController) Create(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	product, err := p.service.Create(r.Context(), &body)
+	product, err := p.service.Create(r.Context(), middleware.GetStaffId(r.Context()), &body)
 	if err != nil {
 		e, ok := err.(*exception.CustomError)
 		if ok {
@@ -81,26 +100,12 @@ func (p *productController) GetAll(w http.ResponseWriter, r *http.Request) {
 		queryParams.ID = id
 	}
 
-	if n, err := strconv.Atoi(r.URL.Query().Get("limit")); err != nil {
-		queryParams.Limit = 5
-	} else {
-		if n < 0 {
-			queryParams.Limit = 5
-		} else {
-			queryParams.Limit = n
-
-		}
-	}
-
-	if n, err := strconv.Atoi(r.URL.Query().Get("offset")); err != nil {
-		queryParams.Offset = 0
-	} else {
-		if n < 0 {
-			queryParams.Offset = 0
-		} else {
-			queryParams.Offset = n
-		}
+	page, err := parsePageParams(r)
+	if err != nil {
+		err.(*exception.CustomError).Send(w)
+		return
 	}
+	queryParams.PageParams = page
 
 	if name := r.URL.Query().Get("name"); name != "" {
 		queryParams.Name = name
@@ -116,15 +121,16 @@ func (p *productController) GetAll(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if category := r.URL.Query().Get("category"); category != "" {
-		if p.isValidCategory(category) {
-			queryParams.Category = category
-		}
+		queryParams.Category = category
 	}
 
 	if sku := r.URL.Query().Get("sku"); sku != "" {
 		queryParams.SKU = sku
 	}
 
+	queryParams.Tags = p.parseTags(r)
+	queryParams.Attributes = p.parseAttributes(r)
+
 	if inStock := r.URL.Query().Get("inStock"); inStock != "" {
 		stock, err := strconv.ParseBool(inStock)
 		if err != nil {
@@ -146,7 +152,19 @@ func (p *productController) GetAll(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	data, err := p.service.GetAll(r.Context(), queryParams)
+	if locationId := r.URL.Query().Get("locationId"); p.validate.Var(locationId, "required,uuid") == nil {
+		queryParams.LocationId = locationId
+	}
+
+	if withStocks, err := strconv.ParseBool(r.URL.Query().Get("withStocks")); err == nil {
+		queryParams.WithStocks = withStocks
+	}
+
+	if groupVariants, err := strconv.ParseBool(r.URL.Query().Get("groupVariants")); err == nil {
+		queryParams.GroupVariants = groupVariants
+	}
+
+	data, meta, err := p.service.GetAll(r.Context(), queryParams)
 	if err != nil {
 		log.Println(err)
 		e, ok := err.(*exception.CustomError)
@@ -160,6 +178,7 @@ func (p *productController) GetAll(w http.ResponseWriter, r *http.Request) {
 	success := &successResponse{
 		Message: "success",
 		Data:    data,
+		Meta:    meta,
 	}
 
 	success.Send(w, http.StatusOK)
@@ -190,14 +209,20 @@ func (p *productController) Update(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	product, err := p.service.Update(r.Context(), ID, &body)
+	version, err := parseIfMatch(r)
+	if err != nil {
+		err.(*exception.CustomError).Send(w)
+		return
+	}
+
+	product, err := p.service.Update(r.Context(), middleware.GetStaffId(r.Context()), ID, &body, version)
 	if err != nil {
 		e, ok := err.(*exception.CustomError)
 		if ok {
 			e.Send(w)
 			return
 		}
-		panic(e)
+		panic(err)
 	}
 
 	success := &successResponse{
@@ -205,10 +230,123 @@ func (p *productController) Update(w http.ResponseWriter, r *http.Request) {
 		Data:    product,
 	}
 
+	setETag(w, product.Version)
 	success.Send(w, http.StatusOK)
 	return
 }
 
+func (p *productController) GetOne(w http.ResponseWriter, r *http.Request) {
+	product, err := p.service.FindOne(r.Context(), r.PathValue("id"))
+	if err != nil {
+		if e, ok := err.(*exception.CustomError); ok {
+			e.Send(w)
+			return
+		}
+		panic(err)
+	}
+
+	success := &successResponse{
+		Message: "success",
+		Data:    product,
+	}
+
+	setETag(w, product.Version)
+	success.Send(w, http.StatusOK)
+}
+
+// Patch applies a JSON Merge Patch to the product. Fields missing from the
+// patch keep their value, null removes an optional field or attribute key.
+func (p *productController) Patch(w http.ResponseWriter, r *http.Request) {
+	ID := r.PathValue("id")
+
+	version, err := parseIfMatch(r)
+	if err != nil {
+		err.(*exception.CustomError).Send(w)
+		return
+	}
+
+	patch, err := io.ReadAll(r.Body)
+	if err != nil {
+		e := exception.NewBadRequest("request doesn’t pass validation")
+		e.Send(w)
+		return
+	}
+
+	product, err := p.service.FindOne(r.Context(), ID)
+	if err != nil {
+		if e, ok := err.(*exception.CustomError); ok {
+			e.Send(w)
+			return
+		}
+		panic(err)
+	}
+
+	// without If-Match the patch still only applies to the version it was merged with
+	if version == nil {
+		version = &product.Version
+	}
+
+	current, err := json.Marshal(entity.ProductUpdateRequest{
+		Name:              product.Name,
+		SKU:               product.SKU,
+		Category:          product.Category,
+		ImageUrl:          product.ImageUrl,
+		Notes:             product.Notes,
+		Price:             product.Price,
+		CostPrice:         &product.CostPrice,
+		Location:          product.Location,
+		IsAvailable:       &product.IsAvailable,
+		VariantAttributes: product.VariantAttributes,
+		Tags:              product.Tags,
+		Attributes:        product.Attributes,
+
+		Unit:               product.Unit,
+		QuantityPrecision:  &product.QuantityPrecision,
+		PurchaseUnit:       &product.PurchaseUnit,
+		PurchaseUnitFactor: &product.PurchaseUnitFactor,
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	merged, err := pkg.MergePatch(current, patch)
+	if err != nil {
+		e := exception.NewBadRequest("request doesn’t pass validation")
+		e.Send(w)
+		return
+	}
+
+	body := entity.ProductUpdateRequest{}
+	if err := json.Unmarshal(merged, &body); err != nil {
+		e := exception.NewBadRequest("request doesn’t pass validation")
+		e.Send(w)
+		return
+	}
+
+	if err := p.validate.Struct(body); err != nil {
+		e := exception.NewBadRequest("request doesn’t pass validation")
+		e.Send(w)
+		return
+	}
+
+	product, err = p.service.Update(r.Context(), middleware.GetStaffId(r.Context()), ID, &body, version)
+	if err != nil {
+		if e, ok := err.(*exception.CustomError); ok {
+			e.Send(w)
+			return
+		}
+		panic(err)
+	}
+
+	success := &successResponse{
+		Message: "Success update product",
+		Data:    product,
+	}
+
+	setETag(w, product.Version)
+	success.Send(w, http.StatusOK)
+}
+
 func (p *productController) Delete(w http.ResponseWriter, r *http.Request) {
 	ID := r.PathValue("id")
 
@@ -231,48 +369,96 @@ func (p *productController) Delete(w http.ResponseWriter, r *http.Request) {
 	return
 }
 
-func (p *productController) FindSku(w http.ResponseWriter, r *http.Request) {
-	queryParams := &entity.ProductQueryParams{}
+func (p *productController) GetDeleted(w http.ResponseWriter, r *http.Request) {
+	page, err := parsePageParams(r)
+	if err != nil {
+		err.(*exception.CustomError).Send(w)
+		return
+	}
 
-	if id := r.URL.Query().Get("id"); id != "" {
-		queryParams.ID = id
+	products, meta, err := p.service.GetDeleted(r.Context(), &page)
+	if err != nil {
+		if e, ok := err.(*exception.CustomError); ok {
+			e.Send(w)
+			return
+		}
+		panic(err)
 	}
 
-	if n, err := strconv.Atoi(r.URL.Query().Get("limit")); err != nil {
-		queryParams.Limit = 5
-	} else {
-		if n < 0 {
-			queryParams.Limit = 5
-		} else {
-			queryParams.Limit = n
+	success := &successResponse{
+		Message: "success",
+		Data:    products,
+		Meta:    meta,
+	}
+
+	success.Send(w, http.StatusOK)
+}
 
+func (p *productController) Restore(w http.ResponseWriter, r *http.Request) {
+	product, err := p.service.Restore(r.Context(), r.PathValue("id"))
+	if err != nil {
+		if e, ok := err.(*exception.CustomError); ok {
+			e.Send(w)
+			return
 		}
+		panic(err)
 	}
 
-	if n, err := strconv.Atoi(r.URL.Query().Get("offset")); err != nil {
-		queryParams.Offset = 0
-	} else {
-		if n < 0 {
-			queryParams.Offset = 0
-		} else {
-			queryParams.Offset = n
+	success := &successResponse{
+		Message: "Restore product success",
+		Data:    product,
+	}
+
+	setETag(w, product.Version)
+	success.Send(w, http.StatusOK)
+}
+
+func (p *productController) Purge(w http.ResponseWriter, r *http.Request) {
+	if err := p.service.Purge(r.Context(), r.PathValue("id")); err != nil {
+		if e, ok := err.(*exception.CustomError); ok {
+			e.Send(w)
+			return
 		}
+		panic(err)
+	}
+
+	success := &successResponse{
+		Message: "Purge product success",
+		Data:    []string{},
+	}
+
+	success.Send(w, http.StatusOK)
+}
+
+func (p *productController) FindSku(w http.ResponseWriter, r *http.Request) {
+	queryParams := &entity.ProductQueryParams{}
+
+	if id := r.URL.Query().Get("id"); id != "" {
+		queryParams.ID = id
 	}
 
+	page, err := parsePageParams(r)
+	if err != nil {
+		err.(*exception.CustomError).Send(w)
+		return
+	}
+	queryParams.PageParams = page
+
 	if name := r.URL.Query().Get("name"); name != "" {
 		queryParams.Name = name
 	}
 
 	if category := r.URL.Query().Get("category"); category != "" {
-		if p.isValidCategory(category) {
-			queryParams.Category = category
-		}
+		queryParams.Category = category
 	}
 
 	if sku := r.URL.Query().Get("sku"); sku != "" {
 		queryParams.SKU = sku
 	}
 
+	queryParams.Tags = p.parseTags(r)
+	queryParams.Attributes = p.parseAttributes(r)
+
 	if inStock := r.URL.Query().Get("inStock"); inStock != "" {
 		stock, err := strconv.ParseBool(inStock)
 		if err != nil {
@@ -288,7 +474,11 @@ func (p *productController) FindSku(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	data, err := p.service.FindSku(r.Context(), queryParams)
+	if locationId := r.URL.Query().Get("locationId"); p.validate.Var(locationId, "required,uuid") == nil {
+		queryParams.LocationId = locationId
+	}
+
+	data, meta, err := p.service.FindSku(r.Context(), queryParams)
 	if err != nil {
 		log.Println(err)
 		e, ok := err.(*exception.CustomError)
@@ -302,23 +492,330 @@ func (p *productController) FindSku(w http.ResponseWriter, r *http.Request) {
 	success := &successResponse{
 		Message: "success",
 		Data:    data,
+		Meta:    meta,
 	}
 
 	success.Send(w, http.StatusOK)
 	return
 }
 
-func (p *productController) isValidCategory(key string) bool {
-	ok := false
-	categories := map[string]bool{
-		"Clothing":    true,
-		"Accessories": true,
-		"Footwear":    true,
-		"Beverages":   true,
+func (p *productController) GetStock(w http.ResponseWriter, r *http.Request) {
+	stocks, err := p.service.GetStocks(r.Context(), r.PathValue("id"))
+	if err != nil {
+		if e, ok := err.(*exception.CustomError); ok {
+			e.Send(w)
+			return
+		}
+		panic(err)
 	}
 
-	_, ok = categories[key]
-	return ok
+	success := &successResponse{
+		Message: "success",
+		Data:    stocks,
+	}
+
+	success.Send(w, http.StatusOK)
+}
+
+func (p *productController) UpdateStock(w http.ResponseWriter, r *http.Request) {
+	body := &entity.ProductStockUpdateRequest{}
+
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		e := exception.NewBadRequest("request doesn’t pass validation")
+		e.Send(w)
+		return
+	}
+
+	if err := p.validate.Struct(body); err != nil {
+		e := exception.NewBadRequest("request doesn’t pass validation")
+		e.Send(w)
+		return
+	}
+
+	ifMatch, err := parseIfMatch(r)
+	if err != nil {
+		err.(*exception.CustomError).Send(w)
+		return
+	}
+
+	stocks, version, err := p.service.UpdateStock(r.Context(), r.PathValue("id"), body, ifMatch)
+	if err != nil {
+		if e, ok := err.(*exception.CustomError); ok {
+			e.Send(w)
+			return
+		}
+		panic(err)
+	}
+
+	success := &successResponse{
+		Message: "success",
+		Data:    stocks,
+	}
+
+	setETag(w, version)
+
+	success.Send(w, http.StatusOK)
+}
+
+func (p *productController) GetVariants(w http.ResponseWriter, r *http.Request) {
+	variants, err := p.service.GetVariants(r.Context(), r.PathValue("id"))
+	if err != nil {
+		if e, ok := err.(*exception.CustomError); ok {
+			e.Send(w)
+			return
+		}
+		panic(err)
+	}
+
+	success := &successResponse{
+		Message: "success",
+		Data:    variants,
+	}
+
+	success.Send(w, http.StatusOK)
+}
+
+func (p *productController) CreateVariant(w http.ResponseWriter, r *http.Request) {
+	body := &entity.ProductVariantInsertUpdateRequest{}
+
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		e := exception.NewBadRequest("request doesn’t pass validation")
+		e.Send(w)
+		return
+	}
+
+	if err := p.validate.Struct(body); err != nil {
+		e := exception.NewBadRequest("request doesn’t pass validation")
+		e.Send(w)
+		return
+	}
+
+	variant, err := p.service.CreateVariant(r.Context(), middleware.GetStaffId(r.Context()), r.PathValue("id"), body)
+	if err != nil {
+		if e, ok := err.(*exception.CustomError); ok {
+			e.Send(w)
+			return
+		}
+		panic(err)
+	}
+
+	success := &successResponse{
+		Message: "success",
+		Data:    variant,
+	}
+
+	success.Send(w, http.StatusCreated)
+}
+
+func (p *productController) UpdateVariant(w http.ResponseWriter, r *http.Request) {
+	body := &entity.ProductVariantInsertUpdateRequest{}
+
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		e := exception.NewBadRequest("request doesn’t pass validation")
+		e.Send(w)
+		return
+	}
+
+	if err := p.validate.Struct(body); err != nil {
+		e := exception.NewBadRequest("request doesn’t pass validation")
+		e.Send(w)
+		return
+	}
+
+	variant, err := p.service.UpdateVariant(r.Context(), middleware.GetStaffId(r.Context()), r.PathValue("id"), r.PathValue("variantId"), body)
+	if err != nil {
+		if e, ok := err.(*exception.CustomError); ok {
+			e.Send(w)
+			return
+		}
+		panic(err)
+	}
+
+	success := &successResponse{
+		Message: "Success update variant",
+		Data:    variant,
+	}
+
+	success.Send(w, http.StatusOK)
+}
+
+func (p *productController) DeleteVariant(w http.ResponseWriter, r *http.Request) {
+	err := p.service.DeleteVariant(r.Context(), r.PathValue("id"), r.PathValue("variantId"))
+	if err != nil {
+		if e, ok := err.(*exception.CustomError); ok {
+			e.Send(w)
+			return
+		}
+		panic(err)
+	}
+
+	success := &successResponse{
+		Message: "Delete variant success",
+		Data:    []string{},
+	}
+
+	success.Send(w, http.StatusOK)
+}
+
+func (p *productController) GetComponents(w http.ResponseWriter, r *http.Request) {
+	components, err := p.service.GetComponents(r.Context(), r.PathValue("id"))
+	if err != nil {
+		if e, ok := err.(*exception.CustomError); ok {
+			e.Send(w)
+			return
+		}
+		panic(err)
+	}
+
+	success := &successResponse{
+		Message: "success",
+		Data:    components,
+	}
+
+	success.Send(w, http.StatusOK)
+}
+
+func (p *productController) UpdateComponents(w http.ResponseWriter, r *http.Request) {
+	body := &entity.BundleComponentsUpdateRequest{}
+
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		e := exception.NewBadRequest("request doesn’t pass validation")
+		e.Send(w)
+		return
+	}
+
+	if err := p.validate.Struct(body); err != nil {
+		e := exception.NewBadRequest("request doesn’t pass validation")
+		e.Send(w)
+		return
+	}
+
+	version, err := parseIfMatch(r)
+	if err != nil {
+		err.(*exception.CustomError).Send(w)
+		return
+	}
+
+	product, err := p.service.UpdateComponents(r.Context(), r.PathValue("id"), body, version)
+	if err != nil {
+		if e, ok := err.(*exception.CustomError); ok {
+			e.Send(w)
+			return
+		}
+		panic(err)
+	}
+
+	success := &successResponse{
+		Message: "success",
+		Data:    product,
+	}
+
+	setETag(w, product.Version)
+
+	success.Send(w, http.StatusOK)
+}
+
+func (p *productController) Search(w http.ResponseWriter, r *http.Request) {
+	params := &entity.ProductSearchParams{}
+
+	params.Q = strings.TrimSpace(r.URL.Query().Get("q"))
+	if params.Q == "" || len(params.Q) > 100 {
+		e := exception.NewBadRequest("request doesn’t pass validation")
+		e.Send(w)
+		return
+	}
+
+	page, err := parsePageParams(r)
+	if err != nil {
+		err.(*exception.CustomError).Send(w)
+		return
+	}
+	params.PageParams = page
+
+	if category := r.URL.Query().Get("category"); category != "" {
+		params.Category = category
+	}
+
+	if available, err := strconv.ParseBool(r.URL.Query().Get("isAvailable")); err == nil {
+		params.IsAvailable = &available
+	}
+
+	results, meta, err := p.service.Search(r.Context(), params)
+	if err != nil {
+		log.Println(err)
+		if e, ok := err.(*exception.CustomError); ok {
+			e.Send(w)
+			return
+		}
+		panic(err)
+	}
+
+	success := &successResponse{
+		Message: "success",
+		Data:    results,
+		Meta:    meta,
+	}
+
+	success.Send(w, http.StatusOK)
+}
+
+// Suggest serves the POS search box, an empty query returns no suggestion.
+func (p *productController) Suggest(w http.ResponseWriter, r *http.Request) {
+	q := strings.TrimSpace(r.URL.Query().Get("q"))
+	if q == "" || len(q) > 100 {
+		success := &successResponse{
+			Message: "success",
+			Data:    []entity.ProductSuggestion{},
+		}
+		success.Send(w, http.StatusOK)
+		return
+	}
+
+	limit := 10
+	if n, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && n > 0 && n <= 50 {
+		limit = n
+	}
+
+	suggestions, err := p.service.Suggest(r.Context(), q, limit)
+	if err != nil {
+		log.Println(err)
+		panic(err)
+	}
+
+	success := &successResponse{
+		Message: "success",
+		Data:    suggestions,
+	}
+
+	success.Send(w, http.StatusOK)
+}
+
+// parseTags accepts both ?tags=a,b and ?tags=a&tags=b, a product must have all of them.
+func (p *productController) parseTags(r *http.Request) []string {
+	tags := []string{}
+	for _, value := range r.URL.Query()["tags"] {
+		for _, tag := range strings.Split(value, ",") {
+			if tag != "" {
+				tags = append(tags, tag)
+			}
+		}
+	}
+
+	return tags
+}
+
+// parseAttributes reads attribute filters written as ?attr.brand=Nike&attr.material=Leather.
+func (p *productController) parseAttributes(r *http.Request) map[string]string {
+	attributes := map[string]string{}
+	for key, values := range r.URL.Query() {
+		name, found := strings.CutPrefix(key, "attr.")
+		if !found || name == "" || len(values) == 0 {
+			continue
+		}
+		attributes[name] = values[0]
+	}
+
+	return attributes
 }
 
 func (p *productController) isValidOrder(key string) bool {
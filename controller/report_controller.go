@@ -0,0 +1,100 @@
+package controller
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/malikfajr/eq-store/entity"
+	"github.com/malikfajr/eq-store/exception"
+	"github.com/malikfajr/eq-store/service"
+)
+
+type ReportController interface {
+	Margin(w http.ResponseWriter, r *http.Request)
+	InventoryValuation(w http.ResponseWriter, r *http.Request)
+}
+
+type reportController struct {
+	reportService service.ReportService
+	validate      *validator.Validate
+}
+
+func NewReportController(validate *validator.Validate, service service.ReportService) ReportController {
+	return &reportController{
+		validate:      validate,
+		reportService: service,
+	}
+}
+
+// Margin reports the gross margin grouped by product (default), category or
+// period. from is inclusive and to is exclusive.
+func (c *reportController) Margin(w http.ResponseWriter, r *http.Request) {
+	params := &entity.MarginReportParams{
+		GroupBy: entity.MarginByProduct,
+		Period:  "day",
+	}
+
+	switch groupBy := r.URL.Query().Get("groupBy"); groupBy {
+	case entity.MarginByCategory, entity.MarginByPeriod:
+		params.GroupBy = groupBy
+	}
+
+	switch period := r.URL.Query().Get("period"); period {
+	case "week", "month":
+		params.Period = period
+	}
+
+	if from, err := time.Parse(time.RFC3339, r.URL.Query().Get("from")); err == nil {
+		from = from.UTC()
+		params.From = &from
+	}
+
+	if to, err := time.Parse(time.RFC3339, r.URL.Query().Get("to")); err == nil {
+		to = to.UTC()
+		params.To = &to
+	}
+
+	if locationId := r.URL.Query().Get("locationId"); c.validate.Var(locationId, "required,uuid") == nil {
+		params.LocationId = locationId
+	}
+
+	report, err := c.reportService.Margin(r.Context(), params)
+	if err != nil {
+		if e, ok := err.(*exception.CustomError); ok {
+			e.Send(w)
+			return
+		}
+		panic(err)
+	}
+
+	success := &successResponse{
+		Message: "success",
+		Data:    report,
+	}
+
+	success.Send(w, http.StatusOK)
+}
+
+func (c *reportController) InventoryValuation(w http.ResponseWriter, r *http.Request) {
+	locationId := r.URL.Query().Get("locationId")
+	if c.validate.Var(locationId, "required,uuid") != nil {
+		locationId = ""
+	}
+
+	valuation, err := c.reportService.InventoryValuation(r.Context(), locationId)
+	if err != nil {
+		if e, ok := err.(*exception.CustomError); ok {
+			e.Send(w)
+			return
+		}
+		panic(err)
+	}
+
+	success := &successResponse{
+		Message: "success",
+		Data:    valuation,
+	}
+
+	success.Send(w, http.StatusOK)
+}
@@ -0,0 +1,174 @@
+package controller
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/malikfajr/eq-store/entity"
+	"github.com/malikfajr/eq-store/exception"
+	"github.com/malikfajr/eq-store/service"
+)
+
+type SegmentController interface {
+	Create(w http.ResponseWriter, r *http.Request)
+	GetAll(w http.ResponseWriter, r *http.Request)
+	GetOne(w http.ResponseWriter, r *http.Request)
+	Update(w http.ResponseWriter, r *http.Request)
+	Delete(w http.ResponseWriter, r *http.Request)
+	GetMembers(w http.ResponseWriter, r *http.Request)
+}
+
+type segmentController struct {
+	segmentService service.SegmentService
+	validate       *validator.Validate
+}
+
+func NewSegmentController(validate *validator.Validate, service service.SegmentService) SegmentController {
+	return &segmentController{
+		validate:       validate,
+		segmentService: service,
+	}
+}
+
+func (s *segmentController) Create(w http.ResponseWriter, r *http.Request) {
+	body := &entity.SegmentInsertUpdateRequest{}
+
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		e := exception.NewBadRequest("request doesn’t pass validation")
+		e.Send(w)
+		return
+	}
+
+	if err := s.validate.Struct(body); err != nil {
+		e := exception.NewBadRequest("request doesn’t pass validation")
+		e.Send(w)
+		return
+	}
+
+	segment, err := s.segmentService.Create(r.Context(), body)
+	if err != nil {
+		if e, ok := err.(*exception.CustomError); ok {
+			e.Send(w)
+			return
+		}
+		panic(err)
+	}
+
+	success := &successResponse{
+		Message: "success",
+		Data:    segment,
+	}
+
+	success.Send(w, http.StatusCreated)
+}
+
+func (s *segmentController) GetAll(w http.ResponseWriter, r *http.Request) {
+	success := &successResponse{
+		Message: "success",
+		Data:    s.segmentService.FindAll(r.Context()),
+	}
+
+	success.Send(w, http.StatusOK)
+}
+
+func (s *segmentController) GetOne(w http.ResponseWriter, r *http.Request) {
+	segment, err := s.segmentService.FindOne(r.Context(), r.PathValue("id"))
+	if err != nil {
+		if e, ok := err.(*exception.CustomError); ok {
+			e.Send(w)
+			return
+		}
+		panic(err)
+	}
+
+	success := &successResponse{
+		Message: "success",
+		Data:    segment,
+	}
+
+	success.Send(w, http.StatusOK)
+}
+
+func (s *segmentController) Update(w http.ResponseWriter, r *http.Request) {
+	body := &entity.SegmentInsertUpdateRequest{}
+
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		e := exception.NewBadRequest("request doesn’t pass validation")
+		e.Send(w)
+		return
+	}
+
+	if err := s.validate.Struct(body); err != nil {
+		e := exception.NewBadRequest("request doesn’t pass validation")
+		e.Send(w)
+		return
+	}
+
+	segment, err := s.segmentService.Update(r.Context(), r.PathValue("id"), body)
+	if err != nil {
+		if e, ok := err.(*exception.CustomError); ok {
+			e.Send(w)
+			return
+		}
+		panic(err)
+	}
+
+	success := &successResponse{
+		Message: "success",
+		Data:    segment,
+	}
+
+	success.Send(w, http.StatusOK)
+}
+
+func (s *segmentController) Delete(w http.ResponseWriter, r *http.Request) {
+	if err := s.segmentService.Delete(r.Context(), r.PathValue("id")); err != nil {
+		if e, ok := err.(*exception.CustomError); ok {
+			e.Send(w)
+			return
+		}
+		panic(err)
+	}
+
+	success := &successResponse{
+		Message: "Delete segment success",
+		Data:    []string{},
+	}
+
+	success.Send(w, http.StatusOK)
+}
+
+// GetMembers lists a page of the customers in a segment, newest first unless
+// createdAt=asc.
+func (s *segmentController) GetMembers(w http.ResponseWriter, r *http.Request) {
+	params := &entity.CustomerQueryParams{}
+
+	if createdAt := r.URL.Query().Get("createdAt"); createdAt == "asc" || createdAt == "desc" {
+		params.CreatedAt = createdAt
+	}
+
+	page, err := parsePageParams(r)
+	if err != nil {
+		err.(*exception.CustomError).Send(w)
+		return
+	}
+	params.PageParams = page
+
+	customers, meta, err := s.segmentService.FindMembers(r.Context(), r.PathValue("id"), params)
+	if err != nil {
+		if e, ok := err.(*exception.CustomError); ok {
+			e.Send(w)
+			return
+		}
+		panic(err)
+	}
+
+	success := &successResponse{
+		Message: "success",
+		Data:    customers,
+		Meta:    meta,
+	}
+
+	success.Send(w, http.StatusOK)
+}
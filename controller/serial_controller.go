@@ -0,0 +1,40 @@
+package controller
+
+import (
+	"net/http"
+
+	"github.com/malikfajr/eq-store/exception"
+	"github.com/malikfajr/eq-store/service"
+)
+
+type SerialController interface {
+	GetOne(w http.ResponseWriter, r *http.Request)
+}
+
+type serialController struct {
+	serialService service.SerialService
+}
+
+func NewSerialController(serialService service.SerialService) SerialController {
+	return &serialController{
+		serialService: serialService,
+	}
+}
+
+func (s *serialController) GetOne(w http.ResponseWriter, r *http.Request) {
+	serials, err := s.serialService.FindBySerial(r.Context(), r.PathValue("serial"))
+	if err != nil {
+		if e, ok := err.(*exception.CustomError); ok {
+			e.Send(w)
+			return
+		}
+		panic(err)
+	}
+
+	success := &successResponse{
+		Message: "success",
+		Data:    serials,
+	}
+
+	success.Send(w, http.StatusOK)
+}
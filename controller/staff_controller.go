@@ -2,14 +2,12 @@ package controller
 
 import (
 	"encoding/json"
-	"log"
 	"net/http"
 
 	"github.com/go-playground/validator/v10"
 	"github.com/malikfajr/eq-store/entity"
 	"github.com/malikfajr/eq-store/exception"
 	"github.com/malikfajr/eq-store/service"
-	"github.com/nyaruka/phonenumbers"
 )
 
 type staffController struct {
@@ -34,14 +32,14 @@ func (s *staffController) Register(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if s.service.PhoneIsExist(r.Context(), body.PhoneNumber) == true {
-		e := exception.NewConflict("phone is registered")
+	if err := s.validate.Struct(body); err != nil {
+		e := exception.NewBadRequest("request doesn’t pass validation")
 		e.Send(w)
 		return
 	}
 
-	if err := s.validate.Struct(body); err != nil {
-		e := exception.NewBadRequest("request doesn’t pass validation")
+	if s.service.PhoneIsExist(r.Context(), body.PhoneNumber) == true {
+		e := exception.NewConflict("phone is registered")
 		e.Send(w)
 		return
 	}
@@ -95,14 +93,3 @@ func (s *staffController) Login(w http.ResponseWriter, r *http.Request) {
 	success.Send(w, http.StatusOK)
 	return
 }
-
-func (s *staffController) isValidPhoneNumber(phone string) bool {
-	num, err := phonenumbers.Parse(phone, "")
-	if err != nil {
-		log.Println("Error parsing phone number:", err)
-		return false
-	}
-
-	// Memeriksa apakah nomor telepon valid
-	return phonenumbers.IsPossibleNumber(num)
-}
@@ -0,0 +1,165 @@
+package controller
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/malikfajr/eq-store/entity"
+	"github.com/malikfajr/eq-store/exception"
+	"github.com/malikfajr/eq-store/middleware"
+	"github.com/malikfajr/eq-store/service"
+)
+
+type StockTransferController interface {
+	Send(w http.ResponseWriter, r *http.Request)
+	Receive(w http.ResponseWriter, r *http.Request)
+	Cancel(w http.ResponseWriter, r *http.Request)
+	GetOne(w http.ResponseWriter, r *http.Request)
+	GetAll(w http.ResponseWriter, r *http.Request)
+}
+
+type stockTransferController struct {
+	stockTransferService service.StockTransferService
+	validate             *validator.Validate
+}
+
+func NewStockTransferController(validate *validator.Validate, service service.StockTransferService) StockTransferController {
+	return &stockTransferController{
+		validate:             validate,
+		stockTransferService: service,
+	}
+}
+
+func (s *stockTransferController) Send(w http.ResponseWriter, r *http.Request) {
+	body := &entity.StockTransferInsertRequest{}
+
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		e := exception.NewBadRequest("request doesn’t pass validation")
+		e.Send(w)
+		return
+	}
+
+	if err := s.validate.Struct(body); err != nil {
+		e := exception.NewBadRequest("request doesn’t pass validation")
+		e.Send(w)
+		return
+	}
+
+	transfer, err := s.stockTransferService.Send(r.Context(), middleware.GetStaffId(r.Context()), body)
+	if err != nil {
+		if e, ok := err.(*exception.CustomError); ok {
+			e.Send(w)
+			return
+		}
+		panic(err)
+	}
+
+	success := &successResponse{
+		Message: "success",
+		Data:    transfer,
+	}
+
+	success.Send(w, http.StatusCreated)
+}
+
+func (s *stockTransferController) Receive(w http.ResponseWriter, r *http.Request) {
+	transfer, err := s.stockTransferService.Receive(r.Context(), middleware.GetStaffId(r.Context()), r.PathValue("id"))
+	if err != nil {
+		if e, ok := err.(*exception.CustomError); ok {
+			e.Send(w)
+			return
+		}
+		panic(err)
+	}
+
+	success := &successResponse{
+		Message: "success",
+		Data:    transfer,
+	}
+
+	success.Send(w, http.StatusOK)
+}
+
+func (s *stockTransferController) Cancel(w http.ResponseWriter, r *http.Request) {
+	transfer, err := s.stockTransferService.Cancel(r.Context(), middleware.GetStaffId(r.Context()), r.PathValue("id"))
+	if err != nil {
+		if e, ok := err.(*exception.CustomError); ok {
+			e.Send(w)
+			return
+		}
+		panic(err)
+	}
+
+	success := &successResponse{
+		Message: "success",
+		Data:    transfer,
+	}
+
+	success.Send(w, http.StatusOK)
+}
+
+func (s *stockTransferController) GetOne(w http.ResponseWriter, r *http.Request) {
+	transfer, err := s.stockTransferService.FindOne(r.Context(), r.PathValue("id"))
+	if err != nil {
+		if e, ok := err.(*exception.CustomError); ok {
+			e.Send(w)
+			return
+		}
+		panic(err)
+	}
+
+	success := &successResponse{
+		Message: "success",
+		Data:    transfer,
+	}
+
+	success.Send(w, http.StatusOK)
+}
+
+func (s *stockTransferController) GetAll(w http.ResponseWriter, r *http.Request) {
+	params := &entity.StockTransferQueryParams{}
+
+	page, err := parsePageParams(r)
+	if err != nil {
+		err.(*exception.CustomError).Send(w)
+		return
+	}
+	params.PageParams = page
+
+	if status := r.URL.Query().Get("status"); s.isValidStatus(status) {
+		params.Status = status
+	}
+
+	if locationId := r.URL.Query().Get("locationId"); s.validate.Var(locationId, "required,uuid") == nil {
+		params.LocationId = locationId
+	}
+
+	transfers, meta, err := s.stockTransferService.FindMany(r.Context(), params)
+	if err != nil {
+		if e, ok := err.(*exception.CustomError); ok {
+			e.Send(w)
+			return
+		}
+		panic(err)
+	}
+
+	success := &successResponse{
+		Message: "success",
+		Data:    transfers,
+		Meta:    meta,
+	}
+
+	success.Send(w, http.StatusOK)
+}
+
+func (s *stockTransferController) isValidStatus(key string) bool {
+	status := map[string]bool{
+		entity.TransferInTransit: true,
+		entity.TransferReceived:  true,
+		entity.TransferCancelled: true,
+	}
+
+	_, ok := status[key]
+	return ok
+}
@@ -0,0 +1,161 @@
+package controller
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/malikfajr/eq-store/entity"
+	"github.com/malikfajr/eq-store/exception"
+	"github.com/malikfajr/eq-store/middleware"
+	"github.com/malikfajr/eq-store/service"
+)
+
+type StoredValueController interface {
+	IssueGiftCard(w http.ResponseWriter, r *http.Request)
+	TopUpGiftCard(w http.ResponseWriter, r *http.Request)
+	GetGiftCard(w http.ResponseWriter, r *http.Request)
+	AddCredit(w http.ResponseWriter, r *http.Request)
+	GetCredit(w http.ResponseWriter, r *http.Request)
+}
+
+type storedValueController struct {
+	storedValueService service.StoredValueService
+	validate           *validator.Validate
+}
+
+func NewStoredValueController(validate *validator.Validate, service service.StoredValueService) StoredValueController {
+	return &storedValueController{
+		validate:           validate,
+		storedValueService: service,
+	}
+}
+
+func (s *storedValueController) IssueGiftCard(w http.ResponseWriter, r *http.Request) {
+	body := &entity.GiftCardInsertRequest{}
+
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		e := exception.NewBadRequest("request doesn’t pass validation")
+		e.Send(w)
+		return
+	}
+
+	if err := s.validate.Struct(body); err != nil {
+		e := exception.NewBadRequest("request doesn’t pass validation")
+		e.Send(w)
+		return
+	}
+
+	account, err := s.storedValueService.IssueGiftCard(r.Context(), middleware.GetStaffId(r.Context()), body)
+	if err != nil {
+		if e, ok := err.(*exception.CustomError); ok {
+			e.Send(w)
+			return
+		}
+		panic(err)
+	}
+
+	success := &successResponse{
+		Message: "success",
+		Data:    account,
+	}
+
+	success.Send(w, http.StatusCreated)
+}
+
+func (s *storedValueController) TopUpGiftCard(w http.ResponseWriter, r *http.Request) {
+	body, ok := s.decodeTopUp(w, r)
+	if !ok {
+		return
+	}
+
+	account, err := s.storedValueService.TopUpGiftCard(r.Context(), middleware.GetStaffId(r.Context()), r.PathValue("code"), body)
+	s.sendAccount(w, account, nil, err)
+}
+
+func (s *storedValueController) GetGiftCard(w http.ResponseWriter, r *http.Request) {
+	params, ok := s.movementParams(w, r)
+	if !ok {
+		return
+	}
+
+	account, meta, err := s.storedValueService.FindGiftCard(r.Context(), r.PathValue("code"), params)
+	s.sendAccount(w, account, meta, err)
+}
+
+func (s *storedValueController) AddCredit(w http.ResponseWriter, r *http.Request) {
+	body, ok := s.decodeTopUp(w, r)
+	if !ok {
+		return
+	}
+
+	account, err := s.storedValueService.AddCredit(r.Context(), middleware.GetStaffId(r.Context()), r.PathValue("id"), body)
+	s.sendAccount(w, account, nil, err)
+}
+
+func (s *storedValueController) GetCredit(w http.ResponseWriter, r *http.Request) {
+	params, ok := s.movementParams(w, r)
+	if !ok {
+		return
+	}
+
+	account, meta, err := s.storedValueService.FindCredit(r.Context(), r.PathValue("id"), params)
+	s.sendAccount(w, account, meta, err)
+}
+
+func (s *storedValueController) decodeTopUp(w http.ResponseWriter, r *http.Request) (*entity.StoredValueTopUpRequest, bool) {
+	body := &entity.StoredValueTopUpRequest{}
+
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		e := exception.NewBadRequest("request doesn’t pass validation")
+		e.Send(w)
+		return nil, false
+	}
+
+	if err := s.validate.Struct(body); err != nil {
+		e := exception.NewBadRequest("request doesn’t pass validation")
+		e.Send(w)
+		return nil, false
+	}
+
+	return body, true
+}
+
+// movementParams reads the page of movements listed with an account, newest first.
+func (s *storedValueController) movementParams(w http.ResponseWriter, r *http.Request) (*entity.MovementQueryParams, bool) {
+	params := &entity.MovementQueryParams{}
+
+	page, err := parsePageParams(r)
+	if err != nil {
+		err.(*exception.CustomError).Send(w)
+		return nil, false
+	}
+	params.PageParams = page
+
+	if createdAt := r.URL.Query().Get("createdAt"); createdAt == "asc" || createdAt == "desc" {
+		params.CreatedAt = createdAt
+	}
+
+	return params, true
+}
+
+func (s *storedValueController) sendAccount(w http.ResponseWriter, account *entity.StoredValueAccount, meta *entity.PageMeta, err error) {
+	if err != nil {
+		if e, ok := err.(*exception.CustomError); ok {
+			e.Send(w)
+			return
+		}
+		panic(err)
+	}
+
+	success := &successResponse{
+		Message: "success",
+		Data:    account,
+	}
+
+	if meta != nil {
+		success.Meta = meta
+	}
+
+	success.Send(w, http.StatusOK)
+}
@@ -4,17 +4,18 @@ import (
 	"encoding/json"
 	"log"
 	"net/http"
-	"strconv"
 
 	"github.com/go-playground/validator/v10"
 	"github.com/malikfajr/eq-store/entity"
 	"github.com/malikfajr/eq-store/exception"
+	"github.com/malikfajr/eq-store/middleware"
 	"github.com/malikfajr/eq-store/service"
 )
 
 type TransactionController interface {
 	Create(w http.ResponseWriter, r *http.Request)
 	GetAll(w http.ResponseWriter, r *http.Request)
+	Refund(w http.ResponseWriter, r *http.Request)
 }
 
 type transactionController struct {
@@ -72,26 +73,12 @@ func (t *transactionController) GetAll(w http.ResponseWriter, r *http.Request) {
 		params.CustomerId = customerId
 	}
 
-	if n, err := strconv.Atoi(r.URL.Query().Get("limit")); err != nil {
-		params.Limit = 5
-	} else {
-		if n < 0 {
-			params.Limit = 5
-		} else {
-			params.Limit = n
-
-		}
-	}
-
-	if n, err := strconv.Atoi(r.URL.Query().Get("offset")); err != nil {
-		params.Offset = 0
-	} else {
-		if n < 0 {
-			params.Offset = 0
-		} else {
-			params.Offset = n
-		}
+	page, err := parsePageParams(r)
+	if err != nil {
+		err.(*exception.CustomError).Send(w)
+		return
 	}
+	params.PageParams = page
 
 	if createdAt := r.URL.Query().Get("createdAt"); createdAt != "" {
 		if t.isValidOrder(createdAt) {
@@ -99,7 +86,11 @@ func (t *transactionController) GetAll(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	data, err := t.transactionService.FindMany(r.Context(), params)
+	if locationId := r.URL.Query().Get("locationId"); t.validate.Var(locationId, "required,uuid") == nil {
+		params.LocationId = locationId
+	}
+
+	data, meta, err := t.transactionService.FindMany(r.Context(), params)
 	if err != nil {
 		e, ok := err.(*exception.CustomError)
 		if ok {
@@ -112,6 +103,29 @@ func (t *transactionController) GetAll(w http.ResponseWriter, r *http.Request) {
 	success := &successResponse{
 		Message: "success",
 		Data:    data,
+		Meta:    meta,
+	}
+
+	success.Send(w, http.StatusOK)
+}
+
+// Refund takes a transaction back, ?toStoreCredit=true pays the cash part
+// back as store credit.
+func (t *transactionController) Refund(w http.ResponseWriter, r *http.Request) {
+	toStoreCredit := r.URL.Query().Get("toStoreCredit") == "true"
+
+	err := t.transactionService.Refund(r.Context(), middleware.GetStaffId(r.Context()), r.PathValue("id"), toStoreCredit)
+	if err != nil {
+		if e, ok := err.(*exception.CustomError); ok {
+			e.Send(w)
+			return
+		}
+		panic(err)
+	}
+
+	success := &successResponse{
+		Message: "success",
+		Data:    []string{},
 	}
 
 	success.Send(w, http.StatusOK)
@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS locations(
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    name VARCHAR(50) NOT NULL,
+    address VARCHAR(200) NOT NULL DEFAULT '',
+    is_default BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    deleted_at TIMESTAMP NULL DEFAULT NULL
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_location_name ON locations(LOWER(name)) WHERE deleted_at IS NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_location_default ON locations(is_default) WHERE is_default = TRUE;
+
+INSERT INTO locations (name, is_default) VALUES ('Main', TRUE);
@@ -0,0 +1,6 @@
+ALTER TABLE transactions DROP COLUMN IF EXISTS location_id;
+
+ALTER TABLE products DROP CONSTRAINT IF EXISTS products_stock_check;
+ALTER TABLE products ADD CONSTRAINT products_stock_check CHECK(stock >= 0 AND stock <= 100000);
+
+DROP TABLE IF EXISTS product_stocks;
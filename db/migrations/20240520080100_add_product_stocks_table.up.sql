@@ -0,0 +1,26 @@
+CREATE TABLE IF NOT EXISTS product_stocks(
+    product_id UUID NOT NULL,
+    location_id UUID NOT NULL,
+    stock INT NOT NULL DEFAULT 0 CHECK(stock >= 0),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+
+    PRIMARY KEY (product_id, location_id),
+    FOREIGN KEY (product_id) REFERENCES products(id)
+    ON UPDATE CASCADE ON DELETE CASCADE,
+    FOREIGN KEY (location_id) REFERENCES locations(id)
+    ON UPDATE CASCADE ON DELETE RESTRICT
+);
+
+CREATE INDEX IF NOT EXISTS idx_product_stock_location_id ON product_stocks(location_id);
+
+-- Existing stock is moved to the default location, products.stock keeps the total of all locations.
+INSERT INTO product_stocks (product_id, location_id, stock)
+    SELECT p.id, l.id, p.stock FROM products p, locations l WHERE l.is_default = TRUE;
+
+ALTER TABLE products DROP CONSTRAINT IF EXISTS products_stock_check;
+ALTER TABLE products ADD CONSTRAINT products_stock_check CHECK(stock >= 0);
+
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS location_id UUID NULL REFERENCES locations(id) ON UPDATE CASCADE ON DELETE RESTRICT;
+UPDATE transactions SET location_id = (SELECT id FROM locations WHERE is_default = TRUE);
+
+CREATE INDEX IF NOT EXISTS idx_trx_location_id ON transactions(location_id);
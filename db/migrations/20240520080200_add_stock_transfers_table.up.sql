@@ -0,0 +1,33 @@
+CREATE TABLE IF NOT EXISTS stock_transfers(
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    from_location_id UUID NOT NULL,
+    to_location_id UUID NOT NULL,
+    status VARCHAR(10) NOT NULL DEFAULT 'in_transit' CHECK(status IN ('in_transit', 'received', 'cancelled')),
+    notes VARCHAR(200) NOT NULL DEFAULT '',
+    sent_by UUID NOT NULL,
+    received_by UUID NULL,
+    sent_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    received_at TIMESTAMP NULL DEFAULT NULL,
+
+    CHECK(from_location_id <> to_location_id),
+    FOREIGN KEY (from_location_id) REFERENCES locations(id)
+    ON UPDATE CASCADE ON DELETE RESTRICT,
+    FOREIGN KEY (to_location_id) REFERENCES locations(id)
+    ON UPDATE CASCADE ON DELETE RESTRICT,
+    FOREIGN KEY (sent_by) REFERENCES staffs(id),
+    FOREIGN KEY (received_by) REFERENCES staffs(id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_transfer_status ON stock_transfers(status);
+
+CREATE TABLE IF NOT EXISTS stock_transfer_items(
+    transfer_id UUID NOT NULL,
+    product_id UUID NOT NULL,
+    quantity INT NOT NULL CHECK(quantity >= 1),
+
+    PRIMARY KEY (transfer_id, product_id),
+    FOREIGN KEY (transfer_id) REFERENCES stock_transfers(id)
+    ON UPDATE CASCADE ON DELETE CASCADE,
+    FOREIGN KEY (product_id) REFERENCES products(id)
+    ON UPDATE CASCADE ON DELETE CASCADE
+);
@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS idx_product_variant_options;
+DROP INDEX IF EXISTS idx_product_parent_id;
+
+ALTER TABLE products DROP COLUMN IF EXISTS inherit_price;
+ALTER TABLE products DROP COLUMN IF EXISTS variant_options;
+ALTER TABLE products DROP COLUMN IF EXISTS variant_attributes;
+ALTER TABLE products DROP COLUMN IF EXISTS parent_id;
@@ -0,0 +1,7 @@
+ALTER TABLE products ADD COLUMN IF NOT EXISTS parent_id UUID NULL REFERENCES products(id) ON UPDATE CASCADE ON DELETE RESTRICT;
+ALTER TABLE products ADD COLUMN IF NOT EXISTS variant_attributes JSONB NOT NULL DEFAULT '[]';
+ALTER TABLE products ADD COLUMN IF NOT EXISTS variant_options JSONB NOT NULL DEFAULT '{}';
+ALTER TABLE products ADD COLUMN IF NOT EXISTS inherit_price BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE INDEX IF NOT EXISTS idx_product_parent_id ON products(parent_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_product_variant_options ON products(parent_id, variant_options) WHERE parent_id IS NOT NULL AND deleted_at IS NULL;
@@ -0,0 +1,5 @@
+ALTER TABLE products DROP CONSTRAINT IF EXISTS products_category_fkey;
+-- names longer than the original column are cut to fit
+ALTER TABLE products ALTER COLUMN category TYPE VARCHAR(11) USING LEFT(category, 11);
+
+DROP TABLE IF EXISTS categories;
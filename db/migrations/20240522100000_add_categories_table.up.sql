@@ -0,0 +1,19 @@
+CREATE TABLE IF NOT EXISTS categories(
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    name VARCHAR(50) NOT NULL UNIQUE,
+    parent_id UUID NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+
+    CHECK(parent_id <> id),
+    FOREIGN KEY (parent_id) REFERENCES categories(id)
+    ON UPDATE CASCADE ON DELETE RESTRICT
+);
+
+CREATE INDEX IF NOT EXISTS idx_category_parent_id ON categories(parent_id);
+
+INSERT INTO categories (name) VALUES ('Clothing'), ('Accessories'), ('Footwear'), ('Beverages');
+INSERT INTO categories (name) SELECT DISTINCT category FROM products ON CONFLICT (name) DO NOTHING;
+
+ALTER TABLE products ALTER COLUMN category TYPE VARCHAR(50);
+ALTER TABLE products ADD CONSTRAINT products_category_fkey FOREIGN KEY (category) REFERENCES categories(name)
+    ON UPDATE CASCADE ON DELETE RESTRICT;
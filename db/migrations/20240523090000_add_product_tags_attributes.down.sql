@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_product_attributes;
+DROP INDEX IF EXISTS idx_product_tags;
+
+ALTER TABLE products DROP COLUMN IF EXISTS attributes;
+ALTER TABLE products DROP COLUMN IF EXISTS tags;
@@ -0,0 +1,5 @@
+ALTER TABLE products ADD COLUMN IF NOT EXISTS tags JSONB NOT NULL DEFAULT '[]';
+ALTER TABLE products ADD COLUMN IF NOT EXISTS attributes JSONB NOT NULL DEFAULT '{}';
+
+CREATE INDEX IF NOT EXISTS idx_product_tags ON products USING GIN(tags jsonb_path_ops);
+CREATE INDEX IF NOT EXISTS idx_product_attributes ON products USING GIN(attributes jsonb_path_ops);
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_product_notes_trgm;
+DROP INDEX IF EXISTS idx_product_sku_trgm;
+DROP INDEX IF EXISTS idx_product_name_trgm;
+
+CREATE INDEX IF NOT EXISTS idx_product_name ON products USING GIN(name);
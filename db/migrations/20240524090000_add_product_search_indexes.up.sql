@@ -0,0 +1,8 @@
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+-- idx_product_name was a btree_gin index, it can not serve LIKE or similarity lookups.
+DROP INDEX IF EXISTS idx_product_name;
+
+CREATE INDEX IF NOT EXISTS idx_product_name_trgm ON products USING GIN(LOWER(name) gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS idx_product_sku_trgm ON products USING GIN(LOWER(sku) gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS idx_product_notes_trgm ON products USING GIN(LOWER(notes) gin_trgm_ops);
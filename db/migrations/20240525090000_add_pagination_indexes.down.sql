@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS idx_transfer_sent_at_id;
+DROP INDEX IF EXISTS idx_trx_created_at_id;
+DROP INDEX IF EXISTS idx_customer_created_at_id;
+DROP INDEX IF EXISTS idx_product_created_at_id;
+
+ALTER TABLE transactions ALTER COLUMN created_at DROP NOT NULL;
+ALTER TABLE customers ALTER COLUMN created_at DROP NOT NULL;
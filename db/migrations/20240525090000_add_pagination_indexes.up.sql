@@ -0,0 +1,10 @@
+UPDATE customers SET created_at = NOW() WHERE created_at IS NULL;
+ALTER TABLE customers ALTER COLUMN created_at SET NOT NULL;
+
+UPDATE transactions SET created_at = NOW() WHERE created_at IS NULL;
+ALTER TABLE transactions ALTER COLUMN created_at SET NOT NULL;
+
+CREATE INDEX IF NOT EXISTS idx_product_created_at_id ON products(created_at, id);
+CREATE INDEX IF NOT EXISTS idx_customer_created_at_id ON customers(created_at, id);
+CREATE INDEX IF NOT EXISTS idx_trx_created_at_id ON transactions(created_at, id);
+CREATE INDEX IF NOT EXISTS idx_transfer_sent_at_id ON stock_transfers(sent_at, id);
@@ -0,0 +1,12 @@
+DROP INDEX IF EXISTS idx_trx_detail_product_id;
+DROP INDEX IF EXISTS idx_product_deleted_at_id;
+
+ALTER TABLE stock_transfer_items DROP CONSTRAINT IF EXISTS stock_transfer_items_product_id_fkey;
+ALTER TABLE stock_transfer_items ADD CONSTRAINT stock_transfer_items_product_id_fkey FOREIGN KEY (product_id) REFERENCES products(id)
+    ON UPDATE CASCADE ON DELETE CASCADE;
+
+ALTER TABLE transaction_detail DROP CONSTRAINT IF EXISTS transaction_detail_product_id_fkey;
+ALTER TABLE transaction_detail ADD CONSTRAINT transaction_detail_product_id_fkey FOREIGN KEY (product_id) REFERENCES products(id)
+    ON UPDATE CASCADE ON DELETE CASCADE;
+
+ALTER TABLE staffs DROP COLUMN IF EXISTS is_admin;
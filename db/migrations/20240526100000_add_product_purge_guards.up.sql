@@ -0,0 +1,13 @@
+ALTER TABLE staffs ADD COLUMN IF NOT EXISTS is_admin BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- sales and transfer lines must survive a purged product
+ALTER TABLE transaction_detail DROP CONSTRAINT IF EXISTS transaction_detail_product_id_fkey;
+ALTER TABLE transaction_detail ADD CONSTRAINT transaction_detail_product_id_fkey FOREIGN KEY (product_id) REFERENCES products(id)
+    ON UPDATE CASCADE ON DELETE RESTRICT;
+
+ALTER TABLE stock_transfer_items DROP CONSTRAINT IF EXISTS stock_transfer_items_product_id_fkey;
+ALTER TABLE stock_transfer_items ADD CONSTRAINT stock_transfer_items_product_id_fkey FOREIGN KEY (product_id) REFERENCES products(id)
+    ON UPDATE CASCADE ON DELETE RESTRICT;
+
+CREATE INDEX IF NOT EXISTS idx_product_deleted_at_id ON products(deleted_at, id) WHERE deleted_at IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_trx_detail_product_id ON transaction_detail(product_id);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS product_price_history;
+DROP TABLE IF EXISTS price_schedules;
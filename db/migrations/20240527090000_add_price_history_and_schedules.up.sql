@@ -0,0 +1,41 @@
+CREATE TABLE IF NOT EXISTS price_schedules(
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    product_id UUID NOT NULL,
+    price INT NOT NULL CHECK(price >= 1),
+    previous_price INT NULL,
+    starts_at TIMESTAMP NOT NULL,
+    ends_at TIMESTAMP NULL CHECK(ends_at > starts_at),
+    status VARCHAR(10) NOT NULL DEFAULT 'pending',
+    created_by UUID NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    applied_at TIMESTAMP NULL,
+    reverted_at TIMESTAMP NULL,
+    FOREIGN KEY (product_id) REFERENCES products(id)
+    ON UPDATE CASCADE ON DELETE CASCADE,
+    FOREIGN KEY (created_by) REFERENCES staffs(id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_price_schedule_product_id ON price_schedules(product_id);
+CREATE INDEX IF NOT EXISTS idx_price_schedule_pending ON price_schedules(starts_at) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS idx_price_schedule_active ON price_schedules(ends_at) WHERE status = 'active';
+
+CREATE TABLE IF NOT EXISTS product_price_history(
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    product_id UUID NOT NULL,
+    old_price INT NULL,
+    new_price INT NOT NULL,
+    changed_by UUID NULL,
+    schedule_id UUID NULL,
+    changed_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    FOREIGN KEY (product_id) REFERENCES products(id)
+    ON UPDATE CASCADE ON DELETE CASCADE,
+    FOREIGN KEY (changed_by) REFERENCES staffs(id),
+    FOREIGN KEY (schedule_id) REFERENCES price_schedules(id)
+    ON DELETE SET NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_price_history_product_changed_at ON product_price_history(product_id, changed_at, id);
+
+-- existing products start their history at the current price
+INSERT INTO product_price_history (product_id, old_price, new_price, changed_at)
+SELECT id, NULL, price, created_at FROM products;
@@ -0,0 +1,7 @@
+DROP TABLE IF EXISTS goods_receipt_items;
+DROP TABLE IF EXISTS goods_receipts;
+
+ALTER TABLE transaction_detail DROP COLUMN IF EXISTS unit_cost;
+ALTER TABLE transaction_detail DROP COLUMN IF EXISTS unit_price;
+
+ALTER TABLE products DROP COLUMN IF EXISTS cost_price;
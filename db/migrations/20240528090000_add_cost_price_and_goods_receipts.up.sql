@@ -0,0 +1,39 @@
+ALTER TABLE products ADD COLUMN IF NOT EXISTS cost_price INT NOT NULL DEFAULT 0 CHECK(cost_price >= 0);
+
+-- price and cost of each sold line at the time of sale
+ALTER TABLE transaction_detail ADD COLUMN IF NOT EXISTS unit_price INT NULL;
+ALTER TABLE transaction_detail ADD COLUMN IF NOT EXISTS unit_cost INT NULL;
+
+UPDATE transaction_detail td SET unit_price = p.price, unit_cost = p.cost_price
+FROM products p WHERE p.id = td.product_id AND td.unit_price IS NULL;
+
+ALTER TABLE transaction_detail ALTER COLUMN unit_price SET NOT NULL;
+ALTER TABLE transaction_detail ALTER COLUMN unit_cost SET NOT NULL;
+
+CREATE TABLE IF NOT EXISTS goods_receipts(
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    location_id UUID NOT NULL,
+    supplier VARCHAR(100) NOT NULL DEFAULT '',
+    notes VARCHAR(200) NOT NULL DEFAULT '',
+    received_by UUID NOT NULL,
+    received_at TIMESTAMP NOT NULL DEFAULT NOW(),
+
+    FOREIGN KEY (location_id) REFERENCES locations(id)
+    ON UPDATE CASCADE ON DELETE RESTRICT,
+    FOREIGN KEY (received_by) REFERENCES staffs(id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_goods_receipt_received_at_id ON goods_receipts(received_at, id);
+
+CREATE TABLE IF NOT EXISTS goods_receipt_items(
+    receipt_id UUID NOT NULL,
+    product_id UUID NOT NULL,
+    quantity INT NOT NULL CHECK(quantity >= 1),
+    unit_cost INT NOT NULL CHECK(unit_cost >= 0),
+
+    PRIMARY KEY (receipt_id, product_id),
+    FOREIGN KEY (receipt_id) REFERENCES goods_receipts(id)
+    ON UPDATE CASCADE ON DELETE CASCADE,
+    FOREIGN KEY (product_id) REFERENCES products(id)
+    ON UPDATE CASCADE ON DELETE RESTRICT
+);
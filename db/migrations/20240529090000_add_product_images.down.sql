@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS product_images;
+
+ALTER TABLE products DROP COLUMN IF EXISTS thumbnail_url;
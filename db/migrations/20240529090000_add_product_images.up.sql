@@ -0,0 +1,19 @@
+ALTER TABLE products ADD COLUMN IF NOT EXISTS thumbnail_url TEXT NOT NULL DEFAULT '';
+
+-- image uploaded through the api, the objects live in the configured storage
+CREATE TABLE IF NOT EXISTS product_images(
+    product_id UUID PRIMARY KEY,
+    image_key VARCHAR(200) NOT NULL,
+    thumbnail_key VARCHAR(200) NOT NULL,
+    content_type VARCHAR(50) NOT NULL,
+    size INT NOT NULL,
+    width INT NOT NULL,
+    height INT NOT NULL,
+    uploaded_by UUID NULL,
+    uploaded_at TIMESTAMP NOT NULL DEFAULT NOW(),
+
+    FOREIGN KEY (product_id) REFERENCES products(id)
+    ON UPDATE CASCADE ON DELETE CASCADE,
+    FOREIGN KEY (uploaded_by) REFERENCES staffs(id)
+    ON UPDATE CASCADE ON DELETE SET NULL
+);
@@ -0,0 +1,6 @@
+DELETE FROM transaction_detail WHERE bundle_id IS NOT NULL;
+ALTER TABLE transaction_detail DROP COLUMN IF EXISTS bundle_id;
+
+DROP TABLE IF EXISTS bundle_items;
+
+ALTER TABLE products DROP COLUMN IF EXISTS is_bundle;
@@ -0,0 +1,21 @@
+ALTER TABLE products ADD COLUMN IF NOT EXISTS is_bundle BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE TABLE IF NOT EXISTS bundle_items(
+    bundle_id UUID NOT NULL,
+    product_id UUID NOT NULL,
+    quantity INT NOT NULL CHECK(quantity > 0),
+
+    PRIMARY KEY (bundle_id, product_id),
+    FOREIGN KEY (bundle_id) REFERENCES products(id)
+    ON UPDATE CASCADE ON DELETE CASCADE,
+    FOREIGN KEY (product_id) REFERENCES products(id)
+    ON UPDATE CASCADE ON DELETE RESTRICT
+);
+
+CREATE INDEX IF NOT EXISTS idx_bundle_items_product_id ON bundle_items(product_id);
+
+-- component lines of a sold bundle point at the bundle line product
+ALTER TABLE transaction_detail ADD COLUMN IF NOT EXISTS bundle_id UUID NULL
+    REFERENCES products(id) ON UPDATE CASCADE ON DELETE RESTRICT;
+
+CREATE INDEX IF NOT EXISTS idx_trx_detail_bundle_id ON transaction_detail(bundle_id) WHERE bundle_id IS NOT NULL;
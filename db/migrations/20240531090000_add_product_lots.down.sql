@@ -0,0 +1,6 @@
+DROP TABLE IF EXISTS stock_transfer_lots;
+DROP TABLE IF EXISTS transaction_lots;
+DROP TABLE IF EXISTS product_lots;
+
+ALTER TABLE goods_receipt_items DROP COLUMN IF EXISTS expires_at;
+ALTER TABLE goods_receipt_items DROP COLUMN IF EXISTS lot_number;
@@ -0,0 +1,51 @@
+ALTER TABLE goods_receipt_items ADD COLUMN IF NOT EXISTS lot_number VARCHAR(50) NOT NULL DEFAULT '';
+ALTER TABLE goods_receipt_items ADD COLUMN IF NOT EXISTS expires_at DATE NULL;
+
+-- part of the stock of a product at a location that came in as one batch,
+-- stock not covered by any lot is untracked
+CREATE TABLE IF NOT EXISTS product_lots(
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    product_id UUID NOT NULL,
+    location_id UUID NOT NULL,
+    lot_number VARCHAR(50) NOT NULL DEFAULT '',
+    expires_at DATE NULL,
+    quantity INT NOT NULL CHECK(quantity >= 0),
+    receipt_id UUID NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+
+    FOREIGN KEY (product_id) REFERENCES products(id)
+    ON UPDATE CASCADE ON DELETE CASCADE,
+    FOREIGN KEY (location_id) REFERENCES locations(id)
+    ON UPDATE CASCADE ON DELETE RESTRICT,
+    FOREIGN KEY (receipt_id) REFERENCES goods_receipts(id)
+    ON UPDATE CASCADE ON DELETE SET NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_product_lots_product_location ON product_lots(product_id, location_id, expires_at) WHERE quantity > 0;
+CREATE INDEX IF NOT EXISTS idx_product_lots_expires_at ON product_lots(expires_at) WHERE quantity > 0;
+
+-- lots taken by a sale, so a recalled batch can be traced to its customers
+CREATE TABLE IF NOT EXISTS transaction_lots(
+    transaction_id UUID NOT NULL,
+    lot_id UUID NOT NULL,
+    quantity INT NOT NULL CHECK(quantity > 0),
+
+    PRIMARY KEY (transaction_id, lot_id),
+    FOREIGN KEY (transaction_id) REFERENCES transactions(id)
+    ON UPDATE CASCADE ON DELETE CASCADE,
+    FOREIGN KEY (lot_id) REFERENCES product_lots(id)
+    ON UPDATE CASCADE ON DELETE CASCADE
+);
+
+-- lots in transit, recreated at the destination or put back when cancelled
+CREATE TABLE IF NOT EXISTS stock_transfer_lots(
+    transfer_id UUID NOT NULL,
+    lot_id UUID NOT NULL,
+    quantity INT NOT NULL CHECK(quantity > 0),
+
+    PRIMARY KEY (transfer_id, lot_id),
+    FOREIGN KEY (transfer_id) REFERENCES stock_transfers(id)
+    ON UPDATE CASCADE ON DELETE CASCADE,
+    FOREIGN KEY (lot_id) REFERENCES product_lots(id)
+    ON UPDATE CASCADE ON DELETE CASCADE
+);
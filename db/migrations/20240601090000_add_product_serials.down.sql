@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS product_serials;
+
+ALTER TABLE products DROP COLUMN IF EXISTS track_serials;
@@ -0,0 +1,31 @@
+ALTER TABLE products ADD COLUMN IF NOT EXISTS track_serials BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- one physical unit of a serial tracked product
+CREATE TABLE IF NOT EXISTS product_serials(
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    product_id UUID NOT NULL,
+    serial VARCHAR(100) NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'in_stock',
+    location_id UUID NOT NULL,
+    receipt_id UUID NULL,
+    transfer_id UUID NULL,
+    transaction_id UUID NULL,
+    received_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    sold_at TIMESTAMP NULL,
+
+    UNIQUE (product_id, serial),
+    FOREIGN KEY (product_id) REFERENCES products(id)
+    ON UPDATE CASCADE ON DELETE CASCADE,
+    FOREIGN KEY (location_id) REFERENCES locations(id)
+    ON UPDATE CASCADE ON DELETE RESTRICT,
+    FOREIGN KEY (receipt_id) REFERENCES goods_receipts(id)
+    ON UPDATE CASCADE ON DELETE SET NULL,
+    FOREIGN KEY (transfer_id) REFERENCES stock_transfers(id)
+    ON UPDATE CASCADE ON DELETE SET NULL,
+    FOREIGN KEY (transaction_id) REFERENCES transactions(id)
+    ON UPDATE CASCADE ON DELETE SET NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_product_serials_serial ON product_serials(serial);
+CREATE INDEX IF NOT EXISTS idx_product_serials_transaction_id ON product_serials(transaction_id) WHERE transaction_id IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_product_serials_transfer_id ON product_serials(transfer_id) WHERE transfer_id IS NOT NULL;
@@ -0,0 +1,22 @@
+ALTER TABLE transaction_detail DROP COLUMN IF EXISTS total_price;
+ALTER TABLE transaction_detail ALTER COLUMN quantity TYPE INT USING CEIL(quantity);
+
+ALTER TABLE goods_receipt_items DROP CONSTRAINT IF EXISTS goods_receipt_items_quantity_check;
+ALTER TABLE goods_receipt_items ALTER COLUMN quantity TYPE INT USING CEIL(quantity);
+ALTER TABLE goods_receipt_items ADD CONSTRAINT goods_receipt_items_quantity_check CHECK(quantity >= 1);
+
+ALTER TABLE stock_transfer_items DROP CONSTRAINT IF EXISTS stock_transfer_items_quantity_check;
+ALTER TABLE stock_transfer_items ALTER COLUMN quantity TYPE INT USING CEIL(quantity);
+ALTER TABLE stock_transfer_items ADD CONSTRAINT stock_transfer_items_quantity_check CHECK(quantity >= 1);
+
+ALTER TABLE stock_transfer_lots ALTER COLUMN quantity TYPE INT USING CEIL(quantity);
+ALTER TABLE transaction_lots ALTER COLUMN quantity TYPE INT USING CEIL(quantity);
+ALTER TABLE product_lots ALTER COLUMN quantity TYPE INT USING FLOOR(quantity);
+ALTER TABLE bundle_items ALTER COLUMN quantity TYPE INT USING CEIL(quantity);
+ALTER TABLE product_stocks ALTER COLUMN stock TYPE INT USING FLOOR(stock);
+ALTER TABLE products ALTER COLUMN stock TYPE INT USING FLOOR(stock);
+
+ALTER TABLE products DROP COLUMN IF EXISTS purchase_unit_factor;
+ALTER TABLE products DROP COLUMN IF EXISTS purchase_unit;
+ALTER TABLE products DROP COLUMN IF EXISTS quantity_precision;
+ALTER TABLE products DROP COLUMN IF EXISTS unit;
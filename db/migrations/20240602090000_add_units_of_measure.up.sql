@@ -0,0 +1,28 @@
+-- products are sold by the piece, metre, kilogram or litre and may be bought in
+-- a bigger purchase unit, e.g. a case of 24 cans
+ALTER TABLE products ADD COLUMN IF NOT EXISTS unit VARCHAR(10) NOT NULL DEFAULT 'pcs' CHECK(unit IN ('pcs', 'm', 'kg', 'l'));
+ALTER TABLE products ADD COLUMN IF NOT EXISTS quantity_precision SMALLINT NOT NULL DEFAULT 0 CHECK(quantity_precision BETWEEN 0 AND 3);
+ALTER TABLE products ADD COLUMN IF NOT EXISTS purchase_unit VARCHAR(20) NOT NULL DEFAULT '';
+ALTER TABLE products ADD COLUMN IF NOT EXISTS purchase_unit_factor NUMERIC(14,3) NOT NULL DEFAULT 1 CHECK(purchase_unit_factor > 0);
+
+-- quantities are kept with three decimals
+ALTER TABLE products ALTER COLUMN stock TYPE NUMERIC(14,3);
+ALTER TABLE product_stocks ALTER COLUMN stock TYPE NUMERIC(14,3);
+ALTER TABLE bundle_items ALTER COLUMN quantity TYPE NUMERIC(14,3);
+ALTER TABLE product_lots ALTER COLUMN quantity TYPE NUMERIC(14,3);
+ALTER TABLE transaction_lots ALTER COLUMN quantity TYPE NUMERIC(14,3);
+ALTER TABLE stock_transfer_lots ALTER COLUMN quantity TYPE NUMERIC(14,3);
+
+ALTER TABLE stock_transfer_items ALTER COLUMN quantity TYPE NUMERIC(14,3);
+ALTER TABLE stock_transfer_items DROP CONSTRAINT IF EXISTS stock_transfer_items_quantity_check;
+ALTER TABLE stock_transfer_items ADD CONSTRAINT stock_transfer_items_quantity_check CHECK(quantity > 0);
+
+ALTER TABLE goods_receipt_items ALTER COLUMN quantity TYPE NUMERIC(14,3);
+ALTER TABLE goods_receipt_items DROP CONSTRAINT IF EXISTS goods_receipt_items_quantity_check;
+ALTER TABLE goods_receipt_items ADD CONSTRAINT goods_receipt_items_quantity_check CHECK(quantity > 0);
+
+-- a sold line keeps its rounded total, reports sum what the customer paid
+ALTER TABLE transaction_detail ALTER COLUMN quantity TYPE NUMERIC(14,3);
+ALTER TABLE transaction_detail ADD COLUMN IF NOT EXISTS total_price BIGINT NULL;
+UPDATE transaction_detail SET total_price = ROUND(quantity * unit_price);
+ALTER TABLE transaction_detail ALTER COLUMN total_price SET NOT NULL;
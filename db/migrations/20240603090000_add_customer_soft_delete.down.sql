@@ -0,0 +1,20 @@
+ALTER TABLE transactions DROP CONSTRAINT IF EXISTS transactions_customer_id_fkey;
+ALTER TABLE transactions ADD CONSTRAINT transactions_customer_id_fkey FOREIGN KEY (customer_id) REFERENCES customers(id)
+    ON UPDATE CASCADE ON DELETE CASCADE;
+
+-- deleted customers may share their number with another customer, they are
+-- kept for their transactions and get a placeholder number made of their id
+-- instead; the active customer, or else the newest, keeps the number
+UPDATE customers c SET phone_number = '#' || LEFT(REPLACE(c.id::TEXT, '-', ''), 15)
+FROM (
+    SELECT id, ROW_NUMBER() OVER (PARTITION BY phone_number
+        ORDER BY deleted_at IS NULL DESC, created_at DESC, id DESC) AS n
+    FROM customers
+) d
+WHERE d.id = c.id AND d.n > 1;
+
+DROP INDEX IF EXISTS idx_customer_phone_number;
+ALTER TABLE customers ADD CONSTRAINT customers_phone_number_key UNIQUE (phone_number);
+CREATE INDEX IF NOT EXISTS idx_customer_phone_number ON customers(phone_number);
+
+ALTER TABLE customers DROP COLUMN IF EXISTS deleted_at;
@@ -0,0 +1,11 @@
+ALTER TABLE customers ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP NULL DEFAULT NULL;
+
+-- a deleted customer frees its phone number
+ALTER TABLE customers DROP CONSTRAINT IF EXISTS customers_phone_number_key;
+DROP INDEX IF EXISTS idx_customer_phone_number;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_customer_phone_number ON customers(phone_number) WHERE deleted_at IS NULL;
+
+-- sales history must survive a deleted customer
+ALTER TABLE transactions DROP CONSTRAINT IF EXISTS transactions_customer_id_fkey;
+ALTER TABLE transactions ADD CONSTRAINT transactions_customer_id_fkey FOREIGN KEY (customer_id) REFERENCES customers(id)
+    ON UPDATE CASCADE ON DELETE RESTRICT;
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_customer_name;
+DROP INDEX IF EXISTS idx_customer_phone_digits_trgm;
+DROP INDEX IF EXISTS idx_customer_name_trgm;
@@ -0,0 +1,6 @@
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+-- partial name search and phone search however the number is written
+CREATE INDEX IF NOT EXISTS idx_customer_name_trgm ON customers USING GIN(LOWER(name) gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS idx_customer_phone_digits_trgm ON customers USING GIN(REGEXP_REPLACE(phone_number, '[^0-9]', '', 'g') gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS idx_customer_name ON customers(LOWER(name), created_at, id) WHERE deleted_at IS NULL;
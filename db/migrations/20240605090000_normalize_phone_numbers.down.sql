@@ -0,0 +1,2 @@
+-- numbers stay in E.164, it passes the previous validation as well
+DROP TABLE IF EXISTS phone_number_conflicts;
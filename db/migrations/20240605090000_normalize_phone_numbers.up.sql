@@ -0,0 +1,18 @@
+-- phone numbers are stored in E.164. Stored numbers were only checked for a
+-- leading "+", the application brings them to E.164 when it starts, through
+-- the same rules and PHONE_DEFAULT_REGION the API reads numbers with.
+
+-- numbers it can not bring to E.164: invalid numbers (normalized is NULL) and
+-- numbers that were written differently but are the same number. The row
+-- already in E.164, or else the oldest, gets the number and the others keep
+-- theirs until they are resolved.
+CREATE TABLE IF NOT EXISTS phone_number_conflicts(
+    table_name VARCHAR(20) NOT NULL,
+    row_id UUID NOT NULL,
+    phone_number VARCHAR(16) NOT NULL,
+    normalized VARCHAR(16) NULL,
+    duplicate_of UUID NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+
+    PRIMARY KEY (table_name, row_id)
+);
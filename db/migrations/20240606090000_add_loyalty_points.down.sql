@@ -0,0 +1,11 @@
+DROP TABLE IF EXISTS loyalty_points;
+DROP FUNCTION IF EXISTS loyalty_points_append_only();
+
+ALTER TABLE transactions DROP CONSTRAINT IF EXISTS transactions_refunded_by_fkey;
+ALTER TABLE transactions DROP COLUMN IF EXISTS refunded_by;
+ALTER TABLE transactions DROP COLUMN IF EXISTS refunded_at;
+ALTER TABLE transactions DROP COLUMN IF EXISTS points_discount;
+ALTER TABLE transactions DROP COLUMN IF EXISTS points_redeemed;
+
+DROP TABLE IF EXISTS loyalty_category_rates;
+DROP TABLE IF EXISTS loyalty_settings;
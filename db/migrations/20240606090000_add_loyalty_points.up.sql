@@ -0,0 +1,71 @@
+-- a single row holding how points are earned, redeemed and expire
+CREATE TABLE IF NOT EXISTS loyalty_settings(
+    id BOOLEAN PRIMARY KEY DEFAULT TRUE,
+    spend_per_point INT NOT NULL DEFAULT 10000,
+    point_value INT NOT NULL DEFAULT 100,
+    expiry_days INT NOT NULL DEFAULT 365,
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+
+    CHECK(id),
+    CHECK(spend_per_point >= 0),
+    CHECK(point_value >= 0),
+    CHECK(expiry_days >= 0)
+);
+
+INSERT INTO loyalty_settings DEFAULT VALUES ON CONFLICT DO NOTHING;
+
+-- a category earning at another rate than loyalty_settings.spend_per_point
+CREATE TABLE IF NOT EXISTS loyalty_category_rates(
+    category VARCHAR(50) PRIMARY KEY,
+    spend_per_point INT NOT NULL,
+
+    CHECK(spend_per_point >= 0),
+    FOREIGN KEY (category) REFERENCES categories(name)
+    ON UPDATE CASCADE ON DELETE CASCADE
+);
+
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS points_redeemed INT NOT NULL DEFAULT 0;
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS points_discount INT NOT NULL DEFAULT 0;
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS refunded_at TIMESTAMP NULL;
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS refunded_by UUID NULL;
+ALTER TABLE transactions ADD CONSTRAINT transactions_refunded_by_fkey FOREIGN KEY (refunded_by) REFERENCES staffs(id)
+    ON UPDATE CASCADE ON DELETE SET NULL;
+
+-- the points ledger, a balance is the sum of its entries. Positive entries
+-- are lots that can expire, negative entries take points out of them.
+CREATE TABLE IF NOT EXISTS loyalty_points(
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    customer_id UUID NOT NULL,
+    transaction_id UUID NULL,
+    type VARCHAR(10) NOT NULL,
+    points INT NOT NULL,
+    source_id UUID NULL,
+    expires_at TIMESTAMP NULL,
+    -- entries written in one transaction must keep their order
+    created_at TIMESTAMP NOT NULL DEFAULT CLOCK_TIMESTAMP(),
+
+    CHECK(type IN ('earn', 'redeem', 'reverse', 'expire')),
+    -- an expire entry of zero closes a lot that was already spent
+    CHECK(points <> 0 OR type = 'expire'),
+    FOREIGN KEY (customer_id) REFERENCES customers(id)
+    ON UPDATE CASCADE ON DELETE RESTRICT,
+    FOREIGN KEY (transaction_id) REFERENCES transactions(id)
+    ON UPDATE CASCADE ON DELETE RESTRICT,
+    FOREIGN KEY (source_id) REFERENCES loyalty_points(id)
+    ON UPDATE CASCADE ON DELETE RESTRICT
+);
+
+CREATE INDEX IF NOT EXISTS idx_loyalty_points_customer_id ON loyalty_points(customer_id, created_at, id);
+CREATE INDEX IF NOT EXISTS idx_loyalty_points_transaction_id ON loyalty_points(transaction_id) WHERE transaction_id IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_loyalty_points_source_id ON loyalty_points(source_id) WHERE source_id IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_loyalty_points_expires_at ON loyalty_points(expires_at) WHERE points > 0 AND expires_at IS NOT NULL;
+
+CREATE OR REPLACE FUNCTION loyalty_points_append_only() RETURNS TRIGGER AS $$
+BEGIN
+    RAISE EXCEPTION 'loyalty_points is append only';
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS loyalty_points_append_only ON loyalty_points;
+CREATE TRIGGER loyalty_points_append_only BEFORE UPDATE OR DELETE ON loyalty_points
+    FOR EACH ROW EXECUTE FUNCTION loyalty_points_append_only();
@@ -0,0 +1,4 @@
+ALTER TABLE transactions DROP COLUMN IF EXISTS stored_value_paid;
+
+DROP TABLE IF EXISTS stored_value_movements;
+DROP TABLE IF EXISTS stored_value_accounts;
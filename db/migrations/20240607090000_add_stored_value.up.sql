@@ -0,0 +1,46 @@
+-- a gift card, found by its code, or the store credit of a customer
+CREATE TABLE IF NOT EXISTS stored_value_accounts(
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    type VARCHAR(10) NOT NULL,
+    code VARCHAR(32) NULL UNIQUE,
+    customer_id UUID NULL UNIQUE,
+    balance INT NOT NULL DEFAULT 0,
+    created_by UUID NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+
+    CHECK(balance >= 0),
+    CHECK((type = 'gift_card' AND code IS NOT NULL AND customer_id IS NULL)
+        OR (type = 'credit' AND customer_id IS NOT NULL AND code IS NULL)),
+    FOREIGN KEY (customer_id) REFERENCES customers(id)
+    ON UPDATE CASCADE ON DELETE RESTRICT,
+    FOREIGN KEY (created_by) REFERENCES staffs(id)
+    ON UPDATE CASCADE ON DELETE SET NULL
+);
+
+-- every change of a balance, balance_after is the balance it left
+CREATE TABLE IF NOT EXISTS stored_value_movements(
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    account_id UUID NOT NULL,
+    type VARCHAR(10) NOT NULL,
+    amount INT NOT NULL,
+    balance_after INT NOT NULL,
+    transaction_id UUID NULL,
+    staff_id UUID NULL,
+    note VARCHAR(255) NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT CLOCK_TIMESTAMP(),
+
+    CHECK(type IN ('issue', 'top_up', 'redeem', 'refund')),
+    CHECK(amount <> 0),
+    CHECK(balance_after >= 0),
+    FOREIGN KEY (account_id) REFERENCES stored_value_accounts(id)
+    ON UPDATE CASCADE ON DELETE RESTRICT,
+    FOREIGN KEY (transaction_id) REFERENCES transactions(id)
+    ON UPDATE CASCADE ON DELETE RESTRICT,
+    FOREIGN KEY (staff_id) REFERENCES staffs(id)
+    ON UPDATE CASCADE ON DELETE SET NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_stored_value_movements_account_id ON stored_value_movements(account_id, created_at, id);
+CREATE INDEX IF NOT EXISTS idx_stored_value_movements_transaction_id ON stored_value_movements(transaction_id) WHERE transaction_id IS NOT NULL;
+
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS stored_value_paid INT NOT NULL DEFAULT 0;
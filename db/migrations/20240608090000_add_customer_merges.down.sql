@@ -0,0 +1,14 @@
+ALTER TABLE stored_value_movements DROP CONSTRAINT IF EXISTS stored_value_movements_type_check;
+ALTER TABLE stored_value_movements ADD CONSTRAINT stored_value_movements_type_check
+    CHECK(type IN ('issue', 'top_up', 'redeem', 'refund')) NOT VALID;
+
+ALTER TABLE loyalty_points DROP CONSTRAINT IF EXISTS loyalty_points_type_check;
+ALTER TABLE loyalty_points ADD CONSTRAINT loyalty_points_type_check
+    CHECK(type IN ('earn', 'redeem', 'reverse', 'expire')) NOT VALID;
+
+ALTER TABLE phone_number_conflicts DROP COLUMN IF EXISTS resolved_at;
+
+DROP TABLE IF EXISTS customer_merges;
+
+ALTER TABLE customers DROP CONSTRAINT IF EXISTS customers_merged_into_fkey;
+ALTER TABLE customers DROP COLUMN IF EXISTS merged_into;
@@ -0,0 +1,37 @@
+ALTER TABLE customers ADD COLUMN IF NOT EXISTS merged_into UUID NULL;
+ALTER TABLE customers ADD CONSTRAINT customers_merged_into_fkey FOREIGN KEY (merged_into) REFERENCES customers(id)
+    ON UPDATE CASCADE ON DELETE RESTRICT;
+
+-- one row per duplicate merged into a surviving customer
+CREATE TABLE IF NOT EXISTS customer_merges(
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    survivor_id UUID NOT NULL,
+    duplicate_id UUID NOT NULL,
+    staff_id UUID NULL,
+    transactions INT NOT NULL DEFAULT 0,
+    points INT NOT NULL DEFAULT 0,
+    credit INT NOT NULL DEFAULT 0,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+
+    FOREIGN KEY (survivor_id) REFERENCES customers(id)
+    ON UPDATE CASCADE ON DELETE RESTRICT,
+    FOREIGN KEY (duplicate_id) REFERENCES customers(id)
+    ON UPDATE CASCADE ON DELETE RESTRICT,
+    FOREIGN KEY (staff_id) REFERENCES staffs(id)
+    ON UPDATE CASCADE ON DELETE SET NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_customer_merges_survivor_id ON customer_merges(survivor_id);
+CREATE INDEX IF NOT EXISTS idx_customer_merges_duplicate_id ON customer_merges(duplicate_id);
+
+-- a merge closes the phone number conflicts of its duplicates
+ALTER TABLE phone_number_conflicts ADD COLUMN IF NOT EXISTS resolved_at TIMESTAMP NULL;
+
+-- points and credit move between customers through merge entries
+ALTER TABLE loyalty_points DROP CONSTRAINT IF EXISTS loyalty_points_type_check;
+ALTER TABLE loyalty_points ADD CONSTRAINT loyalty_points_type_check
+    CHECK(type IN ('earn', 'redeem', 'reverse', 'expire', 'merge'));
+
+ALTER TABLE stored_value_movements DROP CONSTRAINT IF EXISTS stored_value_movements_type_check;
+ALTER TABLE stored_value_movements ADD CONSTRAINT stored_value_movements_type_check
+    CHECK(type IN ('issue', 'top_up', 'redeem', 'refund', 'merge'));
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS customer_privacy_log;
+
+ALTER TABLE customers DROP COLUMN IF EXISTS erased_at;
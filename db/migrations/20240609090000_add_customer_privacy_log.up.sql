@@ -0,0 +1,18 @@
+ALTER TABLE customers ADD COLUMN IF NOT EXISTS erased_at TIMESTAMP NULL;
+
+-- data subject requests answered for a customer, kept for compliance
+CREATE TABLE IF NOT EXISTS customer_privacy_log(
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    customer_id UUID NOT NULL,
+    action VARCHAR(10) NOT NULL,
+    staff_id UUID NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+
+    CHECK(action IN ('export', 'erase')),
+    FOREIGN KEY (customer_id) REFERENCES customers(id)
+    ON UPDATE CASCADE ON DELETE RESTRICT,
+    FOREIGN KEY (staff_id) REFERENCES staffs(id)
+    ON UPDATE CASCADE ON DELETE SET NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_customer_privacy_log_customer_id ON customer_privacy_log(customer_id, created_at);
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_transactions_customer_id_created_at;
+
+DROP TABLE IF EXISTS customer_segments;
+DROP TABLE IF EXISTS customer_tags;
@@ -0,0 +1,24 @@
+CREATE TABLE IF NOT EXISTS customer_tags(
+    customer_id UUID NOT NULL,
+    tag VARCHAR(50) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+
+    PRIMARY KEY (customer_id, tag),
+    FOREIGN KEY (customer_id) REFERENCES customers(id)
+    ON UPDATE CASCADE ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_customer_tags_tag ON customer_tags(tag);
+
+-- a segment is a set of rules, its members are found when it is used
+CREATE TABLE IF NOT EXISTS customer_segments(
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    name VARCHAR(50) NOT NULL UNIQUE,
+    description VARCHAR(255) NOT NULL DEFAULT '',
+    rules JSONB NOT NULL DEFAULT '{}',
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+-- segment rules total the purchases of a customer over a period
+CREATE INDEX IF NOT EXISTS idx_transactions_customer_id_created_at ON transactions(customer_id, created_at) WHERE refunded_at IS NULL;
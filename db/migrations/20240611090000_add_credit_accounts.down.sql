@@ -0,0 +1,4 @@
+ALTER TABLE transactions DROP COLUMN IF EXISTS account_charged;
+
+DROP TABLE IF EXISTS credit_account_entries;
+DROP TABLE IF EXISTS credit_accounts;
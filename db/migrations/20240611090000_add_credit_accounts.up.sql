@@ -0,0 +1,43 @@
+-- a customer allowed to buy on account, balance is what the customer owes
+CREATE TABLE IF NOT EXISTS credit_accounts(
+    customer_id UUID PRIMARY KEY,
+    credit_limit BIGINT NOT NULL,
+    balance BIGINT NOT NULL DEFAULT 0,
+    created_by UUID NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+
+    CHECK(credit_limit >= 0),
+    CHECK(balance >= 0),
+    FOREIGN KEY (customer_id) REFERENCES customers(id)
+    ON UPDATE CASCADE ON DELETE RESTRICT,
+    FOREIGN KEY (created_by) REFERENCES staffs(id)
+    ON UPDATE CASCADE ON DELETE SET NULL
+);
+
+-- charges raise the balance, payments and refunds lower it
+CREATE TABLE IF NOT EXISTS credit_account_entries(
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    customer_id UUID NOT NULL,
+    type VARCHAR(10) NOT NULL,
+    amount BIGINT NOT NULL,
+    balance_after BIGINT NOT NULL,
+    transaction_id UUID NULL,
+    staff_id UUID NULL,
+    note VARCHAR(255) NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT CLOCK_TIMESTAMP(),
+
+    CHECK(type IN ('charge', 'payment', 'refund')),
+    CHECK((type = 'charge' AND amount > 0) OR (type <> 'charge' AND amount < 0)),
+    CHECK(balance_after >= 0),
+    FOREIGN KEY (customer_id) REFERENCES credit_accounts(customer_id)
+    ON UPDATE CASCADE ON DELETE RESTRICT,
+    FOREIGN KEY (transaction_id) REFERENCES transactions(id)
+    ON UPDATE CASCADE ON DELETE RESTRICT,
+    FOREIGN KEY (staff_id) REFERENCES staffs(id)
+    ON UPDATE CASCADE ON DELETE SET NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_credit_account_entries_customer_id ON credit_account_entries(customer_id, created_at, id);
+
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS account_charged BIGINT NOT NULL DEFAULT 0;
@@ -0,0 +1,15 @@
+package entity
+
+import "time"
+
+type Category struct {
+	Id        string     `json:"id"`
+	Name      string     `json:"name"`
+	ParentId  *string    `json:"parentId" db:"parent_id"`
+	CreatedAt *time.Time `json:"createdAt" db:"created_at"`
+}
+
+type CategoryInsertUpdateRequest struct {
+	Name     string  `json:"name" validate:"required,min=1,max=50"`
+	ParentId *string `json:"parentId" validate:"omitempty,uuid"`
+}
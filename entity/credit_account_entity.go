@@ -0,0 +1,89 @@
+package entity
+
+import "time"
+
+const (
+	AccountCharge  = "charge"
+	AccountPayment = "payment"
+	AccountRefund  = "refund"
+)
+
+// CreditAccount lets a customer buy on account up to CreditLimit. Balance is
+// what the customer owes, it is the sum of the entries.
+type CreditAccount struct {
+	CustomerId  string         `json:"customerId"`
+	CreditLimit int            `json:"creditLimit"`
+	Balance     int            `json:"balance"`
+	CreatedAt   time.Time      `json:"createdAt"`
+	UpdatedAt   time.Time      `json:"updatedAt"`
+	Available   int            `json:"available" db:"-"`
+	Aging       *CreditAging   `json:"aging,omitempty" db:"-"`
+	Entries     []AccountEntry `json:"entries,omitempty" db:"-"`
+}
+
+// AccountEntry is a charge, raising the balance, or a payment or refund,
+// lowering it.
+type AccountEntry struct {
+	Id            string    `json:"id"`
+	CustomerId    string    `json:"-"`
+	Type          string    `json:"type"`
+	Amount        int       `json:"amount"`
+	BalanceAfter  int       `json:"balanceAfter"`
+	TransactionId *string   `json:"transactionId"`
+	StaffId       *string   `json:"staffId"`
+	Note          string    `json:"note"`
+	CreatedAt     time.Time `json:"createdAt"`
+}
+
+// CreditAging splits the balance of a customer by the age of the charges it
+// is made of. Payments settle the oldest charges first.
+type CreditAging struct {
+	CustomerId  string `json:"customerId"`
+	Name        string `json:"name"`
+	CreditLimit int    `json:"creditLimit"`
+	Balance     int    `json:"balance"`
+	Days0To30   int    `json:"days0To30"`
+	Days31To60  int    `json:"days31To60"`
+	DaysOver60  int    `json:"daysOver60"`
+}
+
+type CreditAgingReport struct {
+	AsOf       time.Time     `json:"asOf"`
+	Customers  []CreditAging `json:"customers"`
+	Balance    int           `json:"balance"`
+	Days0To30  int           `json:"days0To30"`
+	Days31To60 int           `json:"days31To60"`
+	DaysOver60 int           `json:"daysOver60"`
+}
+
+// CreditStatement is the account of a customer over a month. Charges,
+// Payments and Refunds are the totals of the entries of the month.
+type CreditStatement struct {
+	CustomerId     string         `json:"customerId"`
+	Name           string         `json:"name"`
+	Month          string         `json:"month"`
+	From           time.Time      `json:"from"`
+	To             time.Time      `json:"to"`
+	CreditLimit    int            `json:"creditLimit"`
+	OpeningBalance int            `json:"openingBalance"`
+	Charges        int            `json:"charges"`
+	Payments       int            `json:"payments"`
+	Refunds        int            `json:"refunds"`
+	ClosingBalance int            `json:"closingBalance"`
+	Entries        []AccountEntry `json:"entries"`
+	Aging          CreditAging    `json:"aging"`
+}
+
+type CreditAccountRequest struct {
+	CreditLimit *int `json:"creditLimit" validate:"required,min=0"`
+}
+
+type AccountPaymentRequest struct {
+	Amount int    `json:"amount" validate:"required,min=1"`
+	Note   string `json:"note" validate:"max=255"`
+}
+
+type AccountQueryParams struct {
+	PageParams
+	CreatedAt string
+}
@@ -1,17 +1,97 @@
 package entity
 
+import "time"
+
 type Customer struct {
-	UserId      string `json:"userId"`
-	PhoneNumber string `json:"phoneNumber"`
-	Name        string `json:"name"`
+	UserId      string     `json:"userId"`
+	PhoneNumber string     `json:"phoneNumber"`
+	Name        string     `json:"name"`
+	CreatedAt   *time.Time `json:"createdAt" db:"created_at"`
 }
 
 type CustomerQueryParams struct {
+	PageParams
 	PhoneNumber string `json:"phoneNumber"`
 	Name        string `json:"name"`
+	CreatedAt   string `json:"createdAt"`
+	SortName    string `json:"sortName"`
+	Tag         string `json:"tag"`
+	SegmentId   string `json:"segmentId"`
+
+	// Segment limits the customers to the members of a segment, it is the
+	// rules of SegmentId once found
+	Segment *SegmentRules `json:"-"`
 }
 
 type CustomerInsertUpdateRequest struct {
 	Name        string `json:"name" validate:"required,min=5,max=50"`
-	PhoneNumber string `json:"phoneNumber" validate:"required,min=10,max=16,startswith=+,valid_phone"`
+	PhoneNumber string `json:"phoneNumber" validate:"required,max=30,valid_phone"`
+}
+
+// CustomerSummary is what a customer bought over all their transactions.
+type CustomerSummary struct {
+	CustomerId          string              `json:"customerId"`
+	TotalSpend          int                 `json:"totalSpend"`
+	VisitCount          int                 `json:"visitCount"`
+	AverageBasket       int                 `json:"averageBasket"`
+	FirstPurchaseAt     *time.Time          `json:"firstPurchaseAt"`
+	LastPurchaseAt      *time.Time          `json:"lastPurchaseAt"`
+	FavouriteCategories []FavouriteCategory `json:"favouriteCategories"`
+}
+
+type FavouriteCategory struct {
+	Category   string `json:"category"`
+	VisitCount int    `json:"visitCount"`
+	Spend      int    `json:"spend"`
+}
+
+type CustomerMergeRequest struct {
+	DuplicateIds []string `json:"duplicateIds" validate:"required,min=1,max=20,unique,dive,uuid"`
+}
+
+// CustomerMerge records one duplicate merged into a surviving customer and
+// what was moved over.
+type CustomerMerge struct {
+	Id           string    `json:"id"`
+	SurvivorId   string    `json:"survivorId"`
+	DuplicateId  string    `json:"duplicateId"`
+	StaffId      *string   `json:"staffId"`
+	Transactions int       `json:"transactions"`
+	Points       int       `json:"points"`
+	Credit       int       `json:"credit"`
+	CreatedAt    time.Time `json:"createdAt"`
+}
+
+// DuplicateCustomer is a pair of customers that look like the same person,
+// Customer is the older one and the suggested survivor.
+type DuplicateCustomer struct {
+	Customer       Customer `json:"customer"`
+	Duplicate      Customer `json:"duplicate"`
+	SamePhone      bool     `json:"samePhone"`
+	NameSimilarity float64  `json:"nameSimilarity"`
+}
+
+type DuplicateQueryParams struct {
+	PageParams
+	MinSimilarity float64
+}
+
+const (
+	PrivacyExport = "export"
+	PrivacyErase  = "erase"
+)
+
+// CustomerExport is everything stored about a customer, sent as the answer
+// to a data subject access request.
+type CustomerExport struct {
+	ExportedAt    time.Time           `json:"exportedAt"`
+	Customer      Customer            `json:"customer"`
+	Tags          []string            `json:"tags"`
+	DeletedAt     *time.Time          `json:"deletedAt"`
+	ErasedAt      *time.Time          `json:"erasedAt"`
+	Transactions  []Transaction       `json:"transactions"`
+	Points        []PointEntry        `json:"points"`
+	StoreCredit   *StoredValueAccount `json:"storeCredit"`
+	CreditAccount *CreditAccount      `json:"creditAccount"`
+	Merges        []CustomerMerge     `json:"merges"`
 }
@@ -0,0 +1,36 @@
+package entity
+
+import "time"
+
+type GoodsReceiptItem struct {
+	ProductId string   `json:"productId" validate:"required,uuid"`
+	Quantity  Quantity `json:"quantity" validate:"required,gt=0,max=100000000"`
+	// Unit is the product unit or its purchase unit, quantity and unitCost are in it
+	Unit      string   `json:"unit,omitempty" validate:"max=20"`
+	UnitCost  *int     `json:"unitCost" validate:"required,min=0"`
+	LotNumber string   `json:"lotNumber" validate:"max=50"`
+	ExpiresAt *string  `json:"expiresAt" validate:"omitempty,datetime=2006-01-02"`
+	Serials   []string `json:"serials,omitempty" validate:"omitempty,unique,dive,required,max=100"`
+}
+
+type GoodsReceipt struct {
+	Id         string             `json:"id"`
+	LocationId string             `json:"locationId"`
+	Supplier   string             `json:"supplier"`
+	Notes      string             `json:"notes"`
+	Items      []GoodsReceiptItem `json:"items"`
+	ReceivedBy string             `json:"receivedBy"`
+	ReceivedAt *time.Time         `json:"receivedAt"`
+}
+
+type GoodsReceiptInsertRequest struct {
+	LocationId string             `json:"locationId" validate:"omitempty,uuid"`
+	Supplier   string             `json:"supplier" validate:"max=100"`
+	Notes      string             `json:"notes" validate:"max=200"`
+	Items      []GoodsReceiptItem `json:"items" validate:"required,gte=1,dive,required"`
+}
+
+type GoodsReceiptQueryParams struct {
+	PageParams
+	LocationId string
+}
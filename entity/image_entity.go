@@ -0,0 +1,15 @@
+package entity
+
+import "time"
+
+type ProductImage struct {
+	ProductId    string     `json:"productId"`
+	ImageKey     string     `json:"imageKey"`
+	ThumbnailKey string     `json:"thumbnailKey"`
+	ContentType  string     `json:"contentType"`
+	Size         int        `json:"size"`
+	Width        int        `json:"width"`
+	Height       int        `json:"height"`
+	UploadedBy   *string    `json:"uploadedBy"`
+	UploadedAt   *time.Time `json:"uploadedAt"`
+}
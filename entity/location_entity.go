@@ -0,0 +1,29 @@
+package entity
+
+import "time"
+
+type Location struct {
+	Id        string     `json:"id"`
+	Name      string     `json:"name"`
+	Address   string     `json:"address"`
+	IsDefault bool       `json:"isDefault" db:"is_default"`
+	CreatedAt *time.Time `json:"createdAt" db:"created_at"`
+}
+
+type LocationInsertUpdateRequest struct {
+	Name      string `json:"name" validate:"required,min=1,max=50"`
+	Address   string `json:"address" validate:"max=200"`
+	IsDefault bool   `json:"isDefault"`
+}
+
+type ProductStock struct {
+	ProductId    string   `json:"-"`
+	LocationId   string   `json:"locationId"`
+	LocationName string   `json:"locationName"`
+	Stock        Quantity `json:"stock"`
+}
+
+type ProductStockUpdateRequest struct {
+	LocationId string    `json:"locationId" validate:"required,uuid"`
+	Stock      *Quantity `json:"stock" validate:"required,min=0,max=99999999999999"`
+}
@@ -0,0 +1,28 @@
+package entity
+
+import "time"
+
+type Lot struct {
+	Id          string     `json:"id"`
+	ProductId   string     `json:"productId"`
+	ProductName string     `json:"productName"`
+	LocationId  string     `json:"locationId"`
+	LotNumber   string     `json:"lotNumber"`
+	ExpiresAt   *string    `json:"expiresAt"`
+	Quantity    Quantity   `json:"quantity"`
+	Expired     bool       `json:"expired"`
+	ReceiptId   *string    `json:"receiptId"`
+	CreatedAt   *time.Time `json:"createdAt"`
+}
+
+// LotAllocation is the quantity taken out of one lot.
+type LotAllocation struct {
+	LotId    string   `json:"lotId"`
+	Quantity Quantity `json:"quantity"`
+}
+
+type ExpiringLotQueryParams struct {
+	PageParams
+	Days       int
+	LocationId string
+}
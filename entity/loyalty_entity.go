@@ -0,0 +1,76 @@
+package entity
+
+import "time"
+
+const (
+	PointsEarn    = "earn"
+	PointsRedeem  = "redeem"
+	PointsReverse = "reverse"
+	PointsExpire  = "expire"
+	PointsMerge   = "merge"
+)
+
+// LoyaltySettings says how points are earned and what they are worth. A
+// customer earns one point per SpendPerPoint rupiah, a category rate
+// overrides it for the products of that category. Zero earns nothing.
+type LoyaltySettings struct {
+	SpendPerPoint int                   `json:"spendPerPoint" validate:"min=0"`
+	PointValue    int                   `json:"pointValue" validate:"min=0"`
+	ExpiryDays    int                   `json:"expiryDays" validate:"min=0,max=3650"`
+	CategoryRates []LoyaltyCategoryRate `json:"categoryRates" validate:"dive"`
+	UpdatedAt     *time.Time            `json:"updatedAt"`
+}
+
+type LoyaltyCategoryRate struct {
+	Category      string `json:"category" validate:"required,max=50"`
+	SpendPerPoint int    `json:"spendPerPoint" validate:"min=0"`
+}
+
+// EarnRate returns the rupiah a customer spends on a category per point.
+func (l *LoyaltySettings) EarnRate(category string) int {
+	for _, rate := range l.CategoryRates {
+		if rate.Category == category {
+			return rate.SpendPerPoint
+		}
+	}
+
+	return l.SpendPerPoint
+}
+
+// PointEntry is one line of a customer's points ledger. Entries are never
+// changed, a correction is a new entry.
+type PointEntry struct {
+	Id            string     `json:"id"`
+	CustomerId    string     `json:"-"`
+	TransactionId *string    `json:"transactionId"`
+	Type          string     `json:"type"`
+	Points        int        `json:"points"`
+	SourceId      *string    `json:"-"`
+	ExpiresAt     *time.Time `json:"expiresAt"`
+	CreatedAt     time.Time  `json:"createdAt"`
+}
+
+// PointLot is what is left of a positive ledger entry.
+type PointLot struct {
+	EntryId   string     `json:"-"`
+	Points    int        `json:"points"`
+	ExpiresAt *time.Time `json:"expiresAt"`
+}
+
+// Expired reports whether the points of the lot can no longer be used at t.
+func (l *PointLot) Expired(t time.Time) bool {
+	return l.ExpiresAt != nil && !l.ExpiresAt.After(t)
+}
+
+type CustomerPoints struct {
+	CustomerId string       `json:"customerId"`
+	Balance    int          `json:"balance"`
+	Value      int          `json:"value"`
+	Lots       []PointLot   `json:"lots"`
+	Entries    []PointEntry `json:"entries"`
+}
+
+type PointQueryParams struct {
+	PageParams
+	CreatedAt string
+}
@@ -0,0 +1,31 @@
+package entity
+
+import "time"
+
+// Cursor points at the first or last row of a page. Sort keeps the ordering the
+// cursor was created with, a cursor can not be reused with another ordering.
+type Cursor struct {
+	Sort      string    `json:"s"`
+	CreatedAt time.Time `json:"c"`
+	Id        string    `json:"i"`
+	Price     *int      `json:"p,omitempty"`
+	Name      *string   `json:"n,omitempty"`
+	Score     *float64  `json:"r,omitempty"`
+	OtherId   *string   `json:"o,omitempty"`
+	Flag      *bool     `json:"f,omitempty"`
+	Backward  bool      `json:"b,omitempty"`
+}
+
+type PageParams struct {
+	Limit     int
+	Offset    int
+	Cursor    *Cursor
+	WithTotal bool
+}
+
+type PageMeta struct {
+	Limit int     `json:"limit"`
+	Next  *string `json:"next"`
+	Prev  *string `json:"prev"`
+	Total *int    `json:"total,omitempty"`
+}
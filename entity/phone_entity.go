@@ -0,0 +1,22 @@
+package entity
+
+import "time"
+
+// PhoneNumberRow is a staff or customer phone number that is not stored in
+// E.164 yet. Deleted customers do not hold their number, they never conflict.
+type PhoneNumberRow struct {
+	Id          string
+	PhoneNumber string
+	CreatedAt   time.Time
+	IsActive    bool
+}
+
+// PhoneNumberConflict is a stored number left as it is, Normalized is nil when
+// the number is not valid and DuplicateOf is the row that got the number.
+type PhoneNumberConflict struct {
+	TableName   string
+	RowId       string
+	PhoneNumber string
+	Normalized  *string
+	DuplicateOf *string
+}
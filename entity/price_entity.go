@@ -0,0 +1,46 @@
+package entity
+
+import "time"
+
+const (
+	PriceSchedulePending   = "pending"
+	PriceScheduleActive    = "active"
+	PriceScheduleDone      = "done"
+	PriceScheduleCancelled = "cancelled"
+)
+
+type PriceHistory struct {
+	Id         string     `json:"id"`
+	ProductId  string     `json:"productId" db:"product_id"`
+	OldPrice   *int       `json:"oldPrice" db:"old_price"`
+	NewPrice   int        `json:"newPrice" db:"new_price"`
+	ChangedBy  *string    `json:"changedBy" db:"changed_by"`
+	ScheduleId *string    `json:"scheduleId" db:"schedule_id"`
+	ChangedAt  *time.Time `json:"changedAt" db:"changed_at"`
+}
+
+type PriceHistoryQueryParams struct {
+	PageParams
+	From *time.Time
+	To   *time.Time
+}
+
+type PriceSchedule struct {
+	Id            string     `json:"id"`
+	ProductId     string     `json:"productId" db:"product_id"`
+	Price         int        `json:"price"`
+	PreviousPrice *int       `json:"previousPrice" db:"previous_price"`
+	StartsAt      time.Time  `json:"startsAt" db:"starts_at"`
+	EndsAt        *time.Time `json:"endsAt" db:"ends_at"`
+	Status        string     `json:"status"`
+	CreatedBy     *string    `json:"createdBy" db:"created_by"`
+	CreatedAt     *time.Time `json:"createdAt" db:"created_at"`
+	AppliedAt     *time.Time `json:"appliedAt" db:"applied_at"`
+	RevertedAt    *time.Time `json:"revertedAt" db:"reverted_at"`
+}
+
+type PriceScheduleInsertRequest struct {
+	Price    int        `json:"price" validate:"required,min=1"`
+	StartsAt time.Time  `json:"startsAt" validate:"required"`
+	EndsAt   *time.Time `json:"endsAt" validate:"omitempty,gtfield=StartsAt"`
+}
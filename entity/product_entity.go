@@ -2,65 +2,178 @@ package entity
 
 import "time"
 
+// UnitPiece is the unit of products counted by the piece.
+const UnitPiece = "pcs"
+
+// UnitPrecisions is the default number of decimals a quantity of each unit is
+// sold and stocked with.
+var UnitPrecisions = map[string]int{UnitPiece: 0, "m": 2, "kg": 3, "l": 3}
+
+// Product is a sellable item, Stock is the total of the stock levels in
+// Stocks. Location is deprecated, it is the free text shelf products had
+// before locations and is only kept for older clients.
 type Product struct {
-	Id          string     `json:"id"`
-	Name        string     `json:"name"`
-	SKU         string     `json:"sku"`
-	Category    string     `json:"category"`
-	ImageUrl    string     `json:"imageUrl" db:"image_url"`
-	Notes       string     `json:"notes"`
-	Price       int        `json:"price"`
-	Stock       int        `json:"stock"`
-	Location    string     `json:"location"`
-	IsAvailable bool       `json:"isAvailable" db:"is_available"`
-	CreatedAt   *time.Time `json:"createdAt" db:"created_at"`
+	Id                 string            `json:"id"`
+	Name               string            `json:"name"`
+	SKU                string            `json:"sku"`
+	Category           string            `json:"category"`
+	ImageUrl           string            `json:"imageUrl" db:"image_url"`
+	Notes              string            `json:"notes"`
+	Price              int               `json:"price"`
+	Stock              Quantity          `json:"stock"`
+	Location           string            `json:"location"`
+	IsAvailable        bool              `json:"isAvailable" db:"is_available"`
+	CreatedAt          *time.Time        `json:"createdAt" db:"created_at"`
+	ParentId           *string           `json:"parentId,omitempty" db:"parent_id"`
+	VariantAttributes  []string          `json:"variantAttributes,omitempty" db:"variant_attributes"`
+	VariantOptions     map[string]string `json:"variantOptions,omitempty" db:"variant_options"`
+	InheritPrice       bool              `json:"inheritPrice,omitempty" db:"inherit_price"`
+	Tags               []string          `json:"tags"`
+	Attributes         map[string]string `json:"attributes"`
+	Version            int               `json:"version"`
+	CostPrice          int               `json:"costPrice" db:"cost_price"`
+	ThumbnailUrl       string            `json:"thumbnailUrl" db:"thumbnail_url"`
+	IsBundle           bool              `json:"isBundle" db:"is_bundle"`
+	TrackSerials       bool              `json:"trackSerials" db:"track_serials"`
+	Unit               string            `json:"unit"`
+	QuantityPrecision  int               `json:"quantityPrecision" db:"quantity_precision"`
+	PurchaseUnit       string            `json:"purchaseUnit" db:"purchase_unit"`
+	PurchaseUnitFactor Quantity          `json:"purchaseUnitFactor" db:"purchase_unit_factor"`
+	Stocks             []ProductStock    `json:"stocks,omitempty" db:"-"`
+	Variants           []Product         `json:"variants,omitempty" db:"-"`
+	Components         []BundleComponent `json:"components,omitempty" db:"-"`
+}
+
+// HasVariants reports whether p is a parent product, parents are not sold directly.
+func (p *Product) HasVariants() bool {
+	return len(p.VariantAttributes) > 0
+}
+
+// AllowsQuantity reports whether q can be sold or stocked with the precision
+// configured for p, e.g. 1.25 m of fabric sold by the centimetre.
+func (p *Product) AllowsQuantity(q Quantity) bool {
+	return q.Decimals() <= p.QuantityPrecision
+}
+
+// BundleComponent is a product and the quantity of it contained in one bundle.
+type BundleComponent struct {
+	ProductId string   `json:"productId" validate:"required,uuid"`
+	Quantity  Quantity `json:"quantity" validate:"required,gt=0,max=100000000"`
+}
+
+type BundleComponentsUpdateRequest struct {
+	Components []BundleComponent `json:"components" validate:"required,gte=1,max=20,unique=ProductId,dive"`
+}
+
+type DeletedProduct struct {
+	Product
+	DeletedAt *time.Time `json:"deletedAt" db:"deleted_at"`
 }
 
 type ProductSKU struct {
-	Id        string     `json:"id"`
-	Name      string     `json:"name"`
-	SKU       string     `json:"sku"`
-	Category  string     `json:"category"`
-	ImageUrl  string     `json:"imageUrl" db:"image_url"`
-	Price     int        `json:"price"`
-	Stock     int        `json:"stock"`
-	Location  string     `json:"location"`
-	CreatedAt *time.Time `json:"createdAt" db:"created_at"`
+	Id             string            `json:"id"`
+	Name           string            `json:"name"`
+	SKU            string            `json:"sku"`
+	Category       string            `json:"category"`
+	ImageUrl       string            `json:"imageUrl" db:"image_url"`
+	Price          int               `json:"price"`
+	Stock          Quantity          `json:"stock"`
+	Location       string            `json:"location"`
+	CreatedAt      *time.Time        `json:"createdAt" db:"created_at"`
+	ParentId       *string           `json:"parentId,omitempty" db:"parent_id"`
+	VariantOptions map[string]string `json:"variantOptions,omitempty" db:"variant_options"`
+	Tags           []string          `json:"tags"`
+	Attributes     map[string]string `json:"attributes"`
 }
 
 type ProductInsertRequest struct {
-	Name        string `json:"name" validate:"required,min=1,max=30"`
-	SKU         string `json:"sku" validate:"required,min=1,max=30"`
-	Category    string `json:"category" validate:"required,oneof=Clothing Accessories Footwear Beverages"`
-	ImageUrl    string `json:"imageUrl" validate:"required,IsURL"`
-	Notes       string `json:"notes" validate:"required,min=1,max=200"`
-	Price       int    `json:"price" validate:"required,min=1"`
-	Stock       *int   `json:"stock" validate:"required,min=0,max=100000"`
-	Location    string `json:"location" validate:"required,min=1,max=200"`
-	IsAvailable *bool  `json:"isAvailable" validate:"required"`
+	Name               string            `json:"name" validate:"required,min=1,max=30"`
+	SKU                string            `json:"sku" validate:"required,min=1,max=30"`
+	Category           string            `json:"category" validate:"required,min=1,max=50"`
+	ImageUrl           string            `json:"imageUrl" validate:"required,IsURL"`
+	Notes              string            `json:"notes" validate:"required,min=1,max=200"`
+	Price              int               `json:"price" validate:"required,min=1"`
+	CostPrice          *int              `json:"costPrice,omitempty" validate:"omitempty,min=0"`
+	Stock              *Quantity         `json:"stock" validate:"required,min=0,max=99999999999999"`
+	Location           string            `json:"location" validate:"omitempty,max=200"`
+	IsAvailable        *bool             `json:"isAvailable" validate:"required"`
+	LocationId         string            `json:"locationId" validate:"omitempty,uuid"`
+	VariantAttributes  []string          `json:"variantAttributes" validate:"omitempty,max=5,unique,dive,required,max=20"`
+	Tags               []string          `json:"tags" validate:"omitempty,max=20,dive,required,max=30"`
+	Attributes         map[string]string `json:"attributes" validate:"omitempty,max=20,dive,keys,required,max=30,endkeys,required,max=100"`
+	Components         []BundleComponent `json:"components" validate:"omitempty,max=20,unique=ProductId,dive"`
+	TrackSerials       *bool             `json:"trackSerials"`
+	Unit               string            `json:"unit" validate:"omitempty,oneof=pcs m kg l"`
+	QuantityPrecision  *int              `json:"quantityPrecision" validate:"omitempty,min=0,max=3"`
+	PurchaseUnit       string            `json:"purchaseUnit" validate:"max=20"`
+	PurchaseUnitFactor *Quantity         `json:"purchaseUnitFactor" validate:"omitempty,gt=0,max=100000000"`
 }
 
 type ProductUpdateRequest struct {
-	Name        string `json:"name" validate:"required,min=1,max=30"`
-	SKU         string `json:"sku" validate:"required,min=1,max=30"`
-	Category    string `json:"category" validate:"required,oneof=Clothing Accessories Footwear Beverages"`
-	ImageUrl    string `json:"imageUrl" validate:"required,IsURL"`
-	Notes       string `json:"notes" validate:"required,min=1,max=200"`
-	Price       int    `json:"price" validate:"required,min=1"`
-	Stock       *int   `json:"stock" validate:"required,min=0,max=100000"`
-	Location    string `json:"location" validate:"required,min=1,max=200"`
-	IsAvailable *bool  `json:"isAvailable" validate:"required"`
+	Name               string            `json:"name" validate:"required,min=1,max=30"`
+	SKU                string            `json:"sku" validate:"required,min=1,max=30"`
+	Category           string            `json:"category" validate:"required,min=1,max=50"`
+	ImageUrl           string            `json:"imageUrl" validate:"required,IsURL"`
+	Notes              string            `json:"notes" validate:"required,min=1,max=200"`
+	Price              int               `json:"price" validate:"required,min=1"`
+	CostPrice          *int              `json:"costPrice,omitempty" validate:"omitempty,min=0"`
+	Stock              *Quantity         `json:"stock,omitempty" validate:"omitempty,min=0,max=99999999999999"`
+	Location           string            `json:"location" validate:"omitempty,max=200"`
+	IsAvailable        *bool             `json:"isAvailable" validate:"required"`
+	LocationId         string            `json:"locationId,omitempty" validate:"omitempty,uuid"`
+	VariantAttributes  []string          `json:"variantAttributes" validate:"omitempty,max=5,unique,dive,required,max=20"`
+	Tags               []string          `json:"tags" validate:"omitempty,max=20,dive,required,max=30"`
+	Attributes         map[string]string `json:"attributes" validate:"omitempty,max=20,dive,keys,required,max=30,endkeys,required,max=100"`
+	TrackSerials       *bool             `json:"trackSerials,omitempty"`
+	Unit               string            `json:"unit,omitempty" validate:"omitempty,oneof=pcs m kg l"`
+	QuantityPrecision  *int              `json:"quantityPrecision,omitempty" validate:"omitempty,min=0,max=3"`
+	PurchaseUnit       *string           `json:"purchaseUnit,omitempty" validate:"omitempty,max=20"`
+	PurchaseUnitFactor *Quantity         `json:"purchaseUnitFactor,omitempty" validate:"omitempty,gt=0,max=100000000"`
 }
 
 type ProductQueryParams struct {
-	IsAvailable *bool
-	InStock     *bool
-	Limit       int
-	Offset      int
-	ID          string
-	Name        string
+	PageParams
+	IsAvailable   *bool
+	InStock       *bool
+	ID            string
+	Name          string
+	Category      string
+	SKU           string
+	Price         string
+	CreatedAt     string
+	LocationId    string
+	WithStocks    bool
+	GroupVariants bool
+	Tags          []string
+	Attributes    map[string]string
+}
+
+type ProductVariantInsertUpdateRequest struct {
+	SKU            string            `json:"sku" validate:"required,min=1,max=30"`
+	VariantOptions map[string]string `json:"variantOptions" validate:"required,gte=1,dive,keys,required,max=20,endkeys,required,max=30"`
+	Price          *int              `json:"price" validate:"omitempty,min=1"`
+	CostPrice      *int              `json:"costPrice" validate:"omitempty,min=0"`
+	Stock          *Quantity         `json:"stock" validate:"required,min=0,max=99999999999999"`
+	IsAvailable    *bool             `json:"isAvailable" validate:"required"`
+	LocationId     string            `json:"locationId" validate:"omitempty,uuid"`
+}
+
+type ProductSearchParams struct {
+	PageParams
+	Q           string
 	Category    string
-	SKU         string
-	Price       string
-	CreatedAt   string
+	IsAvailable *bool
+}
+
+type ProductSearchResult struct {
+	Product
+	Score float64 `json:"score"`
+}
+
+type ProductSuggestion struct {
+	Id    string   `json:"id"`
+	Name  string   `json:"name"`
+	SKU   string   `json:"sku"`
+	Price int      `json:"price"`
+	Stock Quantity `json:"stock"`
 }
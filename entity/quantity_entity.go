@@ -0,0 +1,167 @@
+package entity
+
+import (
+	"errors"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// QuantityScale is the number of decimals a quantity is kept with.
+const QuantityScale = 3
+
+const quantityUnit = 1000
+
+var ErrInvalidQuantity = errors.New("quantity must be a number with at most 3 decimals")
+
+// Quantity is an amount of a product in thousandths of its unit, 1.5 kg is
+// 1500. It is stored as NUMERIC(14,3) and sent as a plain JSON number.
+// Validation tags compare thousandths too. Every quantity in a request is at
+// most max=100000000, 100000 units, a stock level is only bounded by its
+// column, max=99999999999999.
+type Quantity int64
+
+// Units returns a quantity of n whole units.
+func Units(n int) Quantity {
+	return Quantity(n) * quantityUnit
+}
+
+// ParseQuantity reads a decimal number such as "2", "1.5" or "0.125".
+func ParseQuantity(s string) (Quantity, error) {
+	negative := strings.HasPrefix(s, "-")
+	s = strings.TrimPrefix(s, "-")
+
+	whole, fraction, _ := strings.Cut(s, ".")
+	if whole == "" || len(fraction) > QuantityScale || strings.ContainsAny(whole+fraction, "+-eE") {
+		return 0, ErrInvalidQuantity
+	}
+
+	fraction += strings.Repeat("0", QuantityScale-len(fraction))
+	value, err := strconv.ParseInt(whole+fraction, 10, 64)
+	if err != nil {
+		return 0, ErrInvalidQuantity
+	}
+
+	if negative {
+		value = -value
+	}
+
+	return Quantity(value), nil
+}
+
+// Decimals returns the number of decimals needed to write q.
+func (q Quantity) Decimals() int {
+	decimals := QuantityScale
+	for n := int64(q); decimals > 0 && n%10 == 0; n /= 10 {
+		decimals--
+	}
+
+	return decimals
+}
+
+func (q Quantity) IsWhole() bool {
+	return q%quantityUnit == 0
+}
+
+// Whole returns the number of whole units in q.
+func (q Quantity) Whole() int {
+	return int(q / quantityUnit)
+}
+
+// Mul multiplies two quantities, e.g. the quantity of a component in a bundle
+// by the number of bundles.
+func (q Quantity) Mul(o Quantity) Quantity {
+	return Quantity(roundDiv(int64(q)*int64(o), quantityUnit))
+}
+
+// Amount is the price of q units at price per unit. Every price calculation
+// goes through it so amounts are rounded the same way everywhere, half away
+// from zero to the rupiah.
+func (q Quantity) Amount(price int) int {
+	return int(roundDiv(int64(price)*int64(q), quantityUnit))
+}
+
+// PerUnit splits amount paid for q units into the amount of one unit.
+func (q Quantity) PerUnit(amount int) int {
+	return int(roundDiv(int64(amount)*quantityUnit, int64(q)))
+}
+
+func (q Quantity) String() string {
+	sign := ""
+	n := int64(q)
+	if n < 0 {
+		sign, n = "-", -n
+	}
+
+	s := sign + strconv.FormatInt(n/quantityUnit, 10)
+	if fraction := n % quantityUnit; fraction != 0 {
+		s += "." + strings.TrimRight(strconv.FormatInt(quantityUnit+fraction, 10)[1:], "0")
+	}
+
+	return s
+}
+
+func (q Quantity) MarshalJSON() ([]byte, error) {
+	return []byte(q.String()), nil
+}
+
+func (q *Quantity) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		return nil
+	}
+
+	value, err := ParseQuantity(string(data))
+	if err != nil {
+		return err
+	}
+
+	*q = value
+
+	return nil
+}
+
+// NumericValue lets pgx write q into a NUMERIC column.
+func (q Quantity) NumericValue() (pgtype.Numeric, error) {
+	return pgtype.Numeric{Int: big.NewInt(int64(q)), Exp: -QuantityScale, Valid: true}, nil
+}
+
+// ScanNumeric lets pgx read a NUMERIC column into q.
+func (q *Quantity) ScanNumeric(v pgtype.Numeric) error {
+	if !v.Valid {
+		*q = 0
+		return nil
+	}
+
+	if v.NaN || v.InfinityModifier != pgtype.Finite {
+		return ErrInvalidQuantity
+	}
+
+	value := new(big.Int).Set(v.Int)
+	exp := int(v.Exp) + QuantityScale
+
+	if exp >= 0 {
+		value.Mul(value, new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(exp)), nil))
+	} else {
+		divisor := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(-exp)), nil)
+		value.Quo(value, divisor)
+	}
+
+	if !value.IsInt64() {
+		return ErrInvalidQuantity
+	}
+
+	*q = Quantity(value.Int64())
+
+	return nil
+}
+
+// roundDiv divides a by b rounding half away from zero.
+func roundDiv(a int64, b int64) int64 {
+	if (a < 0) != (b < 0) {
+		return (a - b/2) / b
+	}
+
+	return (a + b/2) / b
+}
@@ -0,0 +1,184 @@
+package entity
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestParseQuantity(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Quantity
+		wantErr bool
+	}{
+		{in: "2", want: 2000},
+		{in: "1.5", want: 1500},
+		{in: "0.125", want: 125},
+		{in: "1.", want: 1000},
+		{in: "-1.25", want: -1250},
+		{in: "-0.005", want: -5},
+		{in: "1.2345", wantErr: true},
+		{in: "0.0001", wantErr: true},
+		{in: "", wantErr: true},
+		{in: ".5", wantErr: true},
+		{in: "-", wantErr: true},
+		{in: "1e3", wantErr: true},
+		{in: "+1", wantErr: true},
+		{in: "1.-5", wantErr: true},
+		{in: "abc", wantErr: true},
+		{in: "99999999999999999999", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseQuantity(tt.in)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("ParseQuantity(%q) = %d, want an error", tt.in, got)
+			}
+			continue
+		}
+
+		if err != nil || got != tt.want {
+			t.Errorf("ParseQuantity(%q) = %d, %v, want %d", tt.in, got, err, tt.want)
+		}
+	}
+}
+
+func TestQuantityString(t *testing.T) {
+	tests := []struct {
+		in   Quantity
+		want string
+	}{
+		{in: 0, want: "0"},
+		{in: 2000, want: "2"},
+		{in: 1500, want: "1.5"},
+		{in: 1250, want: "1.25"},
+		{in: 125, want: "0.125"},
+		{in: 5, want: "0.005"},
+		{in: -1250, want: "-1.25"},
+		{in: -5, want: "-0.005"},
+		{in: Units(100000), want: "100000"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.in.String(); got != tt.want {
+			t.Errorf("Quantity(%d).String() = %q, want %q", int64(tt.in), got, tt.want)
+		}
+
+		parsed, err := ParseQuantity(tt.want)
+		if err != nil || parsed != tt.in {
+			t.Errorf("ParseQuantity(%q) = %d, %v, want %d", tt.want, parsed, err, tt.in)
+		}
+	}
+}
+
+func TestQuantityDecimals(t *testing.T) {
+	tests := []struct {
+		in   Quantity
+		want int
+	}{
+		{in: 0, want: 0},
+		{in: 2000, want: 0},
+		{in: 1500, want: 1},
+		{in: 1250, want: 2},
+		{in: 125, want: 3},
+		{in: -1500, want: 1},
+		{in: -125, want: 3},
+	}
+
+	for _, tt := range tests {
+		if got := tt.in.Decimals(); got != tt.want {
+			t.Errorf("Quantity(%d).Decimals() = %d, want %d", int64(tt.in), got, tt.want)
+		}
+	}
+}
+
+func TestQuantityPerUnit(t *testing.T) {
+	tests := []struct {
+		quantity Quantity
+		amount   int
+		want     int
+	}{
+		{quantity: Units(24), amount: 240000, want: 10000},
+		{quantity: Units(3), amount: 100, want: 33},
+		{quantity: Units(3), amount: 200, want: 67},
+		{quantity: 1500, amount: 1000, want: 667},
+		{quantity: 500, amount: 1000, want: 2000},
+		{quantity: Units(3), amount: -200, want: -67},
+		{quantity: Units(2), amount: 3, want: 2},
+		{quantity: Units(2), amount: -3, want: -2},
+	}
+
+	for _, tt := range tests {
+		if got := tt.quantity.PerUnit(tt.amount); got != tt.want {
+			t.Errorf("Quantity(%d).PerUnit(%d) = %d, want %d", int64(tt.quantity), tt.amount, got, tt.want)
+		}
+	}
+}
+
+func TestQuantityAmount(t *testing.T) {
+	tests := []struct {
+		quantity Quantity
+		price    int
+		want     int
+	}{
+		{quantity: Units(2), price: 15000, want: 30000},
+		{quantity: 1250, price: 10000, want: 12500},
+		{quantity: 1500, price: 999, want: 1499},
+		{quantity: 500, price: 3, want: 2},
+		{quantity: 400, price: 3, want: 1},
+		{quantity: -500, price: 3, want: -2},
+	}
+
+	for _, tt := range tests {
+		if got := tt.quantity.Amount(tt.price); got != tt.want {
+			t.Errorf("Quantity(%d).Amount(%d) = %d, want %d", int64(tt.quantity), tt.price, got, tt.want)
+		}
+	}
+}
+
+func TestQuantityMul(t *testing.T) {
+	tests := []struct {
+		a, b Quantity
+		want Quantity
+	}{
+		{a: 2500, b: Units(3), want: 7500},
+		{a: 333, b: 1500, want: 500},
+		{a: 1, b: 1, want: 0},
+		{a: -2500, b: Units(2), want: -5000},
+	}
+
+	for _, tt := range tests {
+		if got := tt.a.Mul(tt.b); got != tt.want {
+			t.Errorf("Quantity(%d).Mul(%d) = %d, want %d", int64(tt.a), int64(tt.b), int64(got), int64(tt.want))
+		}
+	}
+}
+
+func TestQuantityJSON(t *testing.T) {
+	var body struct {
+		Quantity Quantity  `json:"quantity"`
+		Stock    *Quantity `json:"stock"`
+	}
+
+	if err := json.Unmarshal([]byte(`{"quantity": 1.25, "stock": null}`), &body); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+
+	if body.Quantity != 1250 || body.Stock != nil {
+		t.Errorf("got quantity %d and stock %v, want 1250 and nil", int64(body.Quantity), body.Stock)
+	}
+
+	if err := json.Unmarshal([]byte(`{"quantity": 1.2345}`), &body); err == nil {
+		t.Error("Unmarshal of 1.2345 succeeded, want an error")
+	}
+
+	out, err := json.Marshal(body)
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+
+	if string(out) != `{"quantity":1.25,"stock":null}` {
+		t.Errorf("Marshal = %s", out)
+	}
+}
@@ -0,0 +1,46 @@
+package entity
+
+import "time"
+
+const (
+	MarginByProduct  = "product"
+	MarginByCategory = "category"
+	MarginByPeriod   = "period"
+)
+
+type MarginReportParams struct {
+	GroupBy    string
+	Period     string
+	From       *time.Time
+	To         *time.Time
+	LocationId string
+}
+
+type MarginReportRow struct {
+	Key           string   `json:"key"`
+	Name          string   `json:"name"`
+	Quantity      Quantity `json:"quantity"`
+	Revenue       int      `json:"revenue"`
+	Cost          int      `json:"cost"`
+	GrossMargin   int      `json:"grossMargin" db:"-"`
+	MarginPercent float64  `json:"marginPercent" db:"-"`
+}
+
+type InventoryValuationRow struct {
+	ProductId   string   `json:"productId"`
+	Name        string   `json:"name"`
+	SKU         string   `json:"sku"`
+	Category    string   `json:"category"`
+	Stock       Quantity `json:"stock"`
+	CostPrice   int      `json:"costPrice"`
+	Price       int      `json:"price"`
+	CostValue   int      `json:"costValue"`
+	RetailValue int      `json:"retailValue"`
+}
+
+type InventoryValuation struct {
+	Items            []InventoryValuationRow `json:"items"`
+	TotalStock       Quantity                `json:"totalStock"`
+	TotalCostValue   int                     `json:"totalCostValue"`
+	TotalRetailValue int                     `json:"totalRetailValue"`
+}
@@ -0,0 +1,44 @@
+package entity
+
+import "time"
+
+// SegmentRules select customers by their purchases, every rule that is set
+// must hold. Purchases are the transactions of the last Days days (all of
+// them when 0) that were not refunded.
+type SegmentRules struct {
+	Days      int  `json:"days" validate:"min=0,max=3650"`
+	MinSpend  *int `json:"minSpend,omitempty" validate:"omitempty,min=0"`
+	MaxSpend  *int `json:"maxSpend,omitempty" validate:"omitempty,min=0"`
+	MinVisits *int `json:"minVisits,omitempty" validate:"omitempty,min=0"`
+	MaxVisits *int `json:"maxVisits,omitempty" validate:"omitempty,min=0"`
+
+	// Categories matches customers who bought in any of them, OnlyCategories
+	// customers who bought nothing else. A category includes its subcategories.
+	Categories     []string `json:"categories,omitempty" validate:"omitempty,max=20,dive,required,max=50"`
+	OnlyCategories []string `json:"onlyCategories,omitempty" validate:"omitempty,max=20,dive,required,max=50"`
+
+	// InactiveDays matches customers who bought nothing in that many days
+	InactiveDays *int `json:"inactiveDays,omitempty" validate:"omitempty,min=1,max=3650"`
+
+	// Tags matches customers carrying all of them
+	Tags []string `json:"tags,omitempty" validate:"omitempty,max=20,dive,required,max=50"`
+}
+
+type Segment struct {
+	Id          string       `json:"id"`
+	Name        string       `json:"name"`
+	Description string       `json:"description"`
+	Rules       SegmentRules `json:"rules"`
+	CreatedAt   time.Time    `json:"createdAt"`
+	UpdatedAt   time.Time    `json:"updatedAt"`
+}
+
+type SegmentInsertUpdateRequest struct {
+	Name        string       `json:"name" validate:"required,min=1,max=50"`
+	Description string       `json:"description" validate:"max=255"`
+	Rules       SegmentRules `json:"rules"`
+}
+
+type CustomerTagsRequest struct {
+	Tags []string `json:"tags" validate:"max=20,unique,dive,required,max=50"`
+}
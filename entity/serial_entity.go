@@ -0,0 +1,27 @@
+package entity
+
+import "time"
+
+const (
+	SerialInStock   = "in_stock"
+	SerialInTransit = "in_transit"
+	SerialSold      = "sold"
+)
+
+// Serial is one unit of a serial tracked product, with the sale and customer
+// it went to once sold.
+type Serial struct {
+	Serial              string     `json:"serial"`
+	Status              string     `json:"status"`
+	ProductId           string     `json:"productId"`
+	ProductName         string     `json:"productName"`
+	SKU                 string     `json:"sku"`
+	LocationId          string     `json:"locationId"`
+	ReceiptId           *string    `json:"receiptId"`
+	ReceivedAt          *time.Time `json:"receivedAt"`
+	TransactionId       *string    `json:"transactionId"`
+	SoldAt              *time.Time `json:"soldAt"`
+	CustomerId          *string    `json:"customerId"`
+	CustomerName        *string    `json:"customerName"`
+	CustomerPhoneNumber *string    `json:"customerPhoneNumber"`
+}
@@ -5,15 +5,18 @@ type Staff struct {
 	PhoneNumber string `json:"phoneNumber"`
 	Name        string `json:"name"`
 	Password    string `json:"password"`
+	IsAdmin     bool   `json:"isAdmin"`
 }
 
+// StaffLoginRequest does not check the phone number rules, a number left as
+// it is by a phone number conflict logs in as it is stored.
 type StaffLoginRequest struct {
-	PhoneNumber string `json:"phoneNumber" validate:"required,min=10,max=16,startswith=+,valid_phone"`
+	PhoneNumber string `json:"phoneNumber" validate:"required,max=30"`
 	Password    string `json:"password" validate:"required,min=5,max=15"`
 }
 
 type StaffRegisterRequest struct {
 	Name        string `json:"name" validate:"required,min=5,max=50"`
-	PhoneNumber string `json:"phoneNumber" validate:"required,min=10,max=16,startswith=+,valid_phone"`
+	PhoneNumber string `json:"phoneNumber" validate:"required,max=30,valid_phone"`
 	Password    string `json:"password" validate:"required,min=5,max=15"`
 }
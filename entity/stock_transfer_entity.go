@@ -0,0 +1,41 @@
+package entity
+
+import "time"
+
+const (
+	TransferInTransit = "in_transit"
+	TransferReceived  = "received"
+	TransferCancelled = "cancelled"
+)
+
+type StockTransferItem struct {
+	ProductId string   `json:"productId" validate:"required,uuid"`
+	Quantity  Quantity `json:"quantity" validate:"required,gt=0,max=100000000"`
+	Serials   []string `json:"serials,omitempty" validate:"omitempty,unique,dive,required,max=100"`
+}
+
+type StockTransfer struct {
+	Id             string              `json:"id"`
+	FromLocationId string              `json:"fromLocationId"`
+	ToLocationId   string              `json:"toLocationId"`
+	Status         string              `json:"status"`
+	Notes          string              `json:"notes"`
+	Items          []StockTransferItem `json:"items"`
+	SentBy         string              `json:"sentBy"`
+	ReceivedBy     *string             `json:"receivedBy"`
+	SentAt         *time.Time          `json:"sentAt"`
+	ReceivedAt     *time.Time          `json:"receivedAt"`
+}
+
+type StockTransferInsertRequest struct {
+	FromLocationId string              `json:"fromLocationId" validate:"required,uuid"`
+	ToLocationId   string              `json:"toLocationId" validate:"required,uuid,nefield=FromLocationId"`
+	Notes          string              `json:"notes" validate:"max=200"`
+	Items          []StockTransferItem `json:"items" validate:"required,gte=1,dive,required"`
+}
+
+type StockTransferQueryParams struct {
+	PageParams
+	Status     string
+	LocationId string
+}
@@ -0,0 +1,63 @@
+package entity
+
+import "time"
+
+const (
+	StoredValueGiftCard = "gift_card"
+	StoredValueCredit   = "credit"
+)
+
+const (
+	MovementIssue  = "issue"
+	MovementTopUp  = "top_up"
+	MovementRedeem = "redeem"
+	MovementRefund = "refund"
+	MovementMerge  = "merge"
+)
+
+// StoredValueAccount is a gift card, found by its code, or the store credit
+// of a customer. Its balance is the sum of its movements.
+type StoredValueAccount struct {
+	Id         string                `json:"id"`
+	Type       string                `json:"type"`
+	Code       *string               `json:"code,omitempty"`
+	CustomerId *string               `json:"customerId,omitempty"`
+	Balance    int                   `json:"balance"`
+	CreatedAt  time.Time             `json:"createdAt"`
+	Movements  []StoredValueMovement `json:"movements,omitempty" db:"-"`
+}
+
+type StoredValueMovement struct {
+	Id            string    `json:"id"`
+	AccountId     string    `json:"-"`
+	Type          string    `json:"type"`
+	Amount        int       `json:"amount"`
+	BalanceAfter  int       `json:"balanceAfter"`
+	TransactionId *string   `json:"transactionId"`
+	StaffId       *string   `json:"staffId"`
+	Note          string    `json:"note"`
+	CreatedAt     time.Time `json:"createdAt"`
+}
+
+type GiftCardInsertRequest struct {
+	// Code is generated when left empty
+	Code   string `json:"code" validate:"omitempty,min=6,max=32,alphanum"`
+	Amount int    `json:"amount" validate:"required,min=1"`
+	Note   string `json:"note" validate:"max=255"`
+}
+
+type StoredValueTopUpRequest struct {
+	Amount int    `json:"amount" validate:"required,min=1"`
+	Note   string `json:"note" validate:"max=255"`
+}
+
+// StoredValuePayment pays part of a checkout from a gift card.
+type StoredValuePayment struct {
+	Code   string `json:"code" validate:"required,max=32"`
+	Amount int    `json:"amount" validate:"required,min=1"`
+}
+
+type MovementQueryParams struct {
+	PageParams
+	CreatedAt string
+}
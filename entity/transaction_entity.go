@@ -3,32 +3,59 @@ package entity
 import "time"
 
 type ProductDetail struct {
-	TransactionId string `json:"-"`
-	ProductId     string `json:"productId" validate:"required"`
-	Quantity      int    `json:"quantity" validate:"required,min=1"`
-	Price         int    `json:"-"`
-	TotalPrice    int    `json:"-"`
+	TransactionId string   `json:"-"`
+	ProductId     string   `json:"productId" validate:"required"`
+	Quantity      Quantity `json:"quantity" validate:"required,gt=0,max=100000000"`
+	Price         int      `json:"-"`
+	TotalPrice    int      `json:"-"`
+
+	// Serials name the sold units of a serial tracked product, one per unit
+	Serials []string `json:"serials,omitempty" validate:"omitempty,unique,dive,required,max=100"`
+
+	// Components are the lines a bundle is made of, taken from stock instead of the bundle
+	Components []ProductDetail `json:"components,omitempty" validate:"-"`
 }
 
 type Transaction struct {
-	Id             string          `json:"transactionId"`
-	CustomerId     string          `json:"customerId"`
-	Paid           int             `json:"paid"`
-	Change         int             `json:"change"`
-	ProductDetails []ProductDetail `json:"productDetails"`
-	CreatedAt      *time.Time      `json:"createdAt" db:"created_at"`
+	Id              string          `json:"transactionId"`
+	CustomerId      string          `json:"customerId"`
+	LocationId      string          `json:"locationId"`
+	Paid            int             `json:"paid"`
+	Change          int             `json:"change"`
+	PointsRedeemed  int             `json:"pointsRedeemed"`
+	PointsDiscount  int             `json:"pointsDiscount"`
+	StoredValuePaid int             `json:"storedValuePaid"`
+	AccountCharged  int             `json:"accountCharged"`
+	ProductDetails  []ProductDetail `json:"productDetails"`
+	CreatedAt       *time.Time      `json:"createdAt" db:"created_at"`
+	RefundedAt      *time.Time      `json:"refundedAt"`
 }
 
 type TransactionInsertRequest struct {
-	CustomerId     string          `json:"customerId" validate:"required"`
+	CustomerId     string          `json:"customerId" validate:"required,required_if=ChargeToAccount true"`
 	ProductDetails []ProductDetail `json:"productDetails" validate:"required,gte=1,dive,required"` // TODO: validate if product id duplicate fi
-	Paid           int             `json:"paid" validate:"required,min=1"`
+	Paid           int             `json:"paid" validate:"min=0"`
 	Change         *int            `json:"change" validate:"required,min=0"`
+	LocationId     string          `json:"locationId" validate:"omitempty,uuid"`
+
+	// RedeemPoints pays part of the total with loyalty points
+	RedeemPoints   int `json:"redeemPoints" validate:"min=0"`
+	PointsDiscount int `json:"-"`
+	PointsEarned   int `json:"-"`
+
+	// GiftCards and StoreCredit pay part of the total from stored value
+	GiftCards       []StoredValuePayment `json:"giftCards" validate:"omitempty,dive"`
+	StoreCredit     int                  `json:"storeCredit" validate:"min=0"`
+	StoredValuePaid int                  `json:"-"`
+
+	// ChargeToAccount leaves what is not paid on the customer's credit account
+	ChargeToAccount bool `json:"chargeToAccount"`
+	AccountCharged  int  `json:"-"`
 }
 
 type TransactionQueryParams struct {
-	Limit      int
-	Offset     int
+	PageParams
 	CustomerId string
+	LocationId string
 	CreatedAt  string
 }
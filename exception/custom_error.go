@@ -52,6 +52,20 @@ func NewConflict(message string) *CustomError {
 	}
 }
 
+func NewForbidden(message string) *CustomError {
+	return &CustomError{
+		Message:    message,
+		StatusCode: http.StatusForbidden,
+	}
+}
+
+func NewPreconditionFailed(message string) *CustomError {
+	return &CustomError{
+		Message:    message,
+		StatusCode: http.StatusPreconditionFailed,
+	}
+}
+
 func NewUnauthorized(message string) *CustomError {
 	return &CustomError{
 		Message:    message,
@@ -65,3 +79,17 @@ func NewInternalServer(message string) *CustomError {
 		StatusCode: http.StatusInternalServerError,
 	}
 }
+
+func NewRequestEntityTooLarge(message string) *CustomError {
+	return &CustomError{
+		Message:    message,
+		StatusCode: http.StatusRequestEntityTooLarge,
+	}
+}
+
+func NewUnsupportedMediaType(message string) *CustomError {
+	return &CustomError{
+		Message:    message,
+		StatusCode: http.StatusUnsupportedMediaType,
+	}
+}
@@ -2,21 +2,31 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/go-playground/validator/v10"
 	"github.com/malikfajr/eq-store/middleware"
 	"github.com/malikfajr/eq-store/pkg"
+	"github.com/malikfajr/eq-store/repository"
 	"github.com/malikfajr/eq-store/routes"
+	"github.com/malikfajr/eq-store/service"
 )
 
 func main() {
 
 	connStr := fmt.Sprintf("postgres://%s:%s@%s:%s/%s?%s", os.Getenv("DB_USER"), os.Getenv("DB_PASSWORD"), os.Getenv("DB_HOST"), os.Getenv("DB_PORT"), os.Getenv("DB_NAME"), os.Getenv("DB_PARAMS"))
 
+	// ctx is cancelled on shutdown, background jobs stop with it
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
 	pool := pkg.CreateConnPool(context.Background(), connStr)
 
 	defer pool.Close()
@@ -25,6 +35,18 @@ func main() {
 	validate.RegisterValidation("valid_phone", pkg.IsValidPhoneNumber)
 	validate.RegisterValidation("IsURL", pkg.ValidateURL)
 
+	// numbers stored before they were kept in E.164 are brought to it first
+	phoneService := service.NewPhoneService(pool, repository.NewPhoneRepository())
+	if err := phoneService.NormalizeAll(ctx); err != nil {
+		log.Fatal(err)
+	}
+
+	priceService := service.NewPriceService(pool, repository.NewProductRepository(), repository.NewPriceRepository())
+	go priceService.RunScheduler(ctx, time.Minute)
+
+	loyaltyService := service.NewLoyaltyService(pool, repository.NewCustomerRepository(), repository.NewCategoryRepository(), repository.NewLoyaltyRepository())
+	go loyaltyService.RunExpiry(ctx, time.Hour)
+
 	r := http.NewServeMux()
 
 	RoutesV1 := routes.NewRoutesV1(pool, validate)
@@ -35,5 +57,23 @@ func main() {
 		Handler: middleware.Logging(r),
 	}
 
-	log.Fatal(s.ListenAndServe())
+	// Shutdown lets requests in flight finish before main returns
+	shutdown := make(chan struct{})
+	go func() {
+		defer close(shutdown)
+		<-ctx.Done()
+
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		if err := s.Shutdown(shutdownCtx); err != nil {
+			log.Println("shutdown:", err)
+		}
+	}()
+
+	if err := s.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		log.Fatal(err)
+	}
+
+	<-shutdown
 }
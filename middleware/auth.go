@@ -9,6 +9,7 @@ import (
 )
 
 const AuthStaffID = "auth.staff.id"
+const AuthStaffAdmin = "auth.staff.admin"
 
 func Auth(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -33,8 +34,31 @@ func Auth(next http.Handler) http.Handler {
 		}
 
 		ctx := context.WithValue(r.Context(), AuthStaffID, jwt.StaffId)
+		ctx = context.WithValue(ctx, AuthStaffAdmin, jwt.IsAdmin)
 		req := r.WithContext(ctx)
 
 		next.ServeHTTP(w, req)
 	})
 }
+
+func GetStaffId(ctx context.Context) string {
+	staffId, _ := ctx.Value(AuthStaffID).(string)
+	return staffId
+}
+
+// Admin only lets admin staff through, it must be wrapped by Auth.
+func Admin(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !IsAdmin(r.Context()) {
+			exception.NewForbidden("admin access required").Send(w)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func IsAdmin(ctx context.Context) bool {
+	isAdmin, _ := ctx.Value(AuthStaffAdmin).(bool)
+	return isAdmin
+}
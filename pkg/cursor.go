@@ -0,0 +1,31 @@
+package pkg
+
+import (
+	"encoding/base64"
+	"encoding/json"
+
+	"github.com/malikfajr/eq-store/entity"
+)
+
+func EncodeCursor(cursor entity.Cursor) string {
+	b, err := json.Marshal(cursor)
+	if err != nil {
+		panic(err)
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func DecodeCursor(s string) (*entity.Cursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+
+	cursor := &entity.Cursor{}
+	if err := json.Unmarshal(b, cursor); err != nil {
+		return nil, err
+	}
+
+	return cursor, nil
+}
@@ -0,0 +1,48 @@
+package pkg
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/malikfajr/eq-store/entity"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	price := 15000
+	name := "Kopi Susu"
+	score := 0.875
+	createdAt := time.Date(2024, 5, 25, 9, 30, 0, 123456000, time.UTC)
+
+	tests := []entity.Cursor{
+		{Sort: "created_at:desc", CreatedAt: createdAt, Id: "1f0c6a3e-8a53-4d0e-9b3c-2a8f4f6e7d10"},
+		{Sort: "price:asc", CreatedAt: createdAt, Id: "b", Price: &price, Backward: true},
+		{Sort: "name:asc", Id: "c", Name: &name},
+		{Sort: "score:desc,created_at:desc", CreatedAt: createdAt, Id: "d", Score: &score},
+	}
+
+	for _, cursor := range tests {
+		encoded := EncodeCursor(cursor)
+		if strings.ContainsAny(encoded, "+/=") {
+			t.Errorf("EncodeCursor(%+v) = %q, want URL safe base64 without padding", cursor, encoded)
+		}
+
+		decoded, err := DecodeCursor(encoded)
+		if err != nil {
+			t.Fatalf("DecodeCursor(%q): %v", encoded, err)
+		}
+
+		if !reflect.DeepEqual(*decoded, cursor) {
+			t.Errorf("DecodeCursor(EncodeCursor(%+v)) = %+v", cursor, *decoded)
+		}
+	}
+}
+
+func TestDecodeCursorInvalid(t *testing.T) {
+	for _, s := range []string{"not base64!", "bm90IGpzb24", "eyJzIjoxfQ"} {
+		if _, err := DecodeCursor(s); err == nil {
+			t.Errorf("DecodeCursor(%q) succeeded, want an error", s)
+		}
+	}
+}
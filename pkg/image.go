@@ -0,0 +1,156 @@
+package pkg
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	_ "image/gif"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+)
+
+const (
+	MaxImageSize      = 5 << 20
+	MaxImageDimension = 8000
+	ThumbnailSize     = 200
+)
+
+var (
+	ErrUnsupportedImage = errors.New("image must be a jpeg, png or gif")
+	ErrImageTooLarge    = errors.New("image dimensions are too large")
+)
+
+// imageExtensions lists the accepted content types, detected from the file
+// content rather than the name or header sent by the client.
+var imageExtensions = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/gif":  ".gif",
+}
+
+type Image struct {
+	Data        []byte
+	ContentType string
+	Extension   string
+	Width       int
+	Height      int
+}
+
+// DecodeImage sniffs and validates an uploaded image, the dimensions are
+// checked before the pixels are decoded.
+func DecodeImage(data []byte) (*Image, image.Image, error) {
+	contentType := http.DetectContentType(data)
+	extension, ok := imageExtensions[contentType]
+	if !ok {
+		return nil, nil, ErrUnsupportedImage
+	}
+
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, nil, ErrUnsupportedImage
+	}
+
+	if config.Width > MaxImageDimension || config.Height > MaxImageDimension {
+		return nil, nil, ErrImageTooLarge
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, nil, ErrUnsupportedImage
+	}
+
+	return &Image{
+		Data:        data,
+		ContentType: contentType,
+		Extension:   extension,
+		Width:       config.Width,
+		Height:      config.Height,
+	}, img, nil
+}
+
+// Thumbnail scales img to fit in ThumbnailSize, png keeps its transparency and
+// everything else is stored as jpeg.
+func Thumbnail(img image.Image, contentType string) (*Image, error) {
+	thumb := resize(img, ThumbnailSize)
+
+	var buf bytes.Buffer
+	out := &Image{Width: thumb.Bounds().Dx(), Height: thumb.Bounds().Dy()}
+
+	if contentType == "image/png" {
+		if err := png.Encode(&buf, thumb); err != nil {
+			return nil, err
+		}
+		out.ContentType, out.Extension = "image/png", ".png"
+	} else {
+		if err := jpeg.Encode(&buf, thumb, &jpeg.Options{Quality: 80}); err != nil {
+			return nil, err
+		}
+		out.ContentType, out.Extension = "image/jpeg", ".jpg"
+	}
+
+	out.Data = buf.Bytes()
+
+	return out, nil
+}
+
+// resize downscales src with a box filter so the longest side is at most max.
+func resize(src image.Image, max int) *image.NRGBA {
+	bounds := src.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+
+	if width > max || height > max {
+		if width >= height {
+			height = height * max / width
+			width = max
+		} else {
+			width = width * max / height
+			height = max
+		}
+	}
+	width, height = maxInt(width, 1), maxInt(height, 1)
+
+	dst := image.NewNRGBA(image.Rect(0, 0, width, height))
+	scaleX := float64(bounds.Dx()) / float64(width)
+	scaleY := float64(bounds.Dy()) / float64(height)
+
+	for y := 0; y < height; y++ {
+		y0 := bounds.Min.Y + int(float64(y)*scaleY)
+		y1 := maxInt(bounds.Min.Y+int(float64(y+1)*scaleY), y0+1)
+
+		for x := 0; x < width; x++ {
+			x0 := bounds.Min.X + int(float64(x)*scaleX)
+			x1 := maxInt(bounds.Min.X+int(float64(x+1)*scaleX), x0+1)
+
+			var r, g, b, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					c := color.NRGBAModel.Convert(src.At(sx, sy)).(color.NRGBA)
+					r += uint64(c.R)
+					g += uint64(c.G)
+					b += uint64(c.B)
+					a += uint64(c.A)
+					n++
+				}
+			}
+
+			dst.SetNRGBA(x, y, color.NRGBA{
+				R: uint8(r / n),
+				G: uint8(g / n),
+				B: uint8(b / n),
+				A: uint8(a / n),
+			})
+		}
+	}
+
+	return dst
+}
+
+func maxInt(a int, b int) int {
+	if a > b {
+		return a
+	}
+
+	return b
+}
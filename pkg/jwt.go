@@ -13,14 +13,16 @@ var JWT_SECRET = os.Getenv("JWT_SECRET")
 type JWTClaim struct {
 	StaffId string           `json:"staffId"`
 	Name    string           `json:"name"`
+	IsAdmin bool             `json:"isAdmin,omitempty"`
 	Exp     *jwt.NumericDate `json:"exp"`
 	jwt.RegisteredClaims
 }
 
-func CreateToken(staffId string, name string) string {
+func CreateToken(staffId string, name string, isAdmin bool) string {
 	claim := &JWTClaim{
 		Name:    name,
 		StaffId: staffId,
+		IsAdmin: isAdmin,
 		Exp:     jwt.NewNumericDate(time.Now().Add(8 * time.Hour)),
 	}
 
@@ -0,0 +1,41 @@
+package pkg
+
+import "encoding/json"
+
+// MergePatch applies a JSON Merge Patch (RFC 7396) to the target document.
+func MergePatch(target []byte, patch []byte) ([]byte, error) {
+	var doc, p interface{}
+
+	if err := json.Unmarshal(target, &doc); err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(patch, &p); err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(mergeValue(doc, p))
+}
+
+func mergeValue(target interface{}, patch interface{}) interface{} {
+	patchObject, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+
+	targetObject, ok := target.(map[string]interface{})
+	if !ok {
+		targetObject = map[string]interface{}{}
+	}
+
+	for key, value := range patchObject {
+		if value == nil {
+			delete(targetObject, key)
+			continue
+		}
+
+		targetObject[key] = mergeValue(targetObject[key], value)
+	}
+
+	return targetObject
+}
@@ -0,0 +1,46 @@
+package pkg
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/nyaruka/phonenumbers"
+)
+
+var ErrInvalidPhoneNumber = errors.New("phone number is not valid")
+
+// PhoneRegion is the region numbers written in national format belong to,
+// "0812..." is read as "+62812..." by default. Set PHONE_DEFAULT_REGION=ZZ to
+// accept international numbers only.
+var PhoneRegion = strings.ToUpper(getEnv("PHONE_DEFAULT_REGION", "ID"))
+
+// NormalizePhoneNumber checks phone against the numbering rules of its country
+// and returns it in E.164, the form phone numbers are stored in.
+func NormalizePhoneNumber(phone string) (string, error) {
+	number, err := phonenumbers.Parse(phone, PhoneRegion)
+	if err != nil || !phonenumbers.IsValidNumber(number) {
+		return "", ErrInvalidPhoneNumber
+	}
+
+	return phonenumbers.Format(number, phonenumbers.E164), nil
+}
+
+// PhoneDigits reduces a phone number, or a part of one, to the digits of its
+// E.164 form, so "+62 812-3456", "0812 3456" and "628123456" are all
+// "628123456". Input without a leading 0 is kept as is, it may be any part of
+// a number.
+func PhoneDigits(phone string) string {
+	digits := strings.Map(func(r rune) rune {
+		if r >= '0' && r <= '9' {
+			return r
+		}
+		return -1
+	}, phone)
+
+	if code := phonenumbers.GetCountryCodeForRegion(PhoneRegion); code > 0 && strings.HasPrefix(digits, "0") {
+		digits = strconv.Itoa(code) + digits[1:]
+	}
+
+	return digits
+}
@@ -0,0 +1,58 @@
+package pkg
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestNormalizePhoneNumber(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    string
+		wantErr bool
+	}{
+		{in: "081234567890", want: "+6281234567890"},
+		{in: "0812-3456-7890", want: "+6281234567890"},
+		{in: "+62 812 3456 7890", want: "+6281234567890"},
+		{in: "6281234567890", want: "+6281234567890"},
+		{in: "(021) 5678 9012", want: "+622156789012"},
+		{in: "+1 650-253-0000", want: "+16502530000"},
+		{in: "12345", wantErr: true},
+		{in: "0812", wantErr: true},
+		{in: "phone", wantErr: true},
+		{in: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := NormalizePhoneNumber(tt.in)
+		if tt.wantErr {
+			if !errors.Is(err, ErrInvalidPhoneNumber) {
+				t.Errorf("NormalizePhoneNumber(%q) = %q, %v, want ErrInvalidPhoneNumber", tt.in, got, err)
+			}
+			continue
+		}
+
+		if err != nil || got != tt.want {
+			t.Errorf("NormalizePhoneNumber(%q) = %q, %v, want %q", tt.in, got, err, tt.want)
+		}
+	}
+}
+
+func TestPhoneDigits(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{in: "+62 812-3456", want: "628123456"},
+		{in: "0812 3456", want: "628123456"},
+		{in: "628123456", want: "628123456"},
+		{in: "3456", want: "3456"},
+		{in: "ab-", want: ""},
+	}
+
+	for _, tt := range tests {
+		if got := PhoneDigits(tt.in); got != tt.want {
+			t.Errorf("PhoneDigits(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package pkg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+var ErrObjectNotFound = errors.New("object not found")
+
+// PUBLIC_URL is the address clients reach the API on, uploaded images are
+// served from it.
+var PUBLIC_URL = strings.TrimRight(getEnv("PUBLIC_URL", "http://localhost:8080"), "/")
+
+// Storage keeps uploaded files by key, keys use "/" as separator.
+type Storage interface {
+	Put(ctx context.Context, key string, body []byte, contentType string) error
+	Get(ctx context.Context, key string) (io.ReadCloser, string, error)
+	Delete(ctx context.Context, key string) error
+}
+
+// NewStorage returns the storage selected by STORAGE_DRIVER, "local" (the
+// default) or "s3". It panics on an invalid PUBLIC_URL or S3_ENDPOINT.
+func NewStorage() Storage {
+	if !IsValidEndpoint(PUBLIC_URL) {
+		panic(fmt.Errorf("PUBLIC_URL %q is not a valid address", PUBLIC_URL))
+	}
+
+	if os.Getenv("STORAGE_DRIVER") == "s3" {
+		if endpoint := os.Getenv("S3_ENDPOINT"); endpoint != "" && !IsValidEndpoint(endpoint) {
+			panic(fmt.Errorf("S3_ENDPOINT %q is not a valid address", endpoint))
+		}
+
+		return NewS3Storage(S3Config{
+			Endpoint:  os.Getenv("S3_ENDPOINT"),
+			Region:    getEnv("S3_REGION", "us-east-1"),
+			Bucket:    os.Getenv("S3_BUCKET"),
+			AccessKey: os.Getenv("S3_ACCESS_KEY"),
+			SecretKey: os.Getenv("S3_SECRET_KEY"),
+		})
+	}
+
+	return NewLocalStorage(getEnv("STORAGE_LOCAL_DIR", "uploads"))
+}
+
+func getEnv(key string, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+
+	return fallback
+}
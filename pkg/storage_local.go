@@ -0,0 +1,92 @@
+package pkg
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"mime"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+type localStorage struct {
+	root string
+}
+
+// NewLocalStorage stores files under the root directory, it is created on the
+// first upload.
+func NewLocalStorage(root string) Storage {
+	return &localStorage{root: root}
+}
+
+func (l *localStorage) Put(ctx context.Context, key string, body []byte, contentType string) error {
+	name, err := l.path(key)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+		return err
+	}
+
+	// write to a temporary file first so readers never see a partial file
+	tmp, err := os.CreateTemp(filepath.Dir(name), ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(body); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), name)
+}
+
+func (l *localStorage) Get(ctx context.Context, key string) (io.ReadCloser, string, error) {
+	name, err := l.path(key)
+	if err != nil {
+		return nil, "", err
+	}
+
+	file, err := os.Open(name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, "", ErrObjectNotFound
+	}
+	if err != nil {
+		return nil, "", err
+	}
+
+	return file, mime.TypeByExtension(path.Ext(key)), nil
+}
+
+func (l *localStorage) Delete(ctx context.Context, key string) error {
+	name, err := l.path(key)
+	if err != nil {
+		return err
+	}
+
+	if err := os.Remove(name); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+
+	return nil
+}
+
+// path maps key to a file below root, keys escaping root are rejected.
+func (l *localStorage) path(key string) (string, error) {
+	clean := path.Clean("/" + key)
+	if clean == "/" || strings.Contains(key, "..") {
+		return "", ErrObjectNotFound
+	}
+
+	return filepath.Join(l.root, filepath.FromSlash(clean)), nil
+}
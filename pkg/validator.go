@@ -8,17 +8,31 @@ import (
 )
 
 func IsValidPhoneNumber(fl validator.FieldLevel) bool {
-	phoneNumber := fl.Field().String()
-
-	// Nomor telepon harus diawali dengan "+" dan diikuti dengan angka atau dash
-	return strings.HasPrefix(phoneNumber, "+") && (strings.ContainsAny(phoneNumber[1:], "0123456789-"))
+	// Nomor telepon harus valid menurut aturan negaranya, format nasional dibaca dengan PhoneRegion
+	_, err := NormalizePhoneNumber(fl.Field().String())
+	return err == nil
 }
 
 func ValidateURL(fl validator.FieldLevel) bool {
 	url := fl.Field().String()
 
+	// Gambar yang diunggah ke API ini dilayani dari PUBLIC_URL, yang boleh localhost
+	if strings.HasPrefix(url, PUBLIC_URL+"/v1/image/") {
+		return true
+	}
+
 	// Ekspresi reguler untuk memvalidasi URL
 	regex := `^(https?://)?([a-zA-Z0-9-]+\.){1,}[a-zA-Z]{2,}(/[a-zA-Z0-9-._~:/?#[\]@!$&'()*+,;=]*)?$`
 	match, _ := regexp.MatchString(regex, url)
 	return match
 }
+
+// IsValidEndpoint checks the address of a service the API talks to or is
+// served on, such as PUBLIC_URL or S3_ENDPOINT. Unlike ValidateURL it accepts
+// any host name, such as localhost or "minio", IP addresses and ports, it is
+// only for configuration.
+func IsValidEndpoint(url string) bool {
+	regex := `^https?://[a-zA-Z0-9-]+(\.[a-zA-Z0-9-]+)*(:[0-9]{1,5})?(/[a-zA-Z0-9-._~/]*)?$`
+	match, _ := regexp.MatchString(regex, url)
+	return match
+}
@@ -0,0 +1,53 @@
+package pkg
+
+import (
+	"testing"
+
+	"github.com/go-playground/validator/v10"
+)
+
+func TestValidateURL(t *testing.T) {
+	validate := validator.New()
+	validate.RegisterValidation("IsURL", ValidateURL)
+
+	tests := []struct {
+		in   string
+		want bool
+	}{
+		{in: "https://example.com/image.png", want: true},
+		{in: "example.com/image.png", want: true},
+		{in: PUBLIC_URL + "/v1/image/products/abc.jpg", want: true},
+		{in: "http://localhost:8080/image.png", want: false},
+		{in: "http://127.0.0.1/image.png", want: false},
+		{in: "http://169.254.169.254/latest/meta-data", want: false},
+		{in: "http://internal:9000/image.png", want: false},
+		{in: "not a url", want: false},
+	}
+
+	for _, tt := range tests {
+		if got := validate.Var(tt.in, "IsURL") == nil; got != tt.want {
+			t.Errorf("ValidateURL(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestIsValidEndpoint(t *testing.T) {
+	tests := []struct {
+		in   string
+		want bool
+	}{
+		{in: "http://localhost:8080", want: true},
+		{in: "http://minio:9000", want: true},
+		{in: "http://10.0.0.5:9000/", want: true},
+		{in: "https://s3.us-east-1.amazonaws.com", want: true},
+		{in: "localhost:8080", want: false},
+		{in: "ftp://example.com", want: false},
+		{in: "http://example.com/?a=b", want: false},
+	}
+
+	for _, tt := range tests {
+		if got := IsValidEndpoint(tt.in); got != tt.want {
+			t.Errorf("IsValidEndpoint(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/malikfajr/eq-store/entity"
+)
+
+type BundleRepository interface {
+	FindComponents(ctx context.Context, pool *pgxpool.Pool, bundleIds []string) map[string][]entity.BundleComponent
+	ReplaceComponentsTx(ctx context.Context, tx pgx.Tx, bundleId string, components []entity.BundleComponent) error
+}
+
+type bundleRepository struct{}
+
+func NewBundleRepository() BundleRepository {
+	return &bundleRepository{}
+}
+
+func (b *bundleRepository) FindComponents(ctx context.Context, pool *pgxpool.Pool, bundleIds []string) map[string][]entity.BundleComponent {
+	query := `
+		SELECT bundle_id, product_id, quantity FROM bundle_items
+		WHERE bundle_id::TEXT = ANY($1)
+		ORDER BY bundle_id, product_id`
+
+	rows, err := pool.Query(ctx, query, bundleIds)
+	if err != nil {
+		panic(err)
+	}
+	defer rows.Close()
+
+	result := map[string][]entity.BundleComponent{}
+	for rows.Next() {
+		var bundleId string
+		component := entity.BundleComponent{}
+		if err := rows.Scan(&bundleId, &component.ProductId, &component.Quantity); err != nil {
+			panic(err)
+		}
+		result[bundleId] = append(result[bundleId], component)
+	}
+
+	return result
+}
+
+func (b *bundleRepository) ReplaceComponentsTx(ctx context.Context, tx pgx.Tx, bundleId string, components []entity.BundleComponent) error {
+	if _, err := tx.Exec(ctx, "DELETE FROM bundle_items WHERE bundle_id = $1", bundleId); err != nil {
+		return err
+	}
+
+	query := "INSERT INTO bundle_items (bundle_id, product_id, quantity) VALUES ($1, $2, $3)"
+
+	for _, component := range components {
+		if _, err := tx.Exec(ctx, query, bundleId, component.ProductId, component.Quantity); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
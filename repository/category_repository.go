@@ -0,0 +1,164 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/malikfajr/eq-store/entity"
+)
+
+type CategoryRepository interface {
+	Insert(ctx context.Context, pool *pgxpool.Pool, category *entity.Category) error
+	FindAll(ctx context.Context, pool *pgxpool.Pool, page *entity.PageParams) ([]entity.Category, *entity.PageMeta, error)
+	FindOne(ctx context.Context, pool *pgxpool.Pool, ID string) (*entity.Category, error)
+	IsExist(ctx context.Context, pool *pgxpool.Pool, ID string) bool
+	IsExistByName(ctx context.Context, pool *pgxpool.Pool, name string) bool
+	IsDescendant(ctx context.Context, pool *pgxpool.Pool, ID string, ancestorId string) bool
+	IsUsed(ctx context.Context, pool *pgxpool.Pool, ID string) bool
+	Update(ctx context.Context, pool *pgxpool.Pool, category *entity.Category) error
+	Delete(ctx context.Context, pool *pgxpool.Pool, ID string) error
+}
+
+type categoryRepository struct{}
+
+func NewCategoryRepository() CategoryRepository {
+	return &categoryRepository{}
+}
+
+func (c *categoryRepository) Insert(ctx context.Context, pool *pgxpool.Pool, category *entity.Category) error {
+	query := "INSERT INTO categories (name, parent_id) VALUES ($1, $2) RETURNING id, created_at"
+
+	return pool.QueryRow(ctx, query, category.Name, category.ParentId).Scan(&category.Id, &category.CreatedAt)
+}
+
+func (c *categoryRepository) FindAll(ctx context.Context, pool *pgxpool.Pool, page *entity.PageParams) ([]entity.Category, *entity.PageMeta, error) {
+	keys, sort, err := nameKeys("name", "id", page.Cursor)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	args := pgx.NamedArgs{}
+	rows, err := pool.Query(ctx, pageQuery("SELECT id, name, parent_id, created_at FROM categories WHERE TRUE", keys, page, args), args)
+	if err != nil {
+		panic(err)
+	}
+
+	categories, err := pgx.CollectRows(rows, pgx.RowToStructByPos[entity.Category])
+	if err != nil {
+		panic(err)
+	}
+
+	categories, meta := paginate(categories, page, func(category entity.Category) entity.Cursor {
+		name := category.Name
+		return entity.Cursor{Sort: sort, Name: &name, Id: category.Id}
+	})
+
+	if page.WithTotal {
+		if meta.Total, err = countRows(ctx, pool, "SELECT COUNT(*) FROM categories", args); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return categories, meta, nil
+}
+
+func (c *categoryRepository) FindOne(ctx context.Context, pool *pgxpool.Pool, ID string) (*entity.Category, error) {
+	query := "SELECT id, name, parent_id, created_at FROM categories WHERE id = $1"
+
+	rows, err := pool.Query(ctx, query, ID)
+	if err != nil {
+		return nil, errors.New("category id not found")
+	}
+
+	category, err := pgx.CollectOneRow(rows, pgx.RowToStructByPos[entity.Category])
+	if err != nil {
+		return nil, errors.New("category id not found")
+	}
+
+	return &category, nil
+}
+
+func (c *categoryRepository) IsExist(ctx context.Context, pool *pgxpool.Pool, ID string) bool {
+	var n int
+	query := "SELECT 1 FROM categories WHERE id = $1"
+
+	err := pool.QueryRow(ctx, query, ID).Scan(&n)
+	if err != nil {
+		return false
+	}
+
+	return true
+}
+
+func (c *categoryRepository) IsExistByName(ctx context.Context, pool *pgxpool.Pool, name string) bool {
+	var n int
+	query := "SELECT 1 FROM categories WHERE name = $1"
+
+	err := pool.QueryRow(ctx, query, name).Scan(&n)
+	if err != nil {
+		return false
+	}
+
+	return true
+}
+
+// IsDescendant reports whether ID is ancestorId itself or one of its subcategories.
+func (c *categoryRepository) IsDescendant(ctx context.Context, pool *pgxpool.Pool, ID string, ancestorId string) bool {
+	var n int
+	query := `
+		WITH RECURSIVE tree AS (
+			SELECT id FROM categories WHERE id = $2
+			UNION ALL
+			SELECT c.id FROM categories c JOIN tree t ON c.parent_id = t.id
+		)
+		SELECT 1 FROM tree WHERE id = $1`
+
+	err := pool.QueryRow(ctx, query, ID, ancestorId).Scan(&n)
+	if err != nil {
+		return false
+	}
+
+	return true
+}
+
+func (c *categoryRepository) IsUsed(ctx context.Context, pool *pgxpool.Pool, ID string) bool {
+	var n int
+	query := `
+		SELECT 1 FROM categories c
+		WHERE c.id = $1 AND (
+			EXISTS (SELECT 1 FROM categories sub WHERE sub.parent_id = c.id)
+			OR EXISTS (SELECT 1 FROM products p WHERE p.category = c.name)
+		)`
+
+	err := pool.QueryRow(ctx, query, ID).Scan(&n)
+	if err != nil {
+		return false
+	}
+
+	return true
+}
+
+func (c *categoryRepository) Update(ctx context.Context, pool *pgxpool.Pool, category *entity.Category) error {
+	query := "UPDATE categories SET name = $1, parent_id = $2 WHERE id = $3"
+
+	_, err := pool.Exec(ctx, query, category.Name, category.ParentId, category.Id)
+
+	return err
+}
+
+func (c *categoryRepository) Delete(ctx context.Context, pool *pgxpool.Pool, ID string) error {
+	query := "DELETE FROM categories WHERE id = $1"
+
+	tag, err := pool.Exec(ctx, query, ID)
+	if err != nil {
+		return err
+	}
+
+	if tag.RowsAffected() < 1 {
+		return errors.New("category id not found")
+	}
+
+	return nil
+}
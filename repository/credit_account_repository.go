@@ -0,0 +1,237 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/malikfajr/eq-store/entity"
+)
+
+// ErrOverpaid is returned when a payment or refund would take the balance of
+// a credit account below zero.
+var ErrOverpaid = errors.New("amount is more than the balance")
+
+// CreditAccountRepository keeps the accounts customers buy on and their
+// entries. A balance only changes through MoveTx, which records the entry
+// with it.
+type CreditAccountRepository interface {
+	Save(ctx context.Context, pool *pgxpool.Pool, account *entity.CreditAccount, staffId string) error
+	FindOne(ctx context.Context, pool *pgxpool.Pool, customerId string) (*entity.CreditAccount, error)
+	LockOneTx(ctx context.Context, tx pgx.Tx, customerId string) (*entity.CreditAccount, error)
+	MoveTx(ctx context.Context, tx pgx.Tx, account *entity.CreditAccount, entry *entity.AccountEntry) error
+	FindEntries(ctx context.Context, pool *pgxpool.Pool, customerId string, params *entity.AccountQueryParams) ([]entity.AccountEntry, *entity.PageMeta, error)
+	FindAllEntries(ctx context.Context, pool *pgxpool.Pool, customerId string) []entity.AccountEntry
+	FindEntriesBetween(ctx context.Context, pool *pgxpool.Pool, customerId string, from time.Time, to time.Time) []entity.AccountEntry
+	BalanceAt(ctx context.Context, pool *pgxpool.Pool, customerId string, t time.Time) int
+	Aging(ctx context.Context, pool *pgxpool.Pool, asOf time.Time, customerId string) []entity.CreditAging
+}
+
+type creditAccountRepository struct{}
+
+func NewCreditAccountRepository() CreditAccountRepository {
+	return &creditAccountRepository{}
+}
+
+const (
+	creditAccountColumns = "customer_id, credit_limit, balance, created_at, updated_at"
+	accountEntryColumns  = "id, customer_id, type, amount, balance_after, transaction_id, staff_id, note, created_at"
+)
+
+// Save opens the credit account of a customer or changes its limit.
+func (c *creditAccountRepository) Save(ctx context.Context, pool *pgxpool.Pool, account *entity.CreditAccount, staffId string) error {
+	query := `
+		INSERT INTO credit_accounts (customer_id, credit_limit, created_by)
+		VALUES ($1, $2, NULLIF($3, '')::UUID)
+		ON CONFLICT (customer_id) DO UPDATE SET credit_limit = EXCLUDED.credit_limit, updated_at = NOW()
+		RETURNING balance, created_at, updated_at`
+
+	return pool.QueryRow(ctx, query, account.CustomerId, account.CreditLimit, staffId).
+		Scan(&account.Balance, &account.CreatedAt, &account.UpdatedAt)
+}
+
+func (c *creditAccountRepository) FindOne(ctx context.Context, pool *pgxpool.Pool, customerId string) (*entity.CreditAccount, error) {
+	query := "SELECT " + creditAccountColumns + " FROM credit_accounts WHERE customer_id = $1"
+
+	return c.collectOne(pool.Query(ctx, query, customerId))
+}
+
+func (c *creditAccountRepository) LockOneTx(ctx context.Context, tx pgx.Tx, customerId string) (*entity.CreditAccount, error) {
+	query := "SELECT " + creditAccountColumns + " FROM credit_accounts WHERE customer_id = $1 FOR UPDATE"
+
+	return c.collectOne(tx.Query(ctx, query, customerId))
+}
+
+func (c *creditAccountRepository) collectOne(rows pgx.Rows, err error) (*entity.CreditAccount, error) {
+	if err != nil {
+		return nil, err
+	}
+
+	account, err := pgx.CollectOneRow(rows, pgx.RowToStructByPos[entity.CreditAccount])
+	if err != nil {
+		return nil, err
+	}
+
+	return &account, nil
+}
+
+// MoveTx adds entry.Amount to the balance of a locked account and records the
+// entry. An entry taking the balance below zero is refused with ErrOverpaid,
+// the credit limit is left to the caller.
+func (c *creditAccountRepository) MoveTx(ctx context.Context, tx pgx.Tx, account *entity.CreditAccount, entry *entity.AccountEntry) error {
+	if account.Balance+entry.Amount < 0 {
+		return ErrOverpaid
+	}
+
+	update := "UPDATE credit_accounts SET balance = balance + $2 WHERE customer_id = $1 RETURNING balance"
+	if err := tx.QueryRow(ctx, update, account.CustomerId, entry.Amount).Scan(&account.Balance); err != nil {
+		return err
+	}
+
+	entry.CustomerId = account.CustomerId
+	entry.BalanceAfter = account.Balance
+
+	query := `
+		INSERT INTO credit_account_entries (customer_id, type, amount, balance_after, transaction_id, staff_id, note)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id, created_at`
+
+	return tx.QueryRow(ctx, query, entry.CustomerId, entry.Type, entry.Amount, entry.BalanceAfter, entry.TransactionId, entry.StaffId, entry.Note).
+		Scan(&entry.Id, &entry.CreatedAt)
+}
+
+func (c *creditAccountRepository) FindEntries(ctx context.Context, pool *pgxpool.Pool, customerId string, params *entity.AccountQueryParams) ([]entity.AccountEntry, *entity.PageMeta, error) {
+	query := "SELECT " + accountEntryColumns + " FROM credit_account_entries WHERE customer_id = @customerId"
+	args := pgx.NamedArgs{"customerId": customerId}
+
+	keys, sort, err := createdAtKeys("created_at", "id", params.CreatedAt, params.Cursor)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	rows, err := pool.Query(ctx, pageQuery(query, keys, &params.PageParams, args), args)
+	if err != nil {
+		panic(err)
+	}
+
+	entries, err := pgx.CollectRows(rows, pgx.RowToStructByPos[entity.AccountEntry])
+	if err != nil {
+		panic(err)
+	}
+
+	entries, meta := paginate(entries, &params.PageParams, func(entry entity.AccountEntry) entity.Cursor {
+		return entity.Cursor{Sort: sort, CreatedAt: entry.CreatedAt, Id: entry.Id}
+	})
+
+	if params.WithTotal {
+		if meta.Total, err = countRows(ctx, pool, "SELECT COUNT(*) FROM credit_account_entries WHERE customer_id = @customerId", args); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return entries, meta, nil
+}
+
+// FindAllEntries returns every entry of an account, oldest first.
+func (c *creditAccountRepository) FindAllEntries(ctx context.Context, pool *pgxpool.Pool, customerId string) []entity.AccountEntry {
+	query := "SELECT " + accountEntryColumns + " FROM credit_account_entries WHERE customer_id = $1 ORDER BY created_at ASC, id ASC"
+
+	rows, err := pool.Query(ctx, query, customerId)
+	if err != nil {
+		panic(err)
+	}
+
+	entries, err := pgx.CollectRows(rows, pgx.RowToStructByPos[entity.AccountEntry])
+	if err != nil {
+		panic(err)
+	}
+
+	return entries
+}
+
+// FindEntriesBetween returns the entries of an account from from, inclusive,
+// to to, exclusive, oldest first.
+func (c *creditAccountRepository) FindEntriesBetween(ctx context.Context, pool *pgxpool.Pool, customerId string, from time.Time, to time.Time) []entity.AccountEntry {
+	query := `
+		SELECT ` + accountEntryColumns + ` FROM credit_account_entries
+		WHERE customer_id = $1 AND created_at >= $2 AND created_at < $3
+		ORDER BY created_at ASC, id ASC`
+
+	rows, err := pool.Query(ctx, query, customerId, from, to)
+	if err != nil {
+		panic(err)
+	}
+
+	entries, err := pgx.CollectRows(rows, pgx.RowToStructByPos[entity.AccountEntry])
+	if err != nil {
+		panic(err)
+	}
+
+	return entries
+}
+
+// BalanceAt returns the balance an account had just before t.
+func (c *creditAccountRepository) BalanceAt(ctx context.Context, pool *pgxpool.Pool, customerId string, t time.Time) int {
+	query := `
+		SELECT COALESCE((SELECT balance_after FROM credit_account_entries
+			WHERE customer_id = $1 AND created_at < $2
+			ORDER BY created_at DESC, id DESC LIMIT 1), 0)`
+
+	var balance int
+	if err := pool.QueryRow(ctx, query, customerId, t).Scan(&balance); err != nil {
+		panic(err)
+	}
+
+	return balance
+}
+
+// Aging splits the balances owed just before asOf by the age of their
+// charges, of every account or of customerId only. Payments and refunds
+// settle the oldest charges first, so a balance is made of the newest
+// charges adding up to it.
+func (c *creditAccountRepository) Aging(ctx context.Context, pool *pgxpool.Pool, asOf time.Time, customerId string) []entity.CreditAging {
+	where := ""
+	args := pgx.NamedArgs{"asOf": asOf}
+
+	if customerId != "" {
+		where = " AND customer_id = @customerId"
+		args["customerId"] = customerId
+	}
+
+	query := `
+		WITH balances AS (
+			SELECT DISTINCT ON (customer_id) customer_id, balance_after AS balance
+			FROM credit_account_entries
+			WHERE created_at < @asOf::TIMESTAMP` + where + `
+			ORDER BY customer_id, created_at DESC, id DESC
+		), charges AS (
+			SELECT e.customer_id, b.balance, EXTRACT(DAY FROM @asOf::TIMESTAMP - e.created_at) AS age,
+				LEAST(e.amount, GREATEST(b.balance - (SUM(e.amount) OVER (
+					PARTITION BY e.customer_id ORDER BY e.created_at DESC, e.id DESC) - e.amount), 0)) AS outstanding
+			FROM credit_account_entries e JOIN balances b ON b.customer_id = e.customer_id
+			WHERE e.type = 'charge' AND e.created_at < @asOf::TIMESTAMP AND b.balance > 0
+		)
+		SELECT ch.customer_id, cu.name, a.credit_limit, MAX(ch.balance),
+			COALESCE(SUM(ch.outstanding) FILTER (WHERE ch.age <= 30), 0)::BIGINT,
+			COALESCE(SUM(ch.outstanding) FILTER (WHERE ch.age > 30 AND ch.age <= 60), 0)::BIGINT,
+			COALESCE(SUM(ch.outstanding) FILTER (WHERE ch.age > 60), 0)::BIGINT
+		FROM charges ch
+			JOIN credit_accounts a ON a.customer_id = ch.customer_id
+			JOIN customers cu ON cu.id = ch.customer_id
+		GROUP BY ch.customer_id, cu.name, a.credit_limit
+		ORDER BY MAX(ch.balance) DESC, cu.name ASC`
+
+	rows, err := pool.Query(ctx, query, args)
+	if err != nil {
+		panic(err)
+	}
+
+	aging, err := pgx.CollectRows(rows, pgx.RowToStructByPos[entity.CreditAging])
+	if err != nil {
+		panic(err)
+	}
+
+	return aging
+}
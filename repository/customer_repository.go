@@ -2,17 +2,35 @@ package repository
 
 import (
 	"context"
+	"errors"
 	"strings"
 
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/malikfajr/eq-store/entity"
+	"github.com/malikfajr/eq-store/pkg"
 )
 
 type CustomerRepository interface {
-	FindMany(ctx context.Context, pool *pgxpool.Pool, params *entity.CustomerQueryParams) *[]entity.Customer
+	FindMany(ctx context.Context, pool *pgxpool.Pool, params *entity.CustomerQueryParams) (*[]entity.Customer, *entity.PageMeta, error)
 	Create(ctx context.Context, pool *pgxpool.Pool, customer *entity.Customer) (string, error)
 	IsExist(ctx context.Context, pool *pgxpool.Pool, customerId string) bool
+	FindOne(ctx context.Context, pool *pgxpool.Pool, ID string) (*entity.Customer, error)
+	Update(ctx context.Context, pool *pgxpool.Pool, customer *entity.Customer) error
+	Delete(ctx context.Context, pool *pgxpool.Pool, ID string) error
+	Summary(ctx context.Context, pool *pgxpool.Pool, ID string) *entity.CustomerSummary
+	FavouriteCategories(ctx context.Context, pool *pgxpool.Pool, ID string, limit int) []entity.FavouriteCategory
+	LockManyTx(ctx context.Context, tx pgx.Tx, IDs []string) ([]entity.Customer, error)
+	MoveTransactionsTx(ctx context.Context, tx pgx.Tx, fromId string, toId string) (int, error)
+	MarkMergedTx(ctx context.Context, tx pgx.Tx, ID string, survivorId string) error
+	InsertMergeTx(ctx context.Context, tx pgx.Tx, merge *entity.CustomerMerge) error
+	FindDuplicates(ctx context.Context, pool *pgxpool.Pool, params *entity.DuplicateQueryParams) ([]entity.DuplicateCustomer, *entity.PageMeta, error)
+	FindForExport(ctx context.Context, pool *pgxpool.Pool, ID string) (*entity.CustomerExport, error)
+	FindMerges(ctx context.Context, pool *pgxpool.Pool, ID string) []entity.CustomerMerge
+	EraseTx(ctx context.Context, tx pgx.Tx, ID string) error
+	InsertPrivacyLogTx(ctx context.Context, tx pgx.Tx, ID string, action string, staffId string) error
+	FindTags(ctx context.Context, pool *pgxpool.Pool, ID string) []string
+	SetTagsTx(ctx context.Context, tx pgx.Tx, ID string, tags []string) error
 }
 
 type customerRepository struct{}
@@ -22,43 +40,124 @@ func NewCustomerRepository() CustomerRepository {
 }
 
 func (c *customerRepository) Create(ctx context.Context, pool *pgxpool.Pool, customer *entity.Customer) (string, error) {
-	query := "INSERT INTO customers (phone_number, name) VALUES ($1, $2) RETURNING id"
+	query := "INSERT INTO customers (phone_number, name) VALUES ($1, $2) RETURNING id, created_at"
 
-	err := pool.QueryRow(ctx, query, customer.PhoneNumber, customer.Name).Scan(&customer.UserId)
+	err := pool.QueryRow(ctx, query, customer.PhoneNumber, customer.Name).Scan(&customer.UserId, &customer.CreatedAt)
 
 	return customer.UserId, err
 }
 
-func (c *customerRepository) FindMany(ctx context.Context, pool *pgxpool.Pool, params *entity.CustomerQueryParams) *[]entity.Customer {
-	query := "SELECT id, phone_number, name FROM customers WHERE 1=1"
+// FindMany matches part of the name, typos included, and part of the phone
+// number however it is written. It can be limited to a tag or to the members
+// of a segment.
+func (c *customerRepository) FindMany(ctx context.Context, pool *pgxpool.Pool, params *entity.CustomerQueryParams) (*[]entity.Customer, *entity.PageMeta, error) {
+	where := ""
 	args := pgx.NamedArgs{}
 
-	if params.Name != "" {
-		query += " AND LOWER(name) = @name"
-		args["name"] = "%" + strings.ToLower(params.Name) + "%"
+	if name := strings.ToLower(strings.TrimSpace(params.Name)); name != "" {
+		where += " AND (LOWER(name) LIKE @name OR @nameQ <% LOWER(name))"
+		args["name"] = "%" + escapeLike(name) + "%"
+		args["nameQ"] = name
 	}
 
-	if params.PhoneNumber != "" {
-		query += " AND phone_number = @phoneNumber"
-		args["phoneNumber"] = "%" + params.PhoneNumber
+	if phone := pkg.PhoneDigits(params.PhoneNumber); phone != "" {
+		// a number left as it is by a conflict is found by its normalized form too
+		where += " AND (" + phoneDigitsColumn + ` LIKE @phoneNumber OR id IN (
+			SELECT row_id FROM phone_number_conflicts
+			WHERE table_name = 'customers' AND resolved_at IS NULL
+				AND REGEXP_REPLACE(normalized, '[^0-9]', '', 'g') LIKE @phoneNumber))`
+		args["phoneNumber"] = "%" + phone + "%"
 	}
 
-	rows, err := pool.Query(ctx, query, args)
+	if params.Tag != "" {
+		where += " AND id IN (SELECT customer_id FROM customer_tags WHERE tag = @tag)"
+		args["tag"] = params.Tag
+	}
+
+	if params.Segment != nil {
+		where += " AND id IN (" + segmentQuery(params.Segment, args) + ")"
+	}
+
+	keys, sort, err := c.sortKeys(params)
 	if err != nil {
-		panic(err)
+		return nil, nil, err
 	}
 
-	customers, err := pgx.CollectRows(rows, pgx.RowToStructByPos[entity.Customer])
+	query := pageQuery("SELECT id, phone_number, name, created_at FROM customers WHERE deleted_at IS NULL"+where, keys, &params.PageParams, args)
+
+	var customers []entity.Customer
+	var total *int
+	err = withSimilarityThreshold(ctx, pool, func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, query, args)
+		if err != nil {
+			return err
+		}
+
+		if customers, err = pgx.CollectRows(rows, pgx.RowToStructByPos[entity.Customer]); err != nil {
+			return err
+		}
+
+		if params.WithTotal {
+			total = new(int)
+			return tx.QueryRow(ctx, "SELECT COUNT(*) FROM customers WHERE deleted_at IS NULL"+where, args).Scan(total)
+		}
+
+		return nil
+	})
 	if err != nil {
 		panic(err)
 	}
 
-	return &customers
+	customers, meta := paginate(customers, &params.PageParams, func(customer entity.Customer) entity.Cursor {
+		cursor := entity.Cursor{Sort: sort, CreatedAt: *customer.CreatedAt, Id: customer.UserId}
+		if params.SortName != "" {
+			name := strings.ToLower(customer.Name)
+			cursor.Name = &name
+		}
+		return cursor
+	})
+	meta.Total = total
+
+	return &customers, meta, nil
+}
+
+// phoneDigitsColumn is the phone number without "+", spaces or dashes, the
+// form pkg.PhoneDigits brings a search to.
+const phoneDigitsColumn = "REGEXP_REPLACE(phone_number, '[^0-9]', '', 'g')"
+
+// sortKeys orders by name when asked, then by created_at and id so every
+// ordering can be paged with a cursor.
+func (c *customerRepository) sortKeys(params *entity.CustomerQueryParams) ([]sortKey, string, error) {
+	if params.SortName == "" {
+		return createdAtKeys("created_at", "id", params.CreatedAt, params.Cursor)
+	}
+
+	createdDesc := params.CreatedAt != "asc"
+	sort := "name:" + params.SortName + ",created_at:" + params.CreatedAt
+	keys := []sortKey{
+		{column: "LOWER(name)", desc: params.SortName == "desc"},
+		{column: "created_at", desc: createdDesc},
+		{column: "id", desc: createdDesc},
+	}
+
+	if params.Cursor == nil {
+		return keys, sort, nil
+	}
+
+	if params.Cursor.Sort != sort || params.Cursor.Name == nil {
+		return nil, "", ErrInvalidCursor
+	}
+
+	keys[0].value = *params.Cursor.Name
+	keys[1].value = params.Cursor.CreatedAt
+	keys[2].value = params.Cursor.Id
+
+	return keys, sort, nil
 }
 
 func (c *customerRepository) IsExist(ctx context.Context, pool *pgxpool.Pool, customerId string) bool {
 	var n int
-	query := "SELECT 1 FROM customers WHERE id = $1"
+	query := "SELECT 1 FROM customers WHERE deleted_at IS NULL AND id = $1"
 
 	err := pool.QueryRow(ctx, query, customerId).Scan(&n)
 	if err != nil {
@@ -67,3 +166,340 @@ func (c *customerRepository) IsExist(ctx context.Context, pool *pgxpool.Pool, cu
 
 	return true
 }
+
+func (c *customerRepository) FindOne(ctx context.Context, pool *pgxpool.Pool, ID string) (*entity.Customer, error) {
+	query := "SELECT id, phone_number, name, created_at FROM customers WHERE deleted_at IS NULL AND id = $1 LIMIT 1"
+
+	rows, err := pool.Query(ctx, query, ID)
+	if err != nil {
+		return nil, errors.New("customer id not found")
+	}
+
+	customer, err := pgx.CollectOneRow(rows, pgx.RowToStructByPos[entity.Customer])
+	if err != nil {
+		return nil, errors.New("customer id not found")
+	}
+
+	return &customer, nil
+}
+
+func (c *customerRepository) Update(ctx context.Context, pool *pgxpool.Pool, customer *entity.Customer) error {
+	query := "UPDATE customers SET name = $2, phone_number = $3 WHERE deleted_at IS NULL AND id = $1"
+
+	_, err := pool.Exec(ctx, query, customer.UserId, customer.Name, customer.PhoneNumber)
+
+	return err
+}
+
+// Delete hides customer ID, its transactions are kept.
+func (c *customerRepository) Delete(ctx context.Context, pool *pgxpool.Pool, ID string) error {
+	query := "UPDATE customers SET deleted_at = NOW() WHERE deleted_at IS NULL AND id = $1"
+
+	tag, err := pool.Exec(ctx, query, ID)
+	if err != nil {
+		return err
+	}
+
+	if tag.RowsAffected() < 1 {
+		return errors.New("customer id not found")
+	}
+
+	return nil
+}
+
+// Summary totals the transactions of customer ID that were not refunded. A
+// transaction is worth the lines sold in it, the component lines of a bundle
+// are left out.
+func (c *customerRepository) Summary(ctx context.Context, pool *pgxpool.Pool, ID string) *entity.CustomerSummary {
+	query := `
+		SELECT COALESCE(SUM(total), 0), COUNT(*), COALESCE(ROUND(AVG(total)), 0), MIN(created_at), MAX(created_at)
+		FROM (
+			SELECT t.created_at, (SELECT COALESCE(SUM(td.total_price), 0) FROM transaction_detail td
+				WHERE td.transaction_id = t.id AND td.bundle_id IS NULL) AS total
+			FROM transactions t
+			WHERE t.customer_id = $1 AND t.refunded_at IS NULL
+		) t`
+
+	summary := &entity.CustomerSummary{CustomerId: ID}
+	err := pool.QueryRow(ctx, query, ID).Scan(&summary.TotalSpend, &summary.VisitCount, &summary.AverageBasket, &summary.FirstPurchaseAt, &summary.LastPurchaseAt)
+	if err != nil {
+		panic(err)
+	}
+
+	return summary
+}
+
+// FavouriteCategories ranks the categories customer ID spent the most on.
+func (c *customerRepository) FavouriteCategories(ctx context.Context, pool *pgxpool.Pool, ID string, limit int) []entity.FavouriteCategory {
+	query := `
+		SELECT p.category, COUNT(DISTINCT t.id), SUM(td.total_price)::BIGINT AS spend
+		FROM transactions t
+			JOIN transaction_detail td ON td.transaction_id = t.id AND td.bundle_id IS NULL
+			JOIN products p ON p.id = td.product_id
+		WHERE t.customer_id = $1 AND t.refunded_at IS NULL
+		GROUP BY p.category
+		ORDER BY spend DESC, 2 DESC, p.category ASC
+		LIMIT $2`
+
+	rows, err := pool.Query(ctx, query, ID, limit)
+	if err != nil {
+		panic(err)
+	}
+
+	categories, err := pgx.CollectRows(rows, pgx.RowToStructByPos[entity.FavouriteCategory])
+	if err != nil {
+		panic(err)
+	}
+
+	return categories
+}
+
+// LockManyTx locks the customers that are not deleted among IDs, in id order
+// so two merges can not deadlock.
+func (c *customerRepository) LockManyTx(ctx context.Context, tx pgx.Tx, IDs []string) ([]entity.Customer, error) {
+	query := `
+		SELECT id, phone_number, name, created_at FROM customers
+		WHERE deleted_at IS NULL AND id::TEXT = ANY($1)
+		ORDER BY id
+		FOR UPDATE`
+
+	rows, err := tx.Query(ctx, query, IDs)
+	if err != nil {
+		return nil, err
+	}
+
+	return pgx.CollectRows(rows, pgx.RowToStructByPos[entity.Customer])
+}
+
+func (c *customerRepository) MoveTransactionsTx(ctx context.Context, tx pgx.Tx, fromId string, toId string) (int, error) {
+	tag, err := tx.Exec(ctx, "UPDATE transactions SET customer_id = $2 WHERE customer_id = $1", fromId, toId)
+	if err != nil {
+		return 0, err
+	}
+
+	return int(tag.RowsAffected()), nil
+}
+
+// MarkMergedTx deletes a merged customer, pointing it at the survivor, gives
+// its tags to the survivor and resolves the phone number conflicts it was
+// part of.
+func (c *customerRepository) MarkMergedTx(ctx context.Context, tx pgx.Tx, ID string, survivorId string) error {
+	query := "UPDATE customers SET deleted_at = NOW(), merged_into = $2 WHERE id = $1"
+	if _, err := tx.Exec(ctx, query, ID, survivorId); err != nil {
+		return err
+	}
+
+	tags := `
+		INSERT INTO customer_tags (customer_id, tag)
+		SELECT $2, tag FROM customer_tags WHERE customer_id = $1
+		ON CONFLICT (customer_id, tag) DO NOTHING`
+
+	if _, err := tx.Exec(ctx, tags, ID, survivorId); err != nil {
+		return err
+	}
+
+	conflicts := `
+		UPDATE phone_number_conflicts SET resolved_at = NOW()
+		WHERE table_name = 'customers' AND (row_id = $1 OR duplicate_of = $1) AND resolved_at IS NULL`
+
+	_, err := tx.Exec(ctx, conflicts, ID)
+
+	return err
+}
+
+func (c *customerRepository) InsertMergeTx(ctx context.Context, tx pgx.Tx, merge *entity.CustomerMerge) error {
+	query := `
+		INSERT INTO customer_merges (survivor_id, duplicate_id, staff_id, transactions, points, credit)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at`
+
+	return tx.QueryRow(ctx, query, merge.SurvivorId, merge.DuplicateId, merge.StaffId, merge.Transactions, merge.Points, merge.Credit).
+		Scan(&merge.Id, &merge.CreatedAt)
+}
+
+// FindDuplicates pairs customers that share a phone number once it is
+// normalized, a number left unnormalized by a conflict counts as its
+// normalized form, or whose names are similar. Pairs sharing a phone number
+// come first, then the most similar names.
+func (c *customerRepository) FindDuplicates(ctx context.Context, pool *pgxpool.Pool, params *entity.DuplicateQueryParams) ([]entity.DuplicateCustomer, *entity.PageMeta, error) {
+	from := `
+		FROM (
+			WITH active AS (
+				SELECT c.id, c.phone_number, c.name, c.created_at, LOWER(c.name) AS lower_name,
+					COALESCE(pc.normalized, c.phone_number) AS normalized
+				FROM customers c
+					LEFT JOIN phone_number_conflicts pc
+						ON pc.table_name = 'customers' AND pc.row_id = c.id AND pc.resolved_at IS NULL
+				WHERE c.deleted_at IS NULL
+			)
+			SELECT (CASE WHEN a.normalized = b.normalized THEN 2 ELSE 0 END)
+					+ ROUND(SIMILARITY(a.lower_name, b.lower_name)::NUMERIC, 2)::FLOAT8 AS rank,
+				a.id AS a_id, a.phone_number AS a_phone_number, a.name AS a_name, a.created_at AS a_created_at,
+				b.id AS b_id, b.phone_number AS b_phone_number, b.name AS b_name, b.created_at AS b_created_at,
+				a.normalized = b.normalized AS same_phone,
+				ROUND(SIMILARITY(a.lower_name, b.lower_name)::NUMERIC, 2)::FLOAT8 AS name_similarity
+			FROM active a
+				JOIN active b ON (a.created_at, a.id) < (b.created_at, b.id)
+					AND (a.normalized = b.normalized OR a.lower_name % b.lower_name)
+			WHERE a.normalized = b.normalized OR SIMILARITY(a.lower_name, b.lower_name) >= @minSimilarity
+		) d
+		WHERE TRUE`
+	args := pgx.NamedArgs{"minSimilarity": params.MinSimilarity}
+
+	// rank puts a shared phone number above any name similarity, which is at most 1
+	const sort = "rank:desc,created_at:asc"
+	keys := []sortKey{
+		{column: "d.rank", desc: true},
+		{column: "d.a_created_at"},
+		{column: "d.a_id"},
+		{column: "d.b_id"},
+	}
+
+	if params.Cursor != nil {
+		if params.Cursor.Sort != sort || params.Cursor.Score == nil || params.Cursor.OtherId == nil {
+			return nil, nil, ErrInvalidCursor
+		}
+
+		keys[0].value = *params.Cursor.Score
+		keys[1].value = params.Cursor.CreatedAt
+		keys[2].value = params.Cursor.Id
+		keys[3].value = *params.Cursor.OtherId
+	}
+
+	query := `
+		SELECT d.a_id, d.a_phone_number, d.a_name, d.a_created_at, d.b_id, d.b_phone_number, d.b_name, d.b_created_at,
+			d.same_phone, d.name_similarity` + from
+
+	rows, err := pool.Query(ctx, pageQuery(query, keys, &params.PageParams, args), args)
+	if err != nil {
+		panic(err)
+	}
+	defer rows.Close()
+
+	duplicates := []entity.DuplicateCustomer{}
+	for rows.Next() {
+		d := entity.DuplicateCustomer{}
+		err := rows.Scan(&d.Customer.UserId, &d.Customer.PhoneNumber, &d.Customer.Name, &d.Customer.CreatedAt,
+			&d.Duplicate.UserId, &d.Duplicate.PhoneNumber, &d.Duplicate.Name, &d.Duplicate.CreatedAt, &d.SamePhone, &d.NameSimilarity)
+		if err != nil {
+			panic(err)
+		}
+		duplicates = append(duplicates, d)
+	}
+
+	duplicates, meta := paginate(duplicates, &params.PageParams, func(d entity.DuplicateCustomer) entity.Cursor {
+		rank := d.NameSimilarity
+		if d.SamePhone {
+			rank += 2
+		}
+		otherId := d.Duplicate.UserId
+		return entity.Cursor{Sort: sort, Score: &rank, CreatedAt: *d.Customer.CreatedAt, Id: d.Customer.UserId, OtherId: &otherId}
+	})
+
+	if params.WithTotal {
+		if meta.Total, err = countRows(ctx, pool, "SELECT COUNT(*)"+from, args); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return duplicates, meta, nil
+}
+
+// FindForExport reads the profile of customer ID, deleted and erased
+// customers included.
+func (c *customerRepository) FindForExport(ctx context.Context, pool *pgxpool.Pool, ID string) (*entity.CustomerExport, error) {
+	export := &entity.CustomerExport{}
+	query := "SELECT id, phone_number, name, created_at, deleted_at, erased_at FROM customers WHERE id::TEXT = $1"
+
+	err := pool.QueryRow(ctx, query, ID).Scan(&export.Customer.UserId, &export.Customer.PhoneNumber, &export.Customer.Name,
+		&export.Customer.CreatedAt, &export.DeletedAt, &export.ErasedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return export, nil
+}
+
+// FindMerges returns the merges customer ID took part in, as survivor or duplicate.
+func (c *customerRepository) FindMerges(ctx context.Context, pool *pgxpool.Pool, ID string) []entity.CustomerMerge {
+	query := `
+		SELECT id, survivor_id, duplicate_id, staff_id, transactions, points, credit, created_at
+		FROM customer_merges
+		WHERE survivor_id = $1 OR duplicate_id = $1
+		ORDER BY created_at ASC, id ASC`
+
+	rows, err := pool.Query(ctx, query, ID)
+	if err != nil {
+		panic(err)
+	}
+
+	merges, err := pgx.CollectRows(rows, pgx.RowToStructByPos[entity.CustomerMerge])
+	if err != nil {
+		panic(err)
+	}
+
+	return merges
+}
+
+// EraseTx anonymizes customer ID: the name and phone number are replaced and
+// the customer is deleted, its transactions stay for the books. The phone
+// numbers it left in phone_number_conflicts and its tags are removed as well.
+func (c *customerRepository) EraseTx(ctx context.Context, tx pgx.Tx, ID string) error {
+	query := `
+		UPDATE customers SET name = 'Erased Customer', phone_number = '',
+			deleted_at = COALESCE(deleted_at, NOW()), erased_at = NOW()
+		WHERE id = $1`
+
+	if _, err := tx.Exec(ctx, query, ID); err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(ctx, "DELETE FROM phone_number_conflicts WHERE table_name = 'customers' AND row_id = $1", ID); err != nil {
+		return err
+	}
+
+	_, err := tx.Exec(ctx, "DELETE FROM customer_tags WHERE customer_id = $1", ID)
+
+	return err
+}
+
+func (c *customerRepository) InsertPrivacyLogTx(ctx context.Context, tx pgx.Tx, ID string, action string, staffId string) error {
+	query := "INSERT INTO customer_privacy_log (customer_id, action, staff_id) VALUES ($1, $2, $3)"
+
+	_, err := tx.Exec(ctx, query, ID, action, staffId)
+
+	return err
+}
+
+func (c *customerRepository) FindTags(ctx context.Context, pool *pgxpool.Pool, ID string) []string {
+	query := "SELECT tag FROM customer_tags WHERE customer_id = $1 ORDER BY tag ASC"
+
+	rows, err := pool.Query(ctx, query, ID)
+	if err != nil {
+		panic(err)
+	}
+
+	tags, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		panic(err)
+	}
+
+	return tags
+}
+
+// SetTagsTx replaces the tags of customer ID, the tags kept keep their
+// created_at.
+func (c *customerRepository) SetTagsTx(ctx context.Context, tx pgx.Tx, ID string, tags []string) error {
+	if _, err := tx.Exec(ctx, "DELETE FROM customer_tags WHERE customer_id = $1 AND tag <> ALL($2)", ID, tags); err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO customer_tags (customer_id, tag)
+		SELECT $1, UNNEST($2::TEXT[])
+		ON CONFLICT (customer_id, tag) DO NOTHING`
+
+	_, err := tx.Exec(ctx, query, ID, tags)
+
+	return err
+}
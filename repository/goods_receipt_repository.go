@@ -0,0 +1,111 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/malikfajr/eq-store/entity"
+)
+
+type GoodsReceiptRepository interface {
+	CreateTx(ctx context.Context, tx pgx.Tx, receipt *entity.GoodsReceipt) error
+	InsertItemsTx(ctx context.Context, tx pgx.Tx, receiptId string, items []entity.GoodsReceiptItem) error
+	FindOne(ctx context.Context, pool *pgxpool.Pool, ID string) (*entity.GoodsReceipt, error)
+	FindMany(ctx context.Context, pool *pgxpool.Pool, params *entity.GoodsReceiptQueryParams) ([]entity.GoodsReceipt, *entity.PageMeta, error)
+}
+
+type goodsReceiptRepository struct{}
+
+func NewGoodsReceiptRepository() GoodsReceiptRepository {
+	return &goodsReceiptRepository{}
+}
+
+const goodsReceiptColumns = `
+	g.id, g.location_id, g.supplier, g.notes,
+	(SELECT JSON_AGG(json_build_object('productId', i.product_id, 'quantity', i.quantity, 'unitCost', i.unit_cost,
+		'lotNumber', i.lot_number, 'expiresAt', TO_CHAR(i.expires_at, 'YYYY-MM-DD'),
+		'serials', (SELECT JSON_AGG(s.serial ORDER BY s.serial)
+			FROM product_serials s
+			WHERE s.receipt_id = g.id AND s.product_id = i.product_id)))
+		FROM goods_receipt_items i
+		WHERE i.receipt_id = g.id) AS items,
+	g.received_by, g.received_at`
+
+func (g *goodsReceiptRepository) CreateTx(ctx context.Context, tx pgx.Tx, receipt *entity.GoodsReceipt) error {
+	query := `
+		INSERT INTO goods_receipts (location_id, supplier, notes, received_by)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, received_at`
+
+	return tx.QueryRow(ctx, query, receipt.LocationId, receipt.Supplier, receipt.Notes, receipt.ReceivedBy).
+		Scan(&receipt.Id, &receipt.ReceivedAt)
+}
+
+func (g *goodsReceiptRepository) InsertItemsTx(ctx context.Context, tx pgx.Tx, receiptId string, items []entity.GoodsReceiptItem) error {
+	query := "INSERT INTO goods_receipt_items (receipt_id, product_id, quantity, unit_cost, lot_number, expires_at) VALUES ($1, $2, $3, $4, $5, $6)"
+
+	for _, item := range items {
+		if _, err := tx.Exec(ctx, query, receiptId, item.ProductId, item.Quantity, *item.UnitCost, item.LotNumber, item.ExpiresAt); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (g *goodsReceiptRepository) FindOne(ctx context.Context, pool *pgxpool.Pool, ID string) (*entity.GoodsReceipt, error) {
+	query := "SELECT " + goodsReceiptColumns + " FROM goods_receipts g WHERE g.id = $1"
+
+	rows, err := pool.Query(ctx, query, ID)
+	if err != nil {
+		return nil, errors.New("goods receipt id not found")
+	}
+
+	receipt, err := pgx.CollectOneRow(rows, pgx.RowToStructByPos[entity.GoodsReceipt])
+	if err != nil {
+		return nil, errors.New("goods receipt id not found")
+	}
+
+	return &receipt, nil
+}
+
+func (g *goodsReceiptRepository) FindMany(ctx context.Context, pool *pgxpool.Pool, params *entity.GoodsReceiptQueryParams) ([]entity.GoodsReceipt, *entity.PageMeta, error) {
+	where := ""
+	args := pgx.NamedArgs{}
+
+	if params.LocationId != "" {
+		where += " AND g.location_id = @locationId"
+		args["locationId"] = params.LocationId
+	}
+
+	keys, sort, err := createdAtKeys("g.received_at", "g.id", "desc", params.Cursor)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	query := pageQuery("SELECT "+goodsReceiptColumns+" FROM goods_receipts g WHERE 1=1"+where, keys, &params.PageParams, args)
+
+	rows, err := pool.Query(ctx, query, args)
+	if err != nil {
+		panic(err)
+	}
+
+	receipts, err := pgx.CollectRows(rows, pgx.RowToStructByPos[entity.GoodsReceipt])
+	if err != nil {
+		panic(err)
+	}
+
+	receipts, meta := paginate(receipts, &params.PageParams, func(receipt entity.GoodsReceipt) entity.Cursor {
+		return entity.Cursor{Sort: sort, CreatedAt: *receipt.ReceivedAt, Id: receipt.Id}
+	})
+
+	if params.WithTotal {
+		if meta.Total, err = countRows(ctx, pool, "SELECT COUNT(*) FROM goods_receipts g WHERE 1=1"+where, args); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return receipts, meta, nil
+}
@@ -0,0 +1,217 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/malikfajr/eq-store/entity"
+	"github.com/malikfajr/eq-store/pkg"
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// querier is what a pool and a transaction have in common, for reads that
+// run on either.
+type querier interface {
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+// ErrVersionConflict is returned when a row was changed since it was read.
+var ErrVersionConflict = errors.New("version conflict")
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+// escapeLike escapes user input used inside a LIKE pattern.
+func escapeLike(s string) string {
+	return likeEscaper.Replace(s)
+}
+
+// sortKey is one column of a keyset ordering, the last key must be unique.
+type sortKey struct {
+	column string
+	desc   bool
+	value  any
+}
+
+// orderClause returns the ORDER BY of keys, reversed when reading a previous page.
+func orderClause(keys []sortKey, backward bool) string {
+	columns := []string{}
+	for _, key := range keys {
+		if key.desc != backward {
+			columns = append(columns, key.column+" DESC")
+		} else {
+			columns = append(columns, key.column+" ASC")
+		}
+	}
+
+	return " ORDER BY " + strings.Join(columns, ", ")
+}
+
+// keysetClause returns the condition selecting rows after (or before when
+// backward) the cursor values in keys:
+//
+//	(a > @k0) OR (a = @k0 AND b > @k1) OR ...
+func keysetClause(keys []sortKey, backward bool, args pgx.NamedArgs) string {
+	conditions := []string{}
+
+	for i, key := range keys {
+		parts := []string{}
+		for j := 0; j < i; j++ {
+			parts = append(parts, fmt.Sprintf("%s = @k%d", keys[j].column, j))
+		}
+
+		op := ">"
+		if key.desc != backward {
+			op = "<"
+		}
+		parts = append(parts, fmt.Sprintf("%s %s @k%d", key.column, op, i))
+		args[fmt.Sprintf("k%d", i)] = key.value
+
+		conditions = append(conditions, "("+strings.Join(parts, " AND ")+")")
+	}
+
+	return " AND (" + strings.Join(conditions, " OR ") + ")"
+}
+
+// pageQuery appends the keyset condition, ordering and limit to query. One row
+// more than the limit is read so paginate knows whether another page exists.
+func pageQuery(query string, keys []sortKey, page *entity.PageParams, args pgx.NamedArgs) string {
+	backward := page.Cursor != nil && page.Cursor.Backward
+
+	if page.Cursor != nil {
+		query += keysetClause(keys, backward, args)
+	}
+
+	query += orderClause(keys, backward) + " LIMIT @limit"
+	args["limit"] = page.Limit + 1
+
+	if page.Cursor == nil {
+		query += " OFFSET @offset"
+		args["offset"] = page.Offset
+	}
+
+	return query
+}
+
+// paginate trims the extra row read by pageQuery and builds the page cursors.
+func paginate[T any](rows []T, page *entity.PageParams, cursorOf func(T) entity.Cursor) ([]T, *entity.PageMeta) {
+	backward := page.Cursor != nil && page.Cursor.Backward
+	hasMore := len(rows) > page.Limit
+
+	if hasMore {
+		rows = rows[:page.Limit]
+	}
+
+	if backward {
+		slices.Reverse(rows)
+	}
+
+	meta := &entity.PageMeta{Limit: page.Limit}
+	if len(rows) == 0 {
+		return rows, meta
+	}
+
+	if (!backward && hasMore) || backward {
+		next := cursorOf(rows[len(rows)-1])
+		encoded := pkg.EncodeCursor(next)
+		meta.Next = &encoded
+	}
+
+	if (backward && hasMore) || (!backward && (page.Cursor != nil || page.Offset > 0)) {
+		prev := cursorOf(rows[0])
+		prev.Backward = true
+		encoded := pkg.EncodeCursor(prev)
+		meta.Prev = &encoded
+	}
+
+	return rows, meta
+}
+
+func countRows(ctx context.Context, pool *pgxpool.Pool, query string, args pgx.NamedArgs) (*int, error) {
+	var total int
+
+	err := pool.QueryRow(ctx, query, args).Scan(&total)
+
+	return &total, err
+}
+
+// createdAtKeys orders by a creation time column and id, order is "asc" or
+// "desc" (the default).
+func createdAtKeys(createdColumn string, idColumn string, order string, cursor *entity.Cursor) ([]sortKey, string, error) {
+	desc := order != "asc"
+	sort := "created_at:asc"
+	if desc {
+		sort = "created_at:desc"
+	}
+
+	keys := []sortKey{
+		{column: createdColumn, desc: desc},
+		{column: idColumn, desc: desc},
+	}
+
+	if cursor == nil {
+		return keys, sort, nil
+	}
+
+	if cursor.Sort != sort {
+		return nil, "", ErrInvalidCursor
+	}
+
+	keys[0].value = cursor.CreatedAt
+	keys[1].value = cursor.Id
+
+	return keys, sort, nil
+}
+
+// nameKeys orders by a name column and id, A to Z.
+func nameKeys(nameColumn string, idColumn string, cursor *entity.Cursor) ([]sortKey, string, error) {
+	const sort = "name:asc"
+
+	keys := []sortKey{
+		{column: nameColumn},
+		{column: idColumn},
+	}
+
+	if cursor == nil {
+		return keys, sort, nil
+	}
+
+	if cursor.Sort != sort || cursor.Name == nil {
+		return nil, "", ErrInvalidCursor
+	}
+
+	keys[0].value = *cursor.Name
+	keys[1].value = cursor.Id
+
+	return keys, sort, nil
+}
+
+// searchSimilarityThreshold is lower than the pg_trgm default (0.6) so one or
+// two typos in a short word still match.
+const searchSimilarityThreshold = "0.3"
+
+// withSimilarityThreshold runs fn in a read only transaction where the
+// trigram threshold is lowered for the <% operator.
+func withSimilarityThreshold(ctx context.Context, pool *pgxpool.Pool, fn func(tx pgx.Tx) error) error {
+	tx, err := pool.BeginTx(ctx, pgx.TxOptions{AccessMode: pgx.ReadOnly})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, "SELECT set_config('pg_trgm.word_similarity_threshold', $1, true)", searchSimilarityThreshold); err != nil {
+		return err
+	}
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
@@ -0,0 +1,90 @@
+package repository
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/malikfajr/eq-store/entity"
+)
+
+func TestCreatedAtKeys(t *testing.T) {
+	createdAt := time.Date(2024, 5, 25, 9, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		order    string
+		cursor   *entity.Cursor
+		wantSort string
+		wantDesc bool
+		wantErr  error
+	}{
+		{name: "default is newest first", order: "", wantSort: "created_at:desc", wantDesc: true},
+		{name: "desc", order: "desc", wantSort: "created_at:desc", wantDesc: true},
+		{name: "asc", order: "asc", wantSort: "created_at:asc", wantDesc: false},
+		{name: "unknown order is newest first", order: "up", wantSort: "created_at:desc", wantDesc: true},
+		{
+			name:     "cursor of the same order",
+			order:    "asc",
+			cursor:   &entity.Cursor{Sort: "created_at:asc", CreatedAt: createdAt, Id: "a"},
+			wantSort: "created_at:asc",
+		},
+		{
+			name:    "cursor of the other order",
+			order:   "asc",
+			cursor:  &entity.Cursor{Sort: "created_at:desc", CreatedAt: createdAt, Id: "a"},
+			wantErr: ErrInvalidCursor,
+		},
+		{
+			name:    "cursor of another sort",
+			order:   "desc",
+			cursor:  &entity.Cursor{Sort: "price:desc", CreatedAt: createdAt, Id: "a"},
+			wantErr: ErrInvalidCursor,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keys, sort, err := createdAtKeys("t.created_at", "t.id", tt.order, tt.cursor)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("err = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("err = %v", err)
+			}
+
+			if sort != tt.wantSort {
+				t.Errorf("sort = %q, want %q", sort, tt.wantSort)
+			}
+
+			if len(keys) != 2 || keys[0].column != "t.created_at" || keys[1].column != "t.id" {
+				t.Fatalf("keys = %+v", keys)
+			}
+
+			for _, key := range keys {
+				if key.desc != tt.wantDesc {
+					t.Errorf("%s desc = %v, want %v", key.column, key.desc, tt.wantDesc)
+				}
+			}
+
+			if tt.cursor != nil && (keys[0].value != tt.cursor.CreatedAt || keys[1].value != tt.cursor.Id) {
+				t.Errorf("values = %v, %v, want the cursor's", keys[0].value, keys[1].value)
+			}
+		})
+	}
+}
+
+func TestOrderClause(t *testing.T) {
+	keys := []sortKey{{column: "created_at", desc: true}, {column: "id", desc: true}}
+
+	if got := orderClause(keys, false); got != " ORDER BY created_at DESC, id DESC" {
+		t.Errorf("forward = %q", got)
+	}
+
+	if got := orderClause(keys, true); got != " ORDER BY created_at ASC, id ASC" {
+		t.Errorf("backward = %q", got)
+	}
+}
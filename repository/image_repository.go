@@ -0,0 +1,58 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/malikfajr/eq-store/entity"
+)
+
+type ImageRepository interface {
+	LockOneTx(ctx context.Context, tx pgx.Tx, productId string) (*entity.ProductImage, error)
+	UpsertTx(ctx context.Context, tx pgx.Tx, image *entity.ProductImage) error
+}
+
+type imageRepository struct{}
+
+func NewImageRepository() ImageRepository {
+	return &imageRepository{}
+}
+
+// LockOneTx returns the image currently uploaded for productId, or nil when
+// the product has none.
+func (i *imageRepository) LockOneTx(ctx context.Context, tx pgx.Tx, productId string) (*entity.ProductImage, error) {
+	query := `
+		SELECT product_id, image_key, thumbnail_key, content_type, size, width, height, uploaded_by, uploaded_at
+		FROM product_images WHERE product_id = $1 FOR UPDATE`
+
+	rows, err := tx.Query(ctx, query, productId)
+	if err != nil {
+		return nil, err
+	}
+
+	image, err := pgx.CollectOneRow(rows, pgx.RowToStructByPos[entity.ProductImage])
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &image, nil
+}
+
+func (i *imageRepository) UpsertTx(ctx context.Context, tx pgx.Tx, image *entity.ProductImage) error {
+	query := `
+		INSERT INTO product_images (product_id, image_key, thumbnail_key, content_type, size, width, height, uploaded_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		ON CONFLICT (product_id) DO UPDATE
+			SET image_key = EXCLUDED.image_key, thumbnail_key = EXCLUDED.thumbnail_key,
+				content_type = EXCLUDED.content_type, size = EXCLUDED.size,
+				width = EXCLUDED.width, height = EXCLUDED.height,
+				uploaded_by = EXCLUDED.uploaded_by, uploaded_at = NOW()
+		RETURNING uploaded_at`
+
+	return tx.QueryRow(ctx, query, image.ProductId, image.ImageKey, image.ThumbnailKey, image.ContentType,
+		image.Size, image.Width, image.Height, image.UploadedBy).Scan(&image.UploadedAt)
+}
@@ -0,0 +1,172 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/malikfajr/eq-store/entity"
+)
+
+type LocationRepository interface {
+	Insert(ctx context.Context, tx pgx.Tx, location *entity.Location) error
+	FindAll(ctx context.Context, pool *pgxpool.Pool, page *entity.PageParams) ([]entity.Location, *entity.PageMeta, error)
+	FindOne(ctx context.Context, pool *pgxpool.Pool, ID string) (*entity.Location, error)
+	FindDefault(ctx context.Context, pool *pgxpool.Pool) (*entity.Location, error)
+	IsExist(ctx context.Context, pool *pgxpool.Pool, ID string) bool
+	HasStock(ctx context.Context, pool *pgxpool.Pool, ID string) bool
+	UpdateTx(ctx context.Context, tx pgx.Tx, location *entity.Location) error
+	ClearDefaultTx(ctx context.Context, tx pgx.Tx) error
+	Delete(ctx context.Context, pool *pgxpool.Pool, ID string) error
+}
+
+type locationRepository struct{}
+
+func NewLocationRepository() LocationRepository {
+	return &locationRepository{}
+}
+
+func (l *locationRepository) Insert(ctx context.Context, tx pgx.Tx, location *entity.Location) error {
+	query := "INSERT INTO locations (name, address, is_default) VALUES ($1, $2, $3) RETURNING id, created_at"
+
+	return tx.QueryRow(ctx, query, location.Name, location.Address, location.IsDefault).Scan(&location.Id, &location.CreatedAt)
+}
+
+// FindAll lists the default location first, then the others by name.
+func (l *locationRepository) FindAll(ctx context.Context, pool *pgxpool.Pool, page *entity.PageParams) ([]entity.Location, *entity.PageMeta, error) {
+	const sort = "is_default:desc,name:asc"
+	keys := []sortKey{
+		{column: "is_default", desc: true},
+		{column: "name"},
+		{column: "id"},
+	}
+
+	if page.Cursor != nil {
+		if page.Cursor.Sort != sort || page.Cursor.Flag == nil || page.Cursor.Name == nil {
+			return nil, nil, ErrInvalidCursor
+		}
+
+		keys[0].value = *page.Cursor.Flag
+		keys[1].value = *page.Cursor.Name
+		keys[2].value = page.Cursor.Id
+	}
+
+	args := pgx.NamedArgs{}
+	rows, err := pool.Query(ctx, pageQuery("SELECT id, name, address, is_default, created_at FROM locations WHERE deleted_at IS NULL", keys, page, args), args)
+	if err != nil {
+		panic(err)
+	}
+
+	locations, err := pgx.CollectRows(rows, pgx.RowToStructByPos[entity.Location])
+	if err != nil {
+		panic(err)
+	}
+
+	locations, meta := paginate(locations, page, func(location entity.Location) entity.Cursor {
+		isDefault, name := location.IsDefault, location.Name
+		return entity.Cursor{Sort: sort, Flag: &isDefault, Name: &name, Id: location.Id}
+	})
+
+	if page.WithTotal {
+		if meta.Total, err = countRows(ctx, pool, "SELECT COUNT(*) FROM locations WHERE deleted_at IS NULL", args); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return locations, meta, nil
+}
+
+func (l *locationRepository) FindOne(ctx context.Context, pool *pgxpool.Pool, ID string) (*entity.Location, error) {
+	query := "SELECT id, name, address, is_default, created_at FROM locations WHERE deleted_at IS NULL AND id = $1 LIMIT 1"
+
+	rows, err := pool.Query(ctx, query, ID)
+	if err != nil {
+		return nil, errors.New("location id not found")
+	}
+
+	location, err := pgx.CollectOneRow(rows, pgx.RowToStructByPos[entity.Location])
+	if err != nil {
+		return nil, errors.New("location id not found")
+	}
+
+	return &location, nil
+}
+
+func (l *locationRepository) FindDefault(ctx context.Context, pool *pgxpool.Pool) (*entity.Location, error) {
+	query := "SELECT id, name, address, is_default, created_at FROM locations WHERE deleted_at IS NULL AND is_default = TRUE LIMIT 1"
+
+	rows, err := pool.Query(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	location, err := pgx.CollectOneRow(rows, pgx.RowToStructByPos[entity.Location])
+	if err != nil {
+		return nil, errors.New("default location is not set")
+	}
+
+	return &location, nil
+}
+
+func (l *locationRepository) IsExist(ctx context.Context, pool *pgxpool.Pool, ID string) bool {
+	var n int
+	query := "SELECT 1 FROM locations WHERE deleted_at IS NULL AND id = $1"
+
+	err := pool.QueryRow(ctx, query, ID).Scan(&n)
+	if err != nil {
+		return false
+	}
+
+	return true
+}
+
+func (l *locationRepository) HasStock(ctx context.Context, pool *pgxpool.Pool, ID string) bool {
+	var n int
+	query := "SELECT 1 FROM product_stocks WHERE location_id = $1 AND stock > 0 LIMIT 1"
+
+	err := pool.QueryRow(ctx, query, ID).Scan(&n)
+	if err != nil {
+		return false
+	}
+
+	return true
+}
+
+func (l *locationRepository) UpdateTx(ctx context.Context, tx pgx.Tx, location *entity.Location) error {
+	query := "UPDATE locations SET name = @name, address = @address, is_default = @isDefault WHERE deleted_at IS NULL AND id = @id"
+
+	args := pgx.NamedArgs{
+		"id":        location.Id,
+		"name":      location.Name,
+		"address":   location.Address,
+		"isDefault": location.IsDefault,
+	}
+
+	_, err := tx.Exec(ctx, query, args)
+
+	return err
+}
+
+func (l *locationRepository) ClearDefaultTx(ctx context.Context, tx pgx.Tx) error {
+	query := "UPDATE locations SET is_default = FALSE WHERE is_default = TRUE"
+
+	_, err := tx.Exec(ctx, query)
+
+	return err
+}
+
+func (l *locationRepository) Delete(ctx context.Context, pool *pgxpool.Pool, ID string) error {
+	query := "UPDATE locations SET deleted_at = NOW() WHERE deleted_at IS NULL AND is_default = FALSE AND id = $1"
+
+	tag, err := pool.Exec(ctx, query, ID)
+	if err != nil {
+		return err
+	}
+
+	if tag.RowsAffected() < 1 {
+		return errors.New("location id not found")
+	}
+
+	return nil
+}
@@ -0,0 +1,275 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/malikfajr/eq-store/entity"
+)
+
+// ErrExpiredStock is returned when a quantity can only be taken from expired lots.
+var ErrExpiredStock = errors.New("stock is expired")
+
+// LotRepository keeps the batches received into a location. Lots only describe
+// part of the stock in product_stocks, they never hold more than it.
+type LotRepository interface {
+	InsertTx(ctx context.Context, tx pgx.Tx, lot *entity.Lot) error
+	FindByProduct(ctx context.Context, pool *pgxpool.Pool, productId string) []entity.Lot
+	FindExpiring(ctx context.Context, pool *pgxpool.Pool, params *entity.ExpiringLotQueryParams) ([]entity.Lot, *entity.PageMeta, error)
+	ExpiredAt(ctx context.Context, pool *pgxpool.Pool, locationId string, productIds []string) map[string]entity.Quantity
+	ConsumeTx(ctx context.Context, tx pgx.Tx, productId string, locationId string, quantity entity.Quantity) ([]entity.LotAllocation, error)
+	RestoreTx(ctx context.Context, tx pgx.Tx, allocations []entity.LotAllocation) error
+	MoveTx(ctx context.Context, tx pgx.Tx, allocations []entity.LotAllocation, locationId string) error
+	InsertTransactionLotsTx(ctx context.Context, tx pgx.Tx, transactionId string, allocations []entity.LotAllocation) error
+	FindTransactionLotsTx(ctx context.Context, tx pgx.Tx, transactionId string) ([]entity.LotAllocation, error)
+	InsertTransferLotsTx(ctx context.Context, tx pgx.Tx, transferId string, allocations []entity.LotAllocation) error
+	FindTransferLotsTx(ctx context.Context, tx pgx.Tx, transferId string) ([]entity.LotAllocation, error)
+}
+
+type lotRepository struct{}
+
+func NewLotRepository() LotRepository {
+	return &lotRepository{}
+}
+
+// a lot is sellable up to and including its expiry date
+const lotColumns = `
+	l.id, l.product_id, p.name, l.location_id, l.lot_number, TO_CHAR(l.expires_at, 'YYYY-MM-DD'), l.quantity,
+	COALESCE(l.expires_at < CURRENT_DATE, FALSE), l.receipt_id, l.created_at`
+
+func (l *lotRepository) InsertTx(ctx context.Context, tx pgx.Tx, lot *entity.Lot) error {
+	query := `
+		INSERT INTO product_lots (product_id, location_id, lot_number, expires_at, quantity, receipt_id)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at`
+
+	return tx.QueryRow(ctx, query, lot.ProductId, lot.LocationId, lot.LotNumber, lot.ExpiresAt, lot.Quantity, lot.ReceiptId).
+		Scan(&lot.Id, &lot.CreatedAt)
+}
+
+func (l *lotRepository) FindByProduct(ctx context.Context, pool *pgxpool.Pool, productId string) []entity.Lot {
+	query := `
+		SELECT ` + lotColumns + `
+		FROM product_lots l JOIN products p ON p.id = l.product_id
+		WHERE l.product_id = $1 AND l.quantity > 0
+		ORDER BY l.expires_at ASC NULLS LAST, l.created_at ASC`
+
+	return l.collect(pool.Query(ctx, query, productId))
+}
+
+// FindExpiring lists the lots in stock that expire within params.Days, lots
+// that are already expired included, soonest expiry first.
+func (l *lotRepository) FindExpiring(ctx context.Context, pool *pgxpool.Pool, params *entity.ExpiringLotQueryParams) ([]entity.Lot, *entity.PageMeta, error) {
+	from := `
+		FROM product_lots l JOIN products p ON p.id = l.product_id AND p.deleted_at IS NULL
+		WHERE l.quantity > 0 AND l.expires_at <= CURRENT_DATE + @days::INT`
+	args := pgx.NamedArgs{"days": params.Days}
+
+	if params.LocationId != "" {
+		from += " AND l.location_id = @locationId"
+		args["locationId"] = params.LocationId
+	}
+
+	// the cursor carries the expiry date in CreatedAt
+	const sort = "expires_at:asc"
+	keys := []sortKey{
+		{column: "l.expires_at"},
+		{column: "p.name"},
+		{column: "l.id"},
+	}
+
+	if params.Cursor != nil {
+		if params.Cursor.Sort != sort || params.Cursor.Name == nil {
+			return nil, nil, ErrInvalidCursor
+		}
+
+		keys[0].value = params.Cursor.CreatedAt
+		keys[1].value = *params.Cursor.Name
+		keys[2].value = params.Cursor.Id
+	}
+
+	lots := l.collect(pool.Query(ctx, pageQuery("SELECT "+lotColumns+from, keys, &params.PageParams, args), args))
+
+	lots, meta := paginate(lots, &params.PageParams, func(lot entity.Lot) entity.Cursor {
+		expiresAt, _ := time.Parse("2006-01-02", *lot.ExpiresAt)
+		name := lot.ProductName
+		return entity.Cursor{Sort: sort, CreatedAt: expiresAt, Name: &name, Id: lot.Id}
+	})
+
+	if params.WithTotal {
+		var err error
+		if meta.Total, err = countRows(ctx, pool, "SELECT COUNT(*)"+from, args); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return lots, meta, nil
+}
+
+func (l *lotRepository) collect(rows pgx.Rows, err error) []entity.Lot {
+	if err != nil {
+		panic(err)
+	}
+
+	lots, err := pgx.CollectRows(rows, pgx.RowToStructByPos[entity.Lot])
+	if err != nil {
+		panic(err)
+	}
+
+	return lots
+}
+
+// ExpiredAt returns the expired quantity of each product at a location.
+func (l *lotRepository) ExpiredAt(ctx context.Context, pool *pgxpool.Pool, locationId string, productIds []string) map[string]entity.Quantity {
+	query := `
+		SELECT product_id, SUM(quantity) FROM product_lots
+		WHERE location_id = $1 AND product_id::TEXT = ANY($2) AND quantity > 0 AND expires_at < CURRENT_DATE
+		GROUP BY product_id`
+
+	rows, err := pool.Query(ctx, query, locationId, productIds)
+	if err != nil {
+		panic(err)
+	}
+	defer rows.Close()
+
+	result := map[string]entity.Quantity{}
+	for rows.Next() {
+		var productId string
+		var quantity entity.Quantity
+		if err := rows.Scan(&productId, &quantity); err != nil {
+			panic(err)
+		}
+		result[productId] = quantity
+	}
+
+	return result
+}
+
+// ConsumeTx takes quantity out of the unexpired lots, the first to expire
+// first. It must run after the stock was decremented: when what is left of
+// the stock can not hold the remaining lots, expired stock would have been
+// taken and ErrExpiredStock is returned.
+func (l *lotRepository) ConsumeTx(ctx context.Context, tx pgx.Tx, productId string, locationId string, quantity entity.Quantity) ([]entity.LotAllocation, error) {
+	query := `
+		SELECT id, quantity FROM product_lots
+		WHERE product_id = $1 AND location_id = $2 AND quantity > 0
+			AND (expires_at IS NULL OR expires_at >= CURRENT_DATE)
+		ORDER BY expires_at ASC NULLS LAST, created_at ASC
+		FOR UPDATE`
+
+	rows, err := tx.Query(ctx, query, productId, locationId)
+	if err != nil {
+		return nil, err
+	}
+
+	lots, err := pgx.CollectRows(rows, pgx.RowToStructByPos[entity.LotAllocation])
+	if err != nil {
+		return nil, err
+	}
+
+	allocations := []entity.LotAllocation{}
+	for _, lot := range lots {
+		if quantity == 0 {
+			break
+		}
+
+		taken := min(lot.Quantity, quantity)
+		if _, err := tx.Exec(ctx, "UPDATE product_lots SET quantity = quantity - $1 WHERE id = $2", taken, lot.LotId); err != nil {
+			return nil, err
+		}
+
+		allocations = append(allocations, entity.LotAllocation{LotId: lot.LotId, Quantity: taken})
+		quantity -= taken
+	}
+
+	var exceeds bool
+	check := `
+		SELECT COALESCE(SUM(quantity), 0) > COALESCE((SELECT stock FROM product_stocks WHERE product_id = $1 AND location_id = $2), 0)
+		FROM product_lots WHERE product_id = $1 AND location_id = $2`
+
+	if err := tx.QueryRow(ctx, check, productId, locationId).Scan(&exceeds); err != nil {
+		return nil, err
+	}
+
+	if exceeds {
+		return nil, ErrExpiredStock
+	}
+
+	return allocations, nil
+}
+
+// RestoreTx puts allocated quantities back into their lots.
+func (l *lotRepository) RestoreTx(ctx context.Context, tx pgx.Tx, allocations []entity.LotAllocation) error {
+	for _, allocation := range allocations {
+		if _, err := tx.Exec(ctx, "UPDATE product_lots SET quantity = quantity + $1 WHERE id = $2", allocation.Quantity, allocation.LotId); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// MoveTx creates a copy of every allocated lot at locationId holding the
+// allocated quantity.
+func (l *lotRepository) MoveTx(ctx context.Context, tx pgx.Tx, allocations []entity.LotAllocation, locationId string) error {
+	query := `
+		INSERT INTO product_lots (product_id, location_id, lot_number, expires_at, quantity, receipt_id)
+		SELECT product_id, $2, lot_number, expires_at, $3, receipt_id FROM product_lots WHERE id = $1`
+
+	for _, allocation := range allocations {
+		if _, err := tx.Exec(ctx, query, allocation.LotId, locationId, allocation.Quantity); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (l *lotRepository) InsertTransactionLotsTx(ctx context.Context, tx pgx.Tx, transactionId string, allocations []entity.LotAllocation) error {
+	query := `
+		INSERT INTO transaction_lots (transaction_id, lot_id, quantity) VALUES ($1, $2, $3)
+		ON CONFLICT (transaction_id, lot_id) DO UPDATE SET quantity = transaction_lots.quantity + EXCLUDED.quantity`
+
+	for _, allocation := range allocations {
+		if _, err := tx.Exec(ctx, query, transactionId, allocation.LotId, allocation.Quantity); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (l *lotRepository) FindTransactionLotsTx(ctx context.Context, tx pgx.Tx, transactionId string) ([]entity.LotAllocation, error) {
+	rows, err := tx.Query(ctx, "SELECT lot_id, quantity FROM transaction_lots WHERE transaction_id = $1", transactionId)
+	if err != nil {
+		return nil, err
+	}
+
+	return pgx.CollectRows(rows, pgx.RowToStructByPos[entity.LotAllocation])
+}
+
+func (l *lotRepository) InsertTransferLotsTx(ctx context.Context, tx pgx.Tx, transferId string, allocations []entity.LotAllocation) error {
+	query := `
+		INSERT INTO stock_transfer_lots (transfer_id, lot_id, quantity) VALUES ($1, $2, $3)
+		ON CONFLICT (transfer_id, lot_id) DO UPDATE SET quantity = stock_transfer_lots.quantity + EXCLUDED.quantity`
+
+	for _, allocation := range allocations {
+		if _, err := tx.Exec(ctx, query, transferId, allocation.LotId, allocation.Quantity); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (l *lotRepository) FindTransferLotsTx(ctx context.Context, tx pgx.Tx, transferId string) ([]entity.LotAllocation, error) {
+	rows, err := tx.Query(ctx, "SELECT lot_id, quantity FROM stock_transfer_lots WHERE transfer_id = $1", transferId)
+	if err != nil {
+		return nil, err
+	}
+
+	return pgx.CollectRows(rows, pgx.RowToStructByPos[entity.LotAllocation])
+}
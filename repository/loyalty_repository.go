@@ -0,0 +1,203 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/malikfajr/eq-store/entity"
+)
+
+// LoyaltyRepository keeps the loyalty settings and the points ledger. The
+// ledger is append only, the table refuses updates and deletes.
+type LoyaltyRepository interface {
+	FindSettings(ctx context.Context, pool *pgxpool.Pool) *entity.LoyaltySettings
+	FindSettingsTx(ctx context.Context, tx pgx.Tx) (*entity.LoyaltySettings, error)
+	UpdateSettingsTx(ctx context.Context, tx pgx.Tx, settings *entity.LoyaltySettings) error
+	LockCustomerTx(ctx context.Context, tx pgx.Tx, customerId string) error
+	FindEntries(ctx context.Context, pool *pgxpool.Pool, customerId string) []entity.PointEntry
+	FindEntriesTx(ctx context.Context, tx pgx.Tx, customerId string) ([]entity.PointEntry, error)
+	FindPage(ctx context.Context, pool *pgxpool.Pool, customerId string, params *entity.PointQueryParams) ([]entity.PointEntry, *entity.PageMeta, error)
+	FindByTransactionTx(ctx context.Context, tx pgx.Tx, transactionId string) ([]entity.PointEntry, error)
+	InsertTx(ctx context.Context, tx pgx.Tx, entry *entity.PointEntry) error
+	FindExpiredCustomers(ctx context.Context, pool *pgxpool.Pool, limit int) []string
+}
+
+type loyaltyRepository struct{}
+
+func NewLoyaltyRepository() LoyaltyRepository {
+	return &loyaltyRepository{}
+}
+
+const pointEntryColumns = "id, customer_id, transaction_id, type, points, source_id, expires_at, created_at"
+
+func (l *loyaltyRepository) FindSettings(ctx context.Context, pool *pgxpool.Pool) *entity.LoyaltySettings {
+	settings, err := l.findSettings(ctx, pool, "")
+	if err != nil {
+		panic(err)
+	}
+
+	return settings
+}
+
+// FindSettingsTx reads the settings and keeps them from changing until tx
+// ends, so a checkout prices and earns points with one set of settings.
+func (l *loyaltyRepository) FindSettingsTx(ctx context.Context, tx pgx.Tx) (*entity.LoyaltySettings, error) {
+	return l.findSettings(ctx, tx, " FOR SHARE")
+}
+
+func (l *loyaltyRepository) findSettings(ctx context.Context, db querier, lock string) (*entity.LoyaltySettings, error) {
+	settings := &entity.LoyaltySettings{}
+	query := "SELECT spend_per_point, point_value, expiry_days, updated_at FROM loyalty_settings" + lock
+
+	err := db.QueryRow(ctx, query).Scan(&settings.SpendPerPoint, &settings.PointValue, &settings.ExpiryDays, &settings.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := db.Query(ctx, "SELECT category, spend_per_point FROM loyalty_category_rates ORDER BY category ASC")
+	if err != nil {
+		return nil, err
+	}
+
+	settings.CategoryRates, err = pgx.CollectRows(rows, pgx.RowToStructByPos[entity.LoyaltyCategoryRate])
+	if err != nil {
+		return nil, err
+	}
+
+	return settings, nil
+}
+
+// UpdateSettingsTx replaces the settings, category rates left out are removed.
+func (l *loyaltyRepository) UpdateSettingsTx(ctx context.Context, tx pgx.Tx, settings *entity.LoyaltySettings) error {
+	query := `
+		UPDATE loyalty_settings SET spend_per_point = $1, point_value = $2, expiry_days = $3, updated_at = NOW()
+		RETURNING updated_at`
+
+	if err := tx.QueryRow(ctx, query, settings.SpendPerPoint, settings.PointValue, settings.ExpiryDays).Scan(&settings.UpdatedAt); err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(ctx, "DELETE FROM loyalty_category_rates"); err != nil {
+		return err
+	}
+
+	for _, rate := range settings.CategoryRates {
+		if _, err := tx.Exec(ctx, "INSERT INTO loyalty_category_rates (category, spend_per_point) VALUES ($1, $2)", rate.Category, rate.SpendPerPoint); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// LockCustomerTx locks a customer so their ledger is written by one
+// transaction at a time.
+func (l *loyaltyRepository) LockCustomerTx(ctx context.Context, tx pgx.Tx, customerId string) error {
+	var id string
+
+	return tx.QueryRow(ctx, "SELECT id FROM customers WHERE id = $1 FOR UPDATE", customerId).Scan(&id)
+}
+
+// FindEntries returns the whole ledger of a customer, oldest first.
+func (l *loyaltyRepository) FindEntries(ctx context.Context, pool *pgxpool.Pool, customerId string) []entity.PointEntry {
+	query := "SELECT " + pointEntryColumns + " FROM loyalty_points WHERE customer_id = $1 ORDER BY created_at ASC, id ASC"
+
+	rows, err := pool.Query(ctx, query, customerId)
+	if err != nil {
+		panic(err)
+	}
+
+	entries, err := pgx.CollectRows(rows, pgx.RowToStructByPos[entity.PointEntry])
+	if err != nil {
+		panic(err)
+	}
+
+	return entries
+}
+
+func (l *loyaltyRepository) FindEntriesTx(ctx context.Context, tx pgx.Tx, customerId string) ([]entity.PointEntry, error) {
+	query := "SELECT " + pointEntryColumns + " FROM loyalty_points WHERE customer_id = $1 ORDER BY created_at ASC, id ASC"
+
+	rows, err := tx.Query(ctx, query, customerId)
+	if err != nil {
+		return nil, err
+	}
+
+	return pgx.CollectRows(rows, pgx.RowToStructByPos[entity.PointEntry])
+}
+
+func (l *loyaltyRepository) FindPage(ctx context.Context, pool *pgxpool.Pool, customerId string, params *entity.PointQueryParams) ([]entity.PointEntry, *entity.PageMeta, error) {
+	query := "SELECT " + pointEntryColumns + " FROM loyalty_points WHERE customer_id = @customerId"
+	args := pgx.NamedArgs{"customerId": customerId}
+
+	keys, sort, err := createdAtKeys("created_at", "id", params.CreatedAt, params.Cursor)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	rows, err := pool.Query(ctx, pageQuery(query, keys, &params.PageParams, args), args)
+	if err != nil {
+		panic(err)
+	}
+
+	entries, err := pgx.CollectRows(rows, pgx.RowToStructByPos[entity.PointEntry])
+	if err != nil {
+		panic(err)
+	}
+
+	entries, meta := paginate(entries, &params.PageParams, func(entry entity.PointEntry) entity.Cursor {
+		return entity.Cursor{Sort: sort, CreatedAt: entry.CreatedAt, Id: entry.Id}
+	})
+
+	if params.WithTotal {
+		if meta.Total, err = countRows(ctx, pool, "SELECT COUNT(*) FROM loyalty_points WHERE customer_id = @customerId", args); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return entries, meta, nil
+}
+
+func (l *loyaltyRepository) FindByTransactionTx(ctx context.Context, tx pgx.Tx, transactionId string) ([]entity.PointEntry, error) {
+	query := "SELECT " + pointEntryColumns + " FROM loyalty_points WHERE transaction_id = $1 ORDER BY created_at ASC, id ASC"
+
+	rows, err := tx.Query(ctx, query, transactionId)
+	if err != nil {
+		return nil, err
+	}
+
+	return pgx.CollectRows(rows, pgx.RowToStructByPos[entity.PointEntry])
+}
+
+func (l *loyaltyRepository) InsertTx(ctx context.Context, tx pgx.Tx, entry *entity.PointEntry) error {
+	query := `
+		INSERT INTO loyalty_points (customer_id, transaction_id, type, points, source_id, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at`
+
+	return tx.QueryRow(ctx, query, entry.CustomerId, entry.TransactionId, entry.Type, entry.Points, entry.SourceId, entry.ExpiresAt).
+		Scan(&entry.Id, &entry.CreatedAt)
+}
+
+// FindExpiredCustomers returns customers holding a lot that expired and was
+// not closed by an expire entry yet.
+func (l *loyaltyRepository) FindExpiredCustomers(ctx context.Context, pool *pgxpool.Pool, limit int) []string {
+	query := `
+		SELECT DISTINCT l.customer_id FROM loyalty_points l
+		WHERE l.points > 0 AND l.expires_at <= NOW()
+			AND NOT EXISTS (SELECT 1 FROM loyalty_points e WHERE e.source_id = l.id AND e.type = 'expire')
+		LIMIT $1`
+
+	rows, err := pool.Query(ctx, query, limit)
+	if err != nil {
+		panic(err)
+	}
+
+	customers, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		panic(err)
+	}
+
+	return customers
+}
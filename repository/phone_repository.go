@@ -0,0 +1,94 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/malikfajr/eq-store/entity"
+)
+
+// The tables phone numbers are stored in, the only values table may take below.
+const (
+	PhoneTableStaffs    = "staffs"
+	PhoneTableCustomers = "customers"
+)
+
+type PhoneRepository interface {
+	FindUnnormalizedTx(ctx context.Context, tx pgx.Tx, table string) ([]entity.PhoneNumberRow, error)
+	FindHoldersTx(ctx context.Context, tx pgx.Tx, table string, phoneNumbers []string) (map[string]string, error)
+	UpdateTx(ctx context.Context, tx pgx.Tx, table string, ID string, phoneNumber string) error
+	InsertConflictTx(ctx context.Context, tx pgx.Tx, conflict *entity.PhoneNumberConflict) error
+}
+
+type phoneRepository struct{}
+
+func NewPhoneRepository() PhoneRepository {
+	return &phoneRepository{}
+}
+
+// FindUnnormalizedTx returns the rows of table whose number does not have the
+// E.164 form, oldest first. Rows listed in phone_number_conflicts are left out,
+// they keep their number.
+func (p *phoneRepository) FindUnnormalizedTx(ctx context.Context, tx pgx.Tx, table string) ([]entity.PhoneNumberRow, error) {
+	active := "TRUE"
+	if table == PhoneTableCustomers {
+		active = "t.deleted_at IS NULL"
+	}
+
+	query := `
+		SELECT t.id, t.phone_number, COALESCE(t.created_at, NOW()), ` + active + `
+		FROM ` + table + ` t
+		WHERE t.phone_number !~ '^\+[1-9][0-9]{6,14}$'
+			AND NOT EXISTS (SELECT 1 FROM phone_number_conflicts pc WHERE pc.table_name = $1 AND pc.row_id = t.id)
+		ORDER BY t.created_at ASC, t.id ASC
+		FOR UPDATE`
+
+	rows, err := tx.Query(ctx, query, table)
+	if err != nil {
+		return nil, err
+	}
+
+	return pgx.CollectRows(rows, pgx.RowToStructByPos[entity.PhoneNumberRow])
+}
+
+// FindHoldersTx returns the id of the row of table already holding each of
+// phoneNumbers, deleted customers do not hold their number.
+func (p *phoneRepository) FindHoldersTx(ctx context.Context, tx pgx.Tx, table string, phoneNumbers []string) (map[string]string, error) {
+	query := "SELECT phone_number, id FROM " + table + " WHERE phone_number = ANY($1)"
+	if table == PhoneTableCustomers {
+		query += " AND deleted_at IS NULL"
+	}
+
+	rows, err := tx.Query(ctx, query, phoneNumbers)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	holders := map[string]string{}
+	for rows.Next() {
+		var phoneNumber, id string
+		if err := rows.Scan(&phoneNumber, &id); err != nil {
+			return nil, err
+		}
+		holders[phoneNumber] = id
+	}
+
+	return holders, rows.Err()
+}
+
+func (p *phoneRepository) UpdateTx(ctx context.Context, tx pgx.Tx, table string, ID string, phoneNumber string) error {
+	_, err := tx.Exec(ctx, "UPDATE "+table+" SET phone_number = $2 WHERE id = $1", ID, phoneNumber)
+
+	return err
+}
+
+func (p *phoneRepository) InsertConflictTx(ctx context.Context, tx pgx.Tx, conflict *entity.PhoneNumberConflict) error {
+	query := `
+		INSERT INTO phone_number_conflicts (table_name, row_id, phone_number, normalized, duplicate_of)
+		VALUES ($1, $2, $3, $4, $5)`
+
+	_, err := tx.Exec(ctx, query, conflict.TableName, conflict.RowId, conflict.PhoneNumber, conflict.Normalized, conflict.DuplicateOf)
+
+	return err
+}
@@ -0,0 +1,203 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/malikfajr/eq-store/entity"
+)
+
+type PriceRepository interface {
+	InsertHistoryTx(ctx context.Context, tx pgx.Tx, history *entity.PriceHistory) error
+	InsertVariantsHistoryTx(ctx context.Context, tx pgx.Tx, parentId string, price int, changedBy *string, scheduleId *string) error
+	FindHistory(ctx context.Context, pool *pgxpool.Pool, productId string, params *entity.PriceHistoryQueryParams) ([]entity.PriceHistory, *entity.PageMeta, error)
+	CreateSchedule(ctx context.Context, pool *pgxpool.Pool, schedule *entity.PriceSchedule) error
+	FindSchedules(ctx context.Context, pool *pgxpool.Pool, productId string) []entity.PriceSchedule
+	FindSchedule(ctx context.Context, pool *pgxpool.Pool, ID string) (*entity.PriceSchedule, error)
+	IsOverlapping(ctx context.Context, pool *pgxpool.Pool, productId string, startsAt time.Time, endsAt *time.Time) bool
+	CancelSchedule(ctx context.Context, pool *pgxpool.Pool, ID string) error
+	LockDueTx(ctx context.Context, tx pgx.Tx, limit int) ([]entity.PriceSchedule, error)
+	UpdateScheduleTx(ctx context.Context, tx pgx.Tx, schedule *entity.PriceSchedule) error
+}
+
+type priceRepository struct{}
+
+func NewPriceRepository() PriceRepository {
+	return &priceRepository{}
+}
+
+const priceScheduleColumns = "id, product_id, price, previous_price, starts_at, ends_at, status, created_by, created_at, applied_at, reverted_at"
+
+func (p *priceRepository) InsertHistoryTx(ctx context.Context, tx pgx.Tx, history *entity.PriceHistory) error {
+	query := `
+		INSERT INTO product_price_history (product_id, old_price, new_price, changed_by, schedule_id)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, changed_at`
+
+	return tx.QueryRow(ctx, query, history.ProductId, history.OldPrice, history.NewPrice, history.ChangedBy, history.ScheduleId).
+		Scan(&history.Id, &history.ChangedAt)
+}
+
+// InsertVariantsHistoryTx records the price change of the variants following
+// the parent price, it must run before the variants are updated.
+func (p *priceRepository) InsertVariantsHistoryTx(ctx context.Context, tx pgx.Tx, parentId string, price int, changedBy *string, scheduleId *string) error {
+	query := `
+		INSERT INTO product_price_history (product_id, old_price, new_price, changed_by, schedule_id)
+		SELECT id, price, $2, $3, $4 FROM products
+		WHERE parent_id = $1 AND inherit_price AND price <> $2 AND deleted_at IS NULL`
+
+	_, err := tx.Exec(ctx, query, parentId, price, changedBy, scheduleId)
+
+	return err
+}
+
+func (p *priceRepository) FindHistory(ctx context.Context, pool *pgxpool.Pool, productId string, params *entity.PriceHistoryQueryParams) ([]entity.PriceHistory, *entity.PageMeta, error) {
+	where := " AND product_id = @productId"
+	args := pgx.NamedArgs{"productId": productId}
+
+	if params.From != nil {
+		where += " AND changed_at >= @from"
+		args["from"] = *params.From
+	}
+
+	if params.To != nil {
+		where += " AND changed_at <= @to"
+		args["to"] = *params.To
+	}
+
+	keys, sort, err := createdAtKeys("changed_at", "id", "desc", params.Cursor)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	query := pageQuery("SELECT id, product_id, old_price, new_price, changed_by, schedule_id, changed_at FROM product_price_history WHERE 1=1"+where, keys, &params.PageParams, args)
+
+	rows, err := pool.Query(ctx, query, args)
+	if err != nil {
+		panic(err)
+	}
+
+	history, err := pgx.CollectRows(rows, pgx.RowToStructByPos[entity.PriceHistory])
+	if err != nil {
+		panic(err)
+	}
+
+	history, meta := paginate(history, &params.PageParams, func(h entity.PriceHistory) entity.Cursor {
+		return entity.Cursor{Sort: sort, CreatedAt: *h.ChangedAt, Id: h.Id}
+	})
+
+	if params.WithTotal {
+		if meta.Total, err = countRows(ctx, pool, "SELECT COUNT(*) FROM product_price_history WHERE 1=1"+where, args); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return history, meta, nil
+}
+
+func (p *priceRepository) CreateSchedule(ctx context.Context, pool *pgxpool.Pool, schedule *entity.PriceSchedule) error {
+	query := `
+		INSERT INTO price_schedules (product_id, price, starts_at, ends_at, created_by)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, status, created_at`
+
+	return pool.QueryRow(ctx, query, schedule.ProductId, schedule.Price, schedule.StartsAt, schedule.EndsAt, schedule.CreatedBy).
+		Scan(&schedule.Id, &schedule.Status, &schedule.CreatedAt)
+}
+
+func (p *priceRepository) FindSchedules(ctx context.Context, pool *pgxpool.Pool, productId string) []entity.PriceSchedule {
+	query := "SELECT " + priceScheduleColumns + " FROM price_schedules WHERE product_id = $1 ORDER BY starts_at DESC"
+
+	rows, err := pool.Query(ctx, query, productId)
+	if err != nil {
+		panic(err)
+	}
+
+	schedules, err := pgx.CollectRows(rows, pgx.RowToStructByPos[entity.PriceSchedule])
+	if err != nil {
+		panic(err)
+	}
+
+	return schedules
+}
+
+func (p *priceRepository) FindSchedule(ctx context.Context, pool *pgxpool.Pool, ID string) (*entity.PriceSchedule, error) {
+	query := "SELECT " + priceScheduleColumns + " FROM price_schedules WHERE id = $1"
+
+	rows, err := pool.Query(ctx, query, ID)
+	if err != nil {
+		return nil, errors.New("schedule id not found")
+	}
+
+	schedule, err := pgx.CollectOneRow(rows, pgx.RowToStructByPos[entity.PriceSchedule])
+	if err != nil {
+		return nil, errors.New("schedule id not found")
+	}
+
+	return &schedule, nil
+}
+
+// IsOverlapping reports whether a pending or active schedule of the product
+// runs at the same time. A schedule without an end only occupies its start.
+func (p *priceRepository) IsOverlapping(ctx context.Context, pool *pgxpool.Pool, productId string, startsAt time.Time, endsAt *time.Time) bool {
+	var n int
+	query := `
+		SELECT 1 FROM price_schedules
+		WHERE product_id = $1 AND status IN ('pending', 'active')
+			AND tsrange(starts_at, COALESCE(ends_at, starts_at), '[]') && tsrange($2, COALESCE($3, $2), '[]')
+		LIMIT 1`
+
+	err := pool.QueryRow(ctx, query, productId, startsAt, endsAt).Scan(&n)
+	if err != nil {
+		return false
+	}
+
+	return true
+}
+
+func (p *priceRepository) CancelSchedule(ctx context.Context, pool *pgxpool.Pool, ID string) error {
+	query := "UPDATE price_schedules SET status = 'cancelled' WHERE id = $1 AND status = 'pending'"
+
+	tag, err := pool.Exec(ctx, query, ID)
+	if err != nil {
+		return err
+	}
+
+	if tag.RowsAffected() < 1 {
+		return errors.New("schedule is not pending")
+	}
+
+	return nil
+}
+
+// LockDueTx locks schedules that have to be applied or reverted now. Locked
+// rows are skipped so several instances can run the scheduler.
+func (p *priceRepository) LockDueTx(ctx context.Context, tx pgx.Tx, limit int) ([]entity.PriceSchedule, error) {
+	query := `
+		SELECT ` + priceScheduleColumns + ` FROM price_schedules
+		WHERE (status = 'pending' AND starts_at <= NOW()) OR (status = 'active' AND ends_at <= NOW())
+		ORDER BY CASE WHEN status = 'pending' THEN starts_at ELSE ends_at END ASC
+		LIMIT $1
+		FOR UPDATE SKIP LOCKED`
+
+	rows, err := tx.Query(ctx, query, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	return pgx.CollectRows(rows, pgx.RowToStructByPos[entity.PriceSchedule])
+}
+
+func (p *priceRepository) UpdateScheduleTx(ctx context.Context, tx pgx.Tx, schedule *entity.PriceSchedule) error {
+	query := `
+		UPDATE price_schedules
+			SET status = $2, previous_price = $3, applied_at = $4, reverted_at = $5
+		WHERE id = $1`
+
+	_, err := tx.Exec(ctx, query, schedule.Id, schedule.Status, schedule.PreviousPrice, schedule.AppliedAt, schedule.RevertedAt)
+
+	return err
+}
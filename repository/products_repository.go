@@ -5,6 +5,7 @@ import (
 	"errors"
 	"log"
 	"strings"
+	"time"
 
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
@@ -13,14 +14,29 @@ import (
 )
 
 type ProductRepository interface {
-	Insert(ctx context.Context, pool *pgxpool.Pool, product *entity.Product) (*entity.Product, error)
+	InsertTx(ctx context.Context, tx pgx.Tx, product *entity.Product) (*entity.Product, error)
 	IsExists(ctx context.Context, pool *pgxpool.Pool, productId string) bool
-	FindMany(ctx context.Context, pool *pgxpool.Pool, params *entity.ProductQueryParams) (*[]entity.Product, error)
-	FindSku(ctx context.Context, pool *pgxpool.Pool, params *entity.ProductQueryParams) (*[]entity.ProductSKU, error)
+	FindMany(ctx context.Context, pool *pgxpool.Pool, params *entity.ProductQueryParams) (*[]entity.Product, *entity.PageMeta, error)
+	FindSku(ctx context.Context, pool *pgxpool.Pool, params *entity.ProductQueryParams) (*[]entity.ProductSKU, *entity.PageMeta, error)
 	FindOne(ctx context.Context, pool *pgxpool.Pool, ID string) (*entity.Product, error)
 	FindByIds(ctx context.Context, pool *pgxpool.Pool, productIds []string) *[]entity.Product
+	FindVariants(ctx context.Context, pool *pgxpool.Pool, parentIds []string) map[string][]entity.Product
+	UpdateVariantsTx(ctx context.Context, tx pgx.Tx, parent *entity.Product) error
 	UpdateTx(ctx context.Context, tx pgx.Tx, product *entity.Product) error
+	BumpVersionTx(ctx context.Context, tx pgx.Tx, ID string, version int) (int, error)
+	SetImageTx(ctx context.Context, tx pgx.Tx, ID string, imageUrl string, thumbnailUrl string, version int) (int, error)
+	LockPriceTx(ctx context.Context, tx pgx.Tx, ID string) (int, error)
+	UpdateAverageCostTx(ctx context.Context, tx pgx.Tx, ID string, quantity entity.Quantity, unitCost int) error
+	UpdatePriceTx(ctx context.Context, tx pgx.Tx, ID string, price int) error
 	Delete(ctx context.Context, pool *pgxpool.Pool, ID string) error
+	FindDeleted(ctx context.Context, pool *pgxpool.Pool, page *entity.PageParams) ([]entity.DeletedProduct, *entity.PageMeta, error)
+	FindDeletedOne(ctx context.Context, pool *pgxpool.Pool, ID string) (*entity.DeletedProduct, error)
+	Restore(ctx context.Context, pool *pgxpool.Pool, ID string) error
+	IsSold(ctx context.Context, pool *pgxpool.Pool, ID string) bool
+	IsTransferred(ctx context.Context, pool *pgxpool.Pool, ID string) bool
+	PurgeTx(ctx context.Context, tx pgx.Tx, ID string) error
+	Search(ctx context.Context, pool *pgxpool.Pool, params *entity.ProductSearchParams) ([]entity.ProductSearchResult, *entity.PageMeta, error)
+	Suggest(ctx context.Context, pool *pgxpool.Pool, q string, limit int) ([]entity.ProductSuggestion, error)
 }
 
 type productRepository struct{}
@@ -29,12 +45,14 @@ func NewProductRepository() ProductRepository {
 	return &productRepository{}
 }
 
-func (p *productRepository) Insert(ctx context.Context, pool *pgxpool.Pool, product *entity.Product) (*entity.Product, error) {
+// InsertTx creates the product row only, stock levels are written through StockRepository.
+func (p *productRepository) InsertTx(ctx context.Context, tx pgx.Tx, product *entity.Product) (*entity.Product, error) {
 	query := `
-		INSERT INTO products (name, sku, category, image_url, notes, price, stock, location, is_available)
-		VALUES (@name, @sku, @category, @imageUrl, @notes, @price, @stock, @location, @isAvailable)
-		RETURNING id, created_at
+		INSERT INTO products (name, sku, category, image_url, notes, price, stock, location, is_available, parent_id, variant_attributes, variant_options, inherit_price, tags, attributes, cost_price, is_bundle, track_serials, unit, quantity_precision, purchase_unit, purchase_unit_factor)
+		VALUES (@name, @sku, @category, @imageUrl, @notes, @price, 0, @location, @isAvailable, @parentId, @variantAttributes, @variantOptions, @inheritPrice, @tags, @attributes, @costPrice, @isBundle, @trackSerials, @unit, @quantityPrecision, @purchaseUnit, @purchaseUnitFactor)
+		RETURNING id, created_at, version
 	`
+	p.normalizeJSON(product)
 
 	args := pgx.NamedArgs{
 		"name":        product.Name,
@@ -43,75 +61,61 @@ func (p *productRepository) Insert(ctx context.Context, pool *pgxpool.Pool, prod
 		"imageUrl":    product.ImageUrl,
 		"notes":       product.Notes,
 		"price":       product.Price,
-		"stock":       product.Price,
 		"location":    product.Location,
 		"isAvailable": product.IsAvailable,
+
+		"parentId":          product.ParentId,
+		"variantAttributes": product.VariantAttributes,
+		"variantOptions":    product.VariantOptions,
+		"inheritPrice":      product.InheritPrice,
+		"tags":              product.Tags,
+		"attributes":        product.Attributes,
+		"costPrice":         product.CostPrice,
+		"isBundle":          product.IsBundle,
+		"trackSerials":      product.TrackSerials,
+
+		"unit":               product.Unit,
+		"quantityPrecision":  product.QuantityPrecision,
+		"purchaseUnit":       product.PurchaseUnit,
+		"purchaseUnitFactor": product.PurchaseUnitFactor,
 	}
 
-	err := pool.QueryRow(ctx, query, args).Scan(&product.Id, &product.CreatedAt)
+	err := tx.QueryRow(ctx, query, args).Scan(&product.Id, &product.CreatedAt, &product.Version)
 	return product, err
 }
 
-func (p *productRepository) FindMany(ctx context.Context, pool *pgxpool.Pool, params *entity.ProductQueryParams) (*[]entity.Product, error) {
-	query := "SELECT id, name, sku, category, image_url, notes, price, stock, location, is_available, created_at FROM products WHERE deleted_at IS NULL"
+func (p *productRepository) FindMany(ctx context.Context, pool *pgxpool.Pool, params *entity.ProductQueryParams) (*[]entity.Product, *entity.PageMeta, error) {
 	args := pgx.NamedArgs{}
+	where := p.filter(params, args)
 
-	if params.ID != "" {
-		query += " AND id = @id"
-		args["id"] = params.ID
+	keys, sort, err := p.sortKeys(params)
+	if err != nil {
+		return nil, nil, err
 	}
 
-	if params.Name != "" {
-		query += " AND LOWER(name) = @name"
-		args["name"] = "%" + strings.ToLower(params.Name) + "%"
-	}
+	query := pageQuery("SELECT "+p.columns(params)+" FROM products WHERE deleted_at IS NULL"+where, keys, &params.PageParams, args)
 
-	if params.SKU != "" {
-		query += " AND sku = @sku"
-		args["sku"] = params.SKU
+	rows, err := pool.Query(ctx, query, args)
+	if err != nil {
+		return nil, nil, err
 	}
 
-	if params.Category != "" {
-		query += " AND category = @category"
-		args["category"] = params.Category
+	products, err := pgx.CollectRows(rows, pgx.RowToStructByPos[entity.Product])
+	if err != nil {
+		return nil, nil, err
 	}
 
-	if params.IsAvailable != nil {
-		query += " AND is_available = @isAvailable"
-		args["isAvailable"] = *params.IsAvailable
-	}
+	products, meta := paginate(products, &params.PageParams, func(product entity.Product) entity.Cursor {
+		return p.cursorOf(sort, params, product.Id, product.Price, product.CreatedAt)
+	})
 
-	if params.InStock != nil {
-		if *params.InStock == true {
-			query += " AND stock > 0"
-		} else {
-			query += " AND stock = 0"
+	if params.WithTotal {
+		if meta.Total, err = countRows(ctx, pool, "SELECT COUNT(*) FROM products WHERE deleted_at IS NULL"+where, args); err != nil {
+			return nil, nil, err
 		}
 	}
 
-	if params.Price != "" && params.CreatedAt != "" {
-		query += " ORDER BY price " + params.Price + ", created_at " + params.CreatedAt
-	} else if params.Price != "" {
-		query += " ORDER BY price " + params.Price
-	} else if params.CreatedAt != "" {
-		query += " ORDER BY created_at " + params.CreatedAt
-	} else {
-		query += " ORDER BY  created_at desc"
-	}
-
-	args["limit"] = params.Limit
-	args["offset"] = params.Offset
-
-	query += " LIMIT @limit OFFSET @offset"
-
-	rows, err := pool.Query(ctx, query, args)
-	if err != nil {
-		return nil, err
-	}
-
-	products, err := pgx.CollectRows(rows, pgx.RowToStructByPos[entity.Product])
-
-	return &products, err
+	return &products, meta, nil
 }
 
 func (p *productRepository) IsExists(ctx context.Context, pool *pgxpool.Pool, productId string) bool {
@@ -129,7 +133,7 @@ func (p *productRepository) IsExists(ctx context.Context, pool *pgxpool.Pool, pr
 
 func (p *productRepository) FindOne(ctx context.Context, pool *pgxpool.Pool, ID string) (*entity.Product, error) {
 	var product entity.Product
-	query := "SELECT id, name, sku, category, image_url, notes, price, stock, location, is_available, created_at FROM products WHERE deleted_at IS NULL AND id = $1 LIMIT 1;"
+	query := "SELECT " + p.columns(nil) + " FROM products WHERE deleted_at IS NULL AND id = $1 LIMIT 1;"
 
 	rows, err := pool.Query(ctx, query, ID)
 	if err != nil {
@@ -144,66 +148,57 @@ func (p *productRepository) FindOne(ctx context.Context, pool *pgxpool.Pool, ID
 	return &product, nil
 }
 
-func (p *productRepository) FindSku(ctx context.Context, pool *pgxpool.Pool, params *entity.ProductQueryParams) (*[]entity.ProductSKU, error) {
-	query := "SELECT id, name, sku, category, image_url, price, stock, location, created_at FROM products WHERE deleted_at IS NULL AND is_available = true"
+func (p *productRepository) FindSku(ctx context.Context, pool *pgxpool.Pool, params *entity.ProductQueryParams) (*[]entity.ProductSKU, *entity.PageMeta, error) {
 	args := pgx.NamedArgs{}
+	where := " AND is_available = true" + p.filter(params, args)
 
-	if params.ID != "" {
-		query += " AND id = @id"
-		args["id"] = params.ID
-	}
-
-	if params.Name != "" {
-		query += " AND name = @name"
-		args["name"] = "%" + params.Name + "%"
-	}
-
-	if params.SKU != "" {
-		query += " AND sku = @sku"
-		args["sku"] = params.SKU
+	keys, sort, err := p.sortKeys(params)
+	if err != nil {
+		return nil, nil, err
 	}
 
-	if params.Category != "" {
-		query += " AND category = @category"
-		args["category"] = params.Category
-	}
+	query := pageQuery("SELECT id, name, sku, category, image_url, price, "+p.stockColumn(params)+", location, created_at, parent_id, variant_options, tags, attributes FROM products WHERE deleted_at IS NULL"+where, keys, &params.PageParams, args)
 
-	if params.InStock != nil {
-		if *params.InStock == true {
-			query += " AND stock > 0"
-		} else {
-			query += " AND stock = 0"
-		}
+	rows, err := pool.Query(ctx, query, args)
+	if err != nil {
+		return nil, nil, err
 	}
 
-	if params.Price != "" {
-		query += " ORDER BY price " + params.Price
-	} else {
-		query += " ORDER BY created_at desc"
+	productSKU, err := pgx.CollectRows(rows, pgx.RowToStructByPos[entity.ProductSKU])
+	if err != nil {
+		return nil, nil, err
 	}
 
-	args["limit"] = params.Limit
-	args["offset"] = params.Offset
-
-	query += " LIMIT @limit OFFSET @offset"
+	productSKU, meta := paginate(productSKU, &params.PageParams, func(product entity.ProductSKU) entity.Cursor {
+		return p.cursorOf(sort, params, product.Id, product.Price, product.CreatedAt)
+	})
 
-	rows, err := pool.Query(ctx, query, args)
-	if err != nil {
-		return nil, err
+	if params.WithTotal {
+		if meta.Total, err = countRows(ctx, pool, "SELECT COUNT(*) FROM products WHERE deleted_at IS NULL"+where, args); err != nil {
+			return nil, nil, err
+		}
 	}
 
-	productSKU, err := pgx.CollectRows(rows, pgx.RowToStructByPos[entity.ProductSKU])
-
-	return &productSKU, err
+	return &productSKU, meta, nil
 }
 
+// UpdateTx writes product when its row is still at product.Version, and stores
+// the incremented version back into product. ErrVersionConflict is returned
+// when another update got there first.
 func (p *productRepository) UpdateTx(ctx context.Context, tx pgx.Tx, product *entity.Product) error {
 	query := `
 		UPDATE products 
 			SET name = @name, sku = @sku, category = @category, image_url = @imageUrl, 
-				notes = @notes, price = @price, stock = @stock, location = @location, is_available = @isAvailable
-		WHERE id = @id
+				thumbnail_url = CASE WHEN image_url = @imageUrl THEN thumbnail_url ELSE '' END,
+				notes = @notes, price = @price, location = @location, is_available = @isAvailable,
+				variant_attributes = @variantAttributes, variant_options = @variantOptions, inherit_price = @inheritPrice,
+				tags = @tags, attributes = @attributes, cost_price = @costPrice, track_serials = @trackSerials,
+				unit = @unit, quantity_precision = @quantityPrecision, purchase_unit = @purchaseUnit, purchase_unit_factor = @purchaseUnitFactor,
+				version = version + 1
+		WHERE id = @id AND version = @version AND deleted_at IS NULL
+		RETURNING version
 	`
+	p.normalizeJSON(product)
 
 	args := pgx.NamedArgs{
 		"id":          product.Id,
@@ -213,30 +208,190 @@ func (p *productRepository) UpdateTx(ctx context.Context, tx pgx.Tx, product *en
 		"imageUrl":    product.ImageUrl,
 		"notes":       product.Notes,
 		"price":       product.Price,
-		"stock":       product.Stock,
 		"location":    product.Location,
 		"isAvailable": product.IsAvailable,
+
+		"variantAttributes": product.VariantAttributes,
+		"variantOptions":    product.VariantOptions,
+		"inheritPrice":      product.InheritPrice,
+		"tags":              product.Tags,
+		"attributes":        product.Attributes,
+		"costPrice":         product.CostPrice,
+		"trackSerials":      product.TrackSerials,
+		"version":           product.Version,
+
+		"unit":               product.Unit,
+		"quantityPrecision":  product.QuantityPrecision,
+		"purchaseUnit":       product.PurchaseUnit,
+		"purchaseUnitFactor": product.PurchaseUnitFactor,
 	}
 
-	_, err := tx.Exec(ctx, query, args)
+	err := tx.QueryRow(ctx, query, args).Scan(&product.Version)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrVersionConflict
+	}
 
 	return err
 }
 
+// BumpVersionTx increments the version of product ID when it is still at
+// version, it is used by writes that do not go through UpdateTx.
+func (p *productRepository) BumpVersionTx(ctx context.Context, tx pgx.Tx, ID string, version int) (int, error) {
+	query := "UPDATE products SET version = version + 1 WHERE id = $1 AND version = $2 AND deleted_at IS NULL RETURNING version"
+
+	err := tx.QueryRow(ctx, query, ID, version).Scan(&version)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, ErrVersionConflict
+	}
+
+	return version, err
+}
+
+// SetImageTx points product ID at an uploaded image and its thumbnail when it
+// is still at version.
+func (p *productRepository) SetImageTx(ctx context.Context, tx pgx.Tx, ID string, imageUrl string, thumbnailUrl string, version int) (int, error) {
+	query := `
+		UPDATE products SET image_url = $1, thumbnail_url = $2, version = version + 1
+		WHERE id = $3 AND version = $4 AND deleted_at IS NULL
+		RETURNING version`
+
+	err := tx.QueryRow(ctx, query, imageUrl, thumbnailUrl, ID, version).Scan(&version)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, ErrVersionConflict
+	}
+
+	return version, err
+}
+
+// Delete removes product ID together with its variants that are not deleted
+// yet, they share its deleted_at so Restore brings them back with it. A product
+// already deleted is not found.
 func (p *productRepository) Delete(ctx context.Context, pool *pgxpool.Pool, ID string) error {
-	query := "UPDATE products SET deleted_at = NOW() WHERE ID = $1"
+	query := `
+		UPDATE products SET deleted_at = NOW()
+		WHERE deleted_at IS NULL
+			AND (id = $1 OR parent_id = (SELECT id FROM products WHERE id = $1 AND deleted_at IS NULL))`
 
 	tag, err := pool.Exec(ctx, query, ID)
+	if err != nil {
+		return err
+	}
 
 	if tag.RowsAffected() < 1 {
 		return exception.NewNotFound("product id not found")
 	}
 
+	return nil
+}
+
+func (p *productRepository) FindDeleted(ctx context.Context, pool *pgxpool.Pool, page *entity.PageParams) ([]entity.DeletedProduct, *entity.PageMeta, error) {
+	args := pgx.NamedArgs{}
+
+	keys, sort, err := createdAtKeys("deleted_at", "id", "desc", page.Cursor)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	query := pageQuery("SELECT "+p.columns(nil)+", deleted_at FROM products WHERE deleted_at IS NOT NULL", keys, page, args)
+
+	rows, err := pool.Query(ctx, query, args)
+	if err != nil {
+		panic(err)
+	}
+
+	products, err := pgx.CollectRows(rows, pgx.RowToStructByPos[entity.DeletedProduct])
+	if err != nil {
+		panic(err)
+	}
+
+	products, meta := paginate(products, page, func(product entity.DeletedProduct) entity.Cursor {
+		return entity.Cursor{Sort: sort, CreatedAt: *product.DeletedAt, Id: product.Id}
+	})
+
+	if page.WithTotal {
+		if meta.Total, err = countRows(ctx, pool, "SELECT COUNT(*) FROM products WHERE deleted_at IS NOT NULL", args); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return products, meta, nil
+}
+
+func (p *productRepository) FindDeletedOne(ctx context.Context, pool *pgxpool.Pool, ID string) (*entity.DeletedProduct, error) {
+	query := "SELECT " + p.columns(nil) + ", deleted_at FROM products WHERE deleted_at IS NOT NULL AND id = $1"
+
+	rows, err := pool.Query(ctx, query, ID)
+	if err != nil {
+		return nil, errors.New("product id not found")
+	}
+
+	product, err := pgx.CollectOneRow(rows, pgx.RowToStructByPos[entity.DeletedProduct])
+	if err != nil {
+		return nil, errors.New("product id not found")
+	}
+
+	return &product, nil
+}
+
+// Restore undeletes product ID together with the variants that were deleted
+// with it, variants deleted on their own before stay deleted.
+func (p *productRepository) Restore(ctx context.Context, pool *pgxpool.Pool, ID string) error {
+	query := `
+		UPDATE products SET deleted_at = NULL, version = version + 1
+		WHERE deleted_at IS NOT NULL
+			AND (id = $1 OR (parent_id = $1 AND deleted_at = (SELECT deleted_at FROM products WHERE id = $1)))`
+
+	_, err := pool.Exec(ctx, query, ID)
+
+	return err
+}
+
+// IsSold reports whether product ID or one of its variants appears in a transaction.
+func (p *productRepository) IsSold(ctx context.Context, pool *pgxpool.Pool, ID string) bool {
+	var n int
+	query := `
+		SELECT 1 FROM transaction_detail
+		WHERE product_id IN (SELECT id FROM products WHERE id = $1 OR parent_id = $1)
+		LIMIT 1`
+
+	err := pool.QueryRow(ctx, query, ID).Scan(&n)
+	if err != nil {
+		return false
+	}
+
+	return true
+}
+
+// IsTransferred reports whether product ID or one of its variants appears in a stock transfer.
+func (p *productRepository) IsTransferred(ctx context.Context, pool *pgxpool.Pool, ID string) bool {
+	var n int
+	query := `
+		SELECT 1 FROM stock_transfer_items
+		WHERE product_id IN (SELECT id FROM products WHERE id = $1 OR parent_id = $1)
+		LIMIT 1`
+
+	err := pool.QueryRow(ctx, query, ID).Scan(&n)
+	if err != nil {
+		return false
+	}
+
+	return true
+}
+
+// PurgeTx permanently removes product ID and its variants, stock levels are
+// removed through the foreign key.
+func (p *productRepository) PurgeTx(ctx context.Context, tx pgx.Tx, ID string) error {
+	if _, err := tx.Exec(ctx, "DELETE FROM products WHERE parent_id = $1", ID); err != nil {
+		return err
+	}
+
+	_, err := tx.Exec(ctx, "DELETE FROM products WHERE id = $1", ID)
+
 	return err
 }
 
 func (p *productRepository) FindByIds(ctx context.Context, pool *pgxpool.Pool, productIds []string) *[]entity.Product {
-	query := "SELECT id, name, sku, category, image_url, notes, price, stock, location, is_available, created_at FROM products WHERE deleted_at IS NULL AND id::TEXT = ANY($1);"
+	query := "SELECT " + p.columns(nil) + " FROM products WHERE deleted_at IS NULL AND id::TEXT = ANY($1);"
 
 	rows, err := pool.Query(ctx, query, productIds)
 	if err != nil {
@@ -249,3 +404,384 @@ func (p *productRepository) FindByIds(ctx context.Context, pool *pgxpool.Pool, p
 	}
 	return &products
 }
+
+func (p *productRepository) FindVariants(ctx context.Context, pool *pgxpool.Pool, parentIds []string) map[string][]entity.Product {
+	query := "SELECT " + p.columns(nil) + " FROM products WHERE deleted_at IS NULL AND parent_id::TEXT = ANY($1) ORDER BY created_at ASC"
+
+	rows, err := pool.Query(ctx, query, parentIds)
+	if err != nil {
+		panic(err)
+	}
+
+	products, err := pgx.CollectRows(rows, pgx.RowToStructByPos[entity.Product])
+	if err != nil {
+		panic(err)
+	}
+
+	variants := map[string][]entity.Product{}
+	for _, product := range products {
+		variants[*product.ParentId] = append(variants[*product.ParentId], product)
+	}
+
+	return variants
+}
+
+// LockPriceTx returns the price of product ID and locks its row until the end of tx.
+func (p *productRepository) LockPriceTx(ctx context.Context, tx pgx.Tx, ID string) (int, error) {
+	var price int
+	query := "SELECT price FROM products WHERE id = $1 AND deleted_at IS NULL FOR UPDATE"
+
+	err := tx.QueryRow(ctx, query, ID).Scan(&price)
+	if err != nil {
+		return 0, errors.New("product id not found")
+	}
+
+	return price, nil
+}
+
+// UpdateAverageCostTx folds a received quantity at unitCost into the weighted
+// average cost of product ID. It must run before the stock is incremented, the
+// stock on hand at every location is valued at the current average cost.
+func (p *productRepository) UpdateAverageCostTx(ctx context.Context, tx pgx.Tx, ID string, quantity entity.Quantity, unitCost int) error {
+	query := `
+		WITH on_hand AS (
+			SELECT COALESCE(SUM(stock), 0) AS stock FROM product_stocks WHERE product_id = $1
+		)
+		UPDATE products
+			SET cost_price = CASE
+				WHEN on_hand.stock <= 0 THEN $3
+				ELSE ROUND((on_hand.stock * cost_price + $2::NUMERIC * $3) / (on_hand.stock + $2))
+			END
+		FROM on_hand
+		WHERE id = $1`
+
+	_, err := tx.Exec(ctx, query, ID, quantity, unitCost)
+
+	return err
+}
+
+// UpdatePriceTx changes the price of product ID and of its variants following
+// the parent price.
+func (p *productRepository) UpdatePriceTx(ctx context.Context, tx pgx.Tx, ID string, price int) error {
+	query := `
+		UPDATE products SET price = $2, version = version + 1
+		WHERE id = $1 OR (parent_id = $1 AND inherit_price AND price <> $2 AND deleted_at IS NULL)`
+
+	_, err := tx.Exec(ctx, query, ID, price)
+
+	return err
+}
+
+// UpdateVariantsTx copies the shared fields of a parent product to its live
+// variants, the price is only copied to variants without their own price.
+func (p *productRepository) UpdateVariantsTx(ctx context.Context, tx pgx.Tx, parent *entity.Product) error {
+	query := `
+		UPDATE products
+			SET name = @name, category = @category, notes = @notes, location = @location,
+				tags = @tags, attributes = @attributes, track_serials = @trackSerials,
+				unit = @unit, quantity_precision = @quantityPrecision, purchase_unit = @purchaseUnit, purchase_unit_factor = @purchaseUnitFactor,
+				price = CASE WHEN inherit_price THEN @price ELSE price END,
+				version = version + 1
+		WHERE parent_id = @id AND deleted_at IS NULL
+	`
+
+	args := pgx.NamedArgs{
+		"id":       parent.Id,
+		"name":     parent.Name,
+		"category": parent.Category,
+		"notes":    parent.Notes,
+		"location": parent.Location,
+		"price":    parent.Price,
+
+		"tags":         parent.Tags,
+		"attributes":   parent.Attributes,
+		"trackSerials": parent.TrackSerials,
+
+		"unit":               parent.Unit,
+		"quantityPrecision":  parent.QuantityPrecision,
+		"purchaseUnit":       parent.PurchaseUnit,
+		"purchaseUnitFactor": parent.PurchaseUnitFactor,
+	}
+
+	_, err := tx.Exec(ctx, query, args)
+
+	return err
+}
+
+// filter returns the conditions shared by the product listings.
+func (p *productRepository) filter(params *entity.ProductQueryParams, args pgx.NamedArgs) string {
+	where := ""
+
+	if params.LocationId != "" {
+		args["locationId"] = params.LocationId
+	}
+
+	if params.GroupVariants {
+		where += " AND parent_id IS NULL"
+	}
+
+	if params.ID != "" {
+		where += " AND id = @id"
+		args["id"] = params.ID
+	}
+
+	if params.Name != "" {
+		where += " AND LOWER(name) LIKE @name"
+		args["name"] = "%" + escapeLike(strings.ToLower(params.Name)) + "%"
+	}
+
+	if params.SKU != "" {
+		where += " AND sku = @sku"
+		args["sku"] = params.SKU
+	}
+
+	if params.Category != "" {
+		where += " AND category IN (" + categoryTreeQuery + ")"
+		args["category"] = params.Category
+	}
+
+	if len(params.Tags) > 0 {
+		where += " AND tags @> @tags::JSONB"
+		args["tags"] = params.Tags
+	}
+
+	if len(params.Attributes) > 0 {
+		where += " AND attributes @> @attributes::JSONB"
+		args["attributes"] = params.Attributes
+	}
+
+	if params.IsAvailable != nil {
+		where += " AND is_available = @isAvailable"
+		args["isAvailable"] = *params.IsAvailable
+	}
+
+	if params.InStock != nil {
+		if *params.InStock == true {
+			where += " AND " + p.stockColumnExpr(params) + " > 0"
+		} else {
+			where += " AND " + p.stockColumnExpr(params) + " = 0"
+		}
+	}
+
+	return where
+}
+
+// sortKeys orders by price when asked, then by created_at and id so every
+// ordering can be paged with a cursor.
+func (p *productRepository) sortKeys(params *entity.ProductQueryParams) ([]sortKey, string, error) {
+	keys := []sortKey{}
+	createdDesc := params.CreatedAt != "asc"
+	sort := "created_at:" + params.CreatedAt
+
+	if params.Price != "" {
+		keys = append(keys, sortKey{column: "price", desc: params.Price == "desc"})
+		sort = "price:" + params.Price + "," + sort
+	}
+
+	keys = append(keys,
+		sortKey{column: "created_at", desc: createdDesc},
+		sortKey{column: "id", desc: createdDesc},
+	)
+
+	if params.Cursor == nil {
+		return keys, sort, nil
+	}
+
+	if params.Cursor.Sort != sort || (params.Price != "" && params.Cursor.Price == nil) {
+		return nil, "", ErrInvalidCursor
+	}
+
+	i := 0
+	if params.Price != "" {
+		keys[0].value = *params.Cursor.Price
+		i = 1
+	}
+	keys[i].value = params.Cursor.CreatedAt
+	keys[i+1].value = params.Cursor.Id
+
+	return keys, sort, nil
+}
+
+func (p *productRepository) cursorOf(sort string, params *entity.ProductQueryParams, id string, price int, createdAt *time.Time) entity.Cursor {
+	cursor := entity.Cursor{Sort: sort, Id: id, CreatedAt: *createdAt}
+	if params.Price != "" {
+		cursor.Price = &price
+	}
+
+	return cursor
+}
+
+// searchScore ranks exact and prefix SKU hits first, then name prefix and
+// substring hits, exact tags, and finally trigram similarity which also
+// catches typos.
+const searchScore = `(
+	CASE WHEN LOWER(sku) = @q THEN 10 WHEN LOWER(sku) LIKE @prefix THEN 5 ELSE 0 END
+	+ CASE WHEN LOWER(name) LIKE @prefix THEN 3 WHEN LOWER(name) LIKE @contains THEN 1.5 ELSE 0 END
+	+ CASE WHEN tags @> JSONB_BUILD_ARRAY(@q::TEXT) THEN 2 ELSE 0 END
+	+ 2 * WORD_SIMILARITY(@q, LOWER(name))
+	+ WORD_SIMILARITY(@q, LOWER(sku))
+	+ 0.5 * WORD_SIMILARITY(@q, LOWER(notes))
+)`
+
+const searchMatch = `(
+	LOWER(sku) LIKE @prefix
+	OR LOWER(name) LIKE @contains
+	OR @q <% LOWER(name)
+	OR @q <% LOWER(sku)
+	OR @q <% LOWER(notes)
+	OR tags @> JSONB_BUILD_ARRAY(@q::TEXT)
+)`
+
+// Search ranks the products matching q by score, then newest first. Pages
+// are keyed on the score so a cursor stays valid while products are added.
+func (p *productRepository) Search(ctx context.Context, pool *pgxpool.Pool, params *entity.ProductSearchParams) ([]entity.ProductSearchResult, *entity.PageMeta, error) {
+	where := " WHERE deleted_at IS NULL AND " + searchMatch
+	args := p.searchArgs(params.Q)
+
+	if params.Category != "" {
+		where += " AND category IN (" + categoryTreeQuery + ")"
+		args["category"] = params.Category
+	}
+
+	if params.IsAvailable != nil {
+		where += " AND is_available = @isAvailable"
+		args["isAvailable"] = *params.IsAvailable
+	}
+
+	const sort = "score:desc,created_at:desc"
+	keys := []sortKey{
+		{column: searchScore, desc: true},
+		{column: "created_at", desc: true},
+		{column: "id", desc: true},
+	}
+
+	if params.Cursor != nil {
+		if params.Cursor.Sort != sort || params.Cursor.Score == nil {
+			return nil, nil, ErrInvalidCursor
+		}
+
+		keys[0].value = *params.Cursor.Score
+		keys[1].value = params.Cursor.CreatedAt
+		keys[2].value = params.Cursor.Id
+	}
+
+	query := pageQuery("SELECT "+p.columns(nil)+", "+searchScore+" AS score FROM products"+where, keys, &params.PageParams, args)
+
+	var results []entity.ProductSearchResult
+	var total *int
+	err := withSimilarityThreshold(ctx, pool, func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, query, args)
+		if err != nil {
+			return err
+		}
+
+		if results, err = pgx.CollectRows(rows, pgx.RowToStructByPos[entity.ProductSearchResult]); err != nil {
+			return err
+		}
+
+		if params.WithTotal {
+			total = new(int)
+			return tx.QueryRow(ctx, "SELECT COUNT(*) FROM products"+where, args).Scan(total)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	results, meta := paginate(results, &params.PageParams, func(result entity.ProductSearchResult) entity.Cursor {
+		score := result.Score
+		return entity.Cursor{Sort: sort, CreatedAt: *result.CreatedAt, Id: result.Id, Score: &score}
+	})
+	meta.Total = total
+
+	return results, meta, nil
+}
+
+// Suggest returns the best matching sellable products for an autocomplete box.
+func (p *productRepository) Suggest(ctx context.Context, pool *pgxpool.Pool, q string, limit int) ([]entity.ProductSuggestion, error) {
+	query := `
+		SELECT id, name, sku, price, ` + p.stockColumn(nil) + ` FROM products
+		WHERE deleted_at IS NULL AND is_available = TRUE AND variant_attributes = '[]'::JSONB AND ` + searchMatch + `
+		ORDER BY ` + searchScore + ` DESC, name ASC
+		LIMIT @limit`
+	args := p.searchArgs(q)
+	args["limit"] = limit
+
+	var suggestions []entity.ProductSuggestion
+	err := withSimilarityThreshold(ctx, pool, func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, query, args)
+		if err != nil {
+			return err
+		}
+
+		suggestions, err = pgx.CollectRows(rows, pgx.RowToStructByPos[entity.ProductSuggestion])
+		return err
+	})
+
+	return suggestions, err
+}
+
+func (p *productRepository) searchArgs(q string) pgx.NamedArgs {
+	q = strings.ToLower(strings.TrimSpace(q))
+
+	return pgx.NamedArgs{
+		"q":        q,
+		"prefix":   escapeLike(q) + "%",
+		"contains": "%" + escapeLike(q) + "%",
+	}
+}
+
+func (p *productRepository) normalizeJSON(product *entity.Product) {
+	if product.VariantAttributes == nil {
+		product.VariantAttributes = []string{}
+	}
+
+	if product.VariantOptions == nil {
+		product.VariantOptions = map[string]string{}
+	}
+
+	if product.Tags == nil {
+		product.Tags = []string{}
+	}
+
+	if product.Attributes == nil {
+		product.Attributes = map[string]string{}
+	}
+}
+
+// categoryTreeQuery selects the @category name with all of its subcategories.
+const categoryTreeQuery = `
+	WITH RECURSIVE tree AS (
+		SELECT id, name FROM categories WHERE name = @category
+		UNION ALL
+		SELECT c.id, c.name FROM categories c JOIN tree t ON c.parent_id = t.id
+	)
+	SELECT name FROM tree`
+
+func (p *productRepository) columns(params *entity.ProductQueryParams) string {
+	return "id, name, sku, category, image_url, notes, price, " + p.stockColumn(params) + ", location, is_available, created_at, parent_id, variant_attributes, variant_options, inherit_price, tags, attributes, version, cost_price, thumbnail_url, is_bundle, track_serials, unit, quantity_precision, purchase_unit, purchase_unit_factor"
+}
+
+// stockColumnExpr returns the stock of a single location when the listing is
+// filtered by location, otherwise the total over all locations. A bundle has
+// no stock of its own, it has as many whole bundles as its scarcest component
+// allows.
+func (p *productRepository) stockColumnExpr(params *entity.ProductQueryParams) string {
+	own, component := "products.stock", "c.stock"
+	if params != nil && params.LocationId != "" {
+		own = "COALESCE((SELECT ps.stock FROM product_stocks ps WHERE ps.product_id = products.id AND ps.location_id = @locationId), 0)"
+		component = "COALESCE((SELECT ps.stock FROM product_stocks ps WHERE ps.product_id = c.id AND ps.location_id = @locationId), 0)"
+	}
+
+	return `(CASE WHEN products.is_bundle THEN
+		(SELECT COALESCE(MIN(CASE WHEN c.deleted_at IS NULL THEN FLOOR(` + component + ` / b.quantity) ELSE 0 END), 0)
+			FROM bundle_items b JOIN products c ON c.id = b.product_id
+			WHERE b.bundle_id = products.id)
+		ELSE ` + own + ` END)`
+}
+
+func (p *productRepository) stockColumn(params *entity.ProductQueryParams) string {
+	return p.stockColumnExpr(params) + " AS stock"
+}
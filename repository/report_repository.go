@@ -0,0 +1,119 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/malikfajr/eq-store/entity"
+)
+
+type ReportRepository interface {
+	Margin(ctx context.Context, pool *pgxpool.Pool, params *entity.MarginReportParams) []entity.MarginReportRow
+	InventoryValuation(ctx context.Context, pool *pgxpool.Pool, locationId string) []entity.InventoryValuationRow
+}
+
+type reportRepository struct{}
+
+func NewReportRepository() ReportRepository {
+	return &reportRepository{}
+}
+
+// Margin sums the sold lines at the total they were sold for and their cost,
+// rounded per line like the price. Points redeemed in a transaction are taken
+// off its lines in proportion to their totals. The component lines of a sold
+// bundle are left out, the bundle line holds both.
+func (r *reportRepository) Margin(ctx context.Context, pool *pgxpool.Pool, params *entity.MarginReportParams) []entity.MarginReportRow {
+	var key, name, order string
+	args := pgx.NamedArgs{}
+
+	switch params.GroupBy {
+	case entity.MarginByCategory:
+		key, name, order = "p.category", "p.category", "revenue DESC"
+	case entity.MarginByPeriod:
+		key = "TO_CHAR(DATE_TRUNC(@period, t.created_at), 'YYYY-MM-DD')"
+		name, order = key, "key ASC"
+		args["period"] = params.Period
+	default:
+		key, name, order = "p.id::TEXT", "p.name", "revenue DESC"
+	}
+
+	query := `
+		SELECT ` + key + ` AS key, ` + name + ` AS name,
+			SUM(td.quantity) AS quantity,
+			SUM(td.total_price - CASE WHEN t.points_discount = 0 THEN 0
+				ELSE ROUND(t.points_discount::NUMERIC * td.total_price / NULLIF(lines.total, 0)) END)::BIGINT AS revenue,
+			SUM(ROUND(td.quantity * td.unit_cost))::BIGINT AS cost
+		FROM transaction_detail td
+			JOIN transactions t ON t.id = td.transaction_id
+			JOIN products p ON p.id = td.product_id
+			JOIN (
+				SELECT transaction_id, SUM(total_price) AS total FROM transaction_detail
+				WHERE bundle_id IS NULL GROUP BY transaction_id
+			) lines ON lines.transaction_id = td.transaction_id
+		WHERE td.bundle_id IS NULL AND t.refunded_at IS NULL`
+
+	if params.From != nil {
+		query += " AND t.created_at >= @from"
+		args["from"] = *params.From
+	}
+
+	if params.To != nil {
+		query += " AND t.created_at < @to"
+		args["to"] = *params.To
+	}
+
+	if params.LocationId != "" {
+		query += " AND t.location_id = @locationId"
+		args["locationId"] = params.LocationId
+	}
+
+	query += " GROUP BY 1, 2 ORDER BY " + order
+
+	rows, err := pool.Query(ctx, query, args)
+	if err != nil {
+		panic(err)
+	}
+
+	report, err := pgx.CollectRows(rows, pgx.RowToStructByPos[entity.MarginReportRow])
+	if err != nil {
+		panic(err)
+	}
+
+	return report
+}
+
+// InventoryValuation values the stock of every sellable product, at one
+// location or over all locations.
+func (r *reportRepository) InventoryValuation(ctx context.Context, pool *pgxpool.Pool, locationId string) []entity.InventoryValuationRow {
+	stock := "p.stock"
+	args := pgx.NamedArgs{}
+
+	if locationId != "" {
+		stock = "COALESCE((SELECT ps.stock FROM product_stocks ps WHERE ps.product_id = p.id AND ps.location_id = @locationId), 0)"
+		args["locationId"] = locationId
+	}
+
+	query := `
+		SELECT id, name, sku, category, stock, cost_price, price,
+			ROUND(stock * cost_price)::BIGINT AS cost_value, ROUND(stock * price)::BIGINT AS retail_value
+		FROM (
+			SELECT p.id, p.name, p.sku, p.category, ` + stock + ` AS stock, p.cost_price, p.price
+			FROM products p
+			WHERE p.deleted_at IS NULL AND p.variant_attributes = '[]'
+		) p
+		WHERE stock > 0
+		ORDER BY category ASC, name ASC`
+
+	rows, err := pool.Query(ctx, query, args)
+	if err != nil {
+		panic(err)
+	}
+
+	report, err := pgx.CollectRows(rows, pgx.RowToStructByPos[entity.InventoryValuationRow])
+	if err != nil {
+		panic(err)
+	}
+
+	return report
+}
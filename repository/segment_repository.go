@@ -0,0 +1,163 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/malikfajr/eq-store/entity"
+)
+
+type SegmentRepository interface {
+	Insert(ctx context.Context, pool *pgxpool.Pool, segment *entity.Segment) error
+	FindAll(ctx context.Context, pool *pgxpool.Pool) []entity.Segment
+	FindOne(ctx context.Context, pool *pgxpool.Pool, ID string) (*entity.Segment, error)
+	Update(ctx context.Context, pool *pgxpool.Pool, segment *entity.Segment) error
+	Delete(ctx context.Context, pool *pgxpool.Pool, ID string) error
+}
+
+type segmentRepository struct{}
+
+func NewSegmentRepository() SegmentRepository {
+	return &segmentRepository{}
+}
+
+const segmentColumns = "id, name, description, rules, created_at, updated_at"
+
+func (s *segmentRepository) Insert(ctx context.Context, pool *pgxpool.Pool, segment *entity.Segment) error {
+	query := "INSERT INTO customer_segments (name, description, rules) VALUES ($1, $2, $3) RETURNING id, created_at, updated_at"
+
+	return pool.QueryRow(ctx, query, segment.Name, segment.Description, segment.Rules).Scan(&segment.Id, &segment.CreatedAt, &segment.UpdatedAt)
+}
+
+func (s *segmentRepository) FindAll(ctx context.Context, pool *pgxpool.Pool) []entity.Segment {
+	query := "SELECT " + segmentColumns + " FROM customer_segments ORDER BY name ASC"
+
+	rows, err := pool.Query(ctx, query)
+	if err != nil {
+		panic(err)
+	}
+
+	segments, err := pgx.CollectRows(rows, pgx.RowToStructByPos[entity.Segment])
+	if err != nil {
+		panic(err)
+	}
+
+	return segments
+}
+
+func (s *segmentRepository) FindOne(ctx context.Context, pool *pgxpool.Pool, ID string) (*entity.Segment, error) {
+	query := "SELECT " + segmentColumns + " FROM customer_segments WHERE id = $1"
+
+	rows, err := pool.Query(ctx, query, ID)
+	if err != nil {
+		return nil, errors.New("segment id not found")
+	}
+
+	segment, err := pgx.CollectOneRow(rows, pgx.RowToStructByPos[entity.Segment])
+	if err != nil {
+		return nil, errors.New("segment id not found")
+	}
+
+	return &segment, nil
+}
+
+func (s *segmentRepository) Update(ctx context.Context, pool *pgxpool.Pool, segment *entity.Segment) error {
+	query := "UPDATE customer_segments SET name = $1, description = $2, rules = $3, updated_at = NOW() WHERE id = $4 RETURNING updated_at"
+
+	return pool.QueryRow(ctx, query, segment.Name, segment.Description, segment.Rules, segment.Id).Scan(&segment.UpdatedAt)
+}
+
+func (s *segmentRepository) Delete(ctx context.Context, pool *pgxpool.Pool, ID string) error {
+	query := "DELETE FROM customer_segments WHERE id = $1"
+
+	tag, err := pool.Exec(ctx, query, ID)
+	if err != nil {
+		return err
+	}
+
+	if tag.RowsAffected() < 1 {
+		return errors.New("segment id not found")
+	}
+
+	return nil
+}
+
+// segmentPurchases is the part of a query reading the purchases of customer
+// c that segment rules look at, the component lines of a bundle are left out.
+const segmentPurchases = `
+	FROM transactions t
+		JOIN transaction_detail td ON td.transaction_id = t.id AND td.bundle_id IS NULL
+		JOIN products p ON p.id = td.product_id
+	WHERE t.customer_id = c.id AND t.refunded_at IS NULL`
+
+// segmentCategories names the categories and all their subcategories.
+const segmentCategories = `
+	WITH RECURSIVE tree AS (
+		SELECT id, name FROM categories WHERE name = ANY(%s)
+		UNION ALL
+		SELECT sub.id, sub.name FROM categories sub JOIN tree ON sub.parent_id = tree.id
+	)
+	SELECT name FROM tree`
+
+// segmentQuery returns a query selecting the ids of the customers matching
+// rules, its arguments are added to args.
+func segmentQuery(rules *entity.SegmentRules, args pgx.NamedArgs) string {
+	purchases := segmentPurchases
+	if rules.Days > 0 {
+		purchases += " AND t.created_at >= NOW() - MAKE_INTERVAL(days => @segDays::INT)"
+		args["segDays"] = rules.Days
+	}
+
+	query := "SELECT c.id FROM customers c WHERE c.deleted_at IS NULL"
+
+	spend := "(SELECT COALESCE(SUM(td.total_price), 0)" + purchases + ")"
+	visits := "(SELECT COUNT(DISTINCT t.id)" + purchases + ")"
+
+	if rules.MinSpend != nil {
+		query += " AND " + spend + " >= @segMinSpend"
+		args["segMinSpend"] = *rules.MinSpend
+	}
+
+	if rules.MaxSpend != nil {
+		query += " AND " + spend + " <= @segMaxSpend"
+		args["segMaxSpend"] = *rules.MaxSpend
+	}
+
+	if rules.MinVisits != nil {
+		query += " AND " + visits + " >= @segMinVisits"
+		args["segMinVisits"] = *rules.MinVisits
+	}
+
+	if rules.MaxVisits != nil {
+		query += " AND " + visits + " <= @segMaxVisits"
+		args["segMaxVisits"] = *rules.MaxVisits
+	}
+
+	if len(rules.Categories) > 0 {
+		query += " AND EXISTS (SELECT 1" + purchases + " AND p.category IN (" + fmt.Sprintf(segmentCategories, "@segCategories") + "))"
+		args["segCategories"] = rules.Categories
+	}
+
+	if len(rules.OnlyCategories) > 0 {
+		query += " AND EXISTS (SELECT 1" + purchases + ")" +
+			" AND NOT EXISTS (SELECT 1" + purchases + " AND p.category NOT IN (" + fmt.Sprintf(segmentCategories, "@segOnlyCategories") + "))"
+		args["segOnlyCategories"] = rules.OnlyCategories
+	}
+
+	if rules.InactiveDays != nil {
+		query += `
+			AND NOT EXISTS (SELECT 1 FROM transactions t
+				WHERE t.customer_id = c.id AND t.refunded_at IS NULL AND t.created_at >= NOW() - MAKE_INTERVAL(days => @segInactiveDays::INT))`
+		args["segInactiveDays"] = *rules.InactiveDays
+	}
+
+	if len(rules.Tags) > 0 {
+		query += " AND (SELECT COUNT(*) FROM customer_tags ct WHERE ct.customer_id = c.id AND ct.tag = ANY(@segTags)) = CARDINALITY(@segTags::TEXT[])"
+		args["segTags"] = rules.Tags
+	}
+
+	return query
+}
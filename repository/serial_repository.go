@@ -0,0 +1,157 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/malikfajr/eq-store/entity"
+)
+
+// ErrSerialUnavailable is returned when one of the serials is not in stock at
+// the location.
+var ErrSerialUnavailable = errors.New("serial is not in stock")
+
+type SerialRepository interface {
+	FindExisting(ctx context.Context, pool *pgxpool.Pool, productId string, serials []string) []string
+	InsertTx(ctx context.Context, tx pgx.Tx, productId string, locationId string, receiptId string, serials []string) error
+	SellTx(ctx context.Context, tx pgx.Tx, productId string, locationId string, transactionId string, serials []string) error
+	ReturnTx(ctx context.Context, tx pgx.Tx, transactionId string, locationId string) error
+	SendTx(ctx context.Context, tx pgx.Tx, productId string, locationId string, transferId string, serials []string) error
+	CloseTransferTx(ctx context.Context, tx pgx.Tx, transferId string, locationId string) error
+	FindBySerial(ctx context.Context, pool *pgxpool.Pool, serial string) []entity.Serial
+	CountInStock(ctx context.Context, pool *pgxpool.Pool, productIds []string) map[string]int
+}
+
+type serialRepository struct{}
+
+func NewSerialRepository() SerialRepository {
+	return &serialRepository{}
+}
+
+// FindExisting returns the serials of a product that are already registered.
+func (s *serialRepository) FindExisting(ctx context.Context, pool *pgxpool.Pool, productId string, serials []string) []string {
+	query := "SELECT serial FROM product_serials WHERE product_id = $1 AND serial = ANY($2) ORDER BY serial"
+
+	rows, err := pool.Query(ctx, query, productId, serials)
+	if err != nil {
+		panic(err)
+	}
+
+	existing, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		panic(err)
+	}
+
+	return existing
+}
+
+func (s *serialRepository) InsertTx(ctx context.Context, tx pgx.Tx, productId string, locationId string, receiptId string, serials []string) error {
+	query := "INSERT INTO product_serials (product_id, serial, location_id, receipt_id) VALUES ($1, $2, $3, $4)"
+
+	for _, serial := range serials {
+		if _, err := tx.Exec(ctx, query, productId, serial, locationId, receiptId); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *serialRepository) SellTx(ctx context.Context, tx pgx.Tx, productId string, locationId string, transactionId string, serials []string) error {
+	query := `
+		UPDATE product_serials SET status = 'sold', transaction_id = $1, sold_at = NOW()
+		WHERE product_id = $2 AND location_id = $3 AND serial = ANY($4) AND status = 'in_stock'`
+
+	return s.update(ctx, tx, query, len(serials), transactionId, productId, locationId, serials)
+}
+
+// ReturnTx puts the serials sold in a refunded transaction back in stock at
+// locationId. They keep the transaction id so the receipt still lists them.
+func (s *serialRepository) ReturnTx(ctx context.Context, tx pgx.Tx, transactionId string, locationId string) error {
+	query := "UPDATE product_serials SET status = 'in_stock', location_id = $2 WHERE transaction_id = $1 AND status = 'sold'"
+
+	_, err := tx.Exec(ctx, query, transactionId, locationId)
+
+	return err
+}
+
+func (s *serialRepository) SendTx(ctx context.Context, tx pgx.Tx, productId string, locationId string, transferId string, serials []string) error {
+	query := `
+		UPDATE product_serials SET status = 'in_transit', transfer_id = $1
+		WHERE product_id = $2 AND location_id = $3 AND serial = ANY($4) AND status = 'in_stock'`
+
+	return s.update(ctx, tx, query, len(serials), transferId, productId, locationId, serials)
+}
+
+// update runs query and fails unless every one of the expected serials changed.
+func (s *serialRepository) update(ctx context.Context, tx pgx.Tx, query string, expected int, args ...any) error {
+	tag, err := tx.Exec(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+
+	if tag.RowsAffected() != int64(expected) {
+		return ErrSerialUnavailable
+	}
+
+	return nil
+}
+
+// CloseTransferTx puts the serials in transit back in stock at locationId.
+func (s *serialRepository) CloseTransferTx(ctx context.Context, tx pgx.Tx, transferId string, locationId string) error {
+	query := "UPDATE product_serials SET status = 'in_stock', location_id = $2 WHERE transfer_id = $1 AND status = 'in_transit'"
+
+	_, err := tx.Exec(ctx, query, transferId, locationId)
+
+	return err
+}
+
+func (s *serialRepository) FindBySerial(ctx context.Context, pool *pgxpool.Pool, serial string) []entity.Serial {
+	query := `
+		SELECT s.serial, s.status, s.product_id, p.name, p.sku, s.location_id, s.receipt_id, s.received_at,
+			s.transaction_id, s.sold_at, c.id, c.name, c.phone_number
+		FROM product_serials s
+			JOIN products p ON p.id = s.product_id
+			LEFT JOIN transactions t ON t.id = s.transaction_id
+			LEFT JOIN customers c ON c.id = t.customer_id
+		WHERE s.serial = $1
+		ORDER BY s.received_at DESC`
+
+	rows, err := pool.Query(ctx, query, serial)
+	if err != nil {
+		panic(err)
+	}
+
+	serials, err := pgx.CollectRows(rows, pgx.RowToStructByPos[entity.Serial])
+	if err != nil {
+		panic(err)
+	}
+
+	return serials
+}
+
+// CountInStock returns how many serials of each product are in stock, at any
+// location. Products without serials in stock are left out.
+func (s *serialRepository) CountInStock(ctx context.Context, pool *pgxpool.Pool, productIds []string) map[string]int {
+	query := "SELECT product_id, COUNT(*) FROM product_serials WHERE status = 'in_stock' AND product_id::TEXT = ANY($1) GROUP BY product_id"
+
+	rows, err := pool.Query(ctx, query, productIds)
+	if err != nil {
+		panic(err)
+	}
+	defer rows.Close()
+
+	result := map[string]int{}
+	for rows.Next() {
+		var productId string
+		var count int
+		if err := rows.Scan(&productId, &count); err != nil {
+			panic(err)
+		}
+		result[productId] = count
+	}
+
+	return result
+}
@@ -12,6 +12,7 @@ import (
 type StaffRepository interface {
 	Register(ctx context.Context, pool *pgxpool.Pool, staff *entity.StaffRegisterRequest) (string, error)
 	Login(ctx context.Context, pool *pgxpool.Pool, phoneNumber string) (*entity.Staff, error)
+	LoginConflict(ctx context.Context, pool *pgxpool.Pool, phoneNumber string) (*entity.Staff, error)
 	PhoneIsExist(ctx context.Context, pool *pgxpool.Pool, phoneNumber string) bool
 }
 
@@ -24,10 +25,30 @@ func NewStaffRepository() StaffRepository {
 
 // Login implements staffRepository.
 func (i *staffRepositoryImp) Login(ctx context.Context, pool *pgxpool.Pool, phoneNumber string) (*entity.Staff, error) {
-	query := "SELECT id, phone_number, name, password FROM staffs WHERE phone_number = $1 LIMIT 1"
+	query := "SELECT id, phone_number, name, password, is_admin FROM staffs WHERE phone_number = $1 LIMIT 1"
 	staff := &entity.Staff{}
 
-	err := pool.QueryRow(ctx, query, phoneNumber).Scan(&staff.Id, &staff.PhoneNumber, &staff.Name, &staff.Password)
+	err := pool.QueryRow(ctx, query, phoneNumber).Scan(&staff.Id, &staff.PhoneNumber, &staff.Name, &staff.Password, &staff.IsAdmin)
+	if err != nil {
+		return nil, errors.New("Phone number not found")
+	}
+
+	return staff, nil
+}
+
+// LoginConflict finds a staff by the number it is stored with, for numbers
+// left as they are by an unresolved phone number conflict.
+func (i *staffRepositoryImp) LoginConflict(ctx context.Context, pool *pgxpool.Pool, phoneNumber string) (*entity.Staff, error) {
+	query := `
+		SELECT s.id, s.phone_number, s.name, s.password, s.is_admin FROM staffs s
+		WHERE s.phone_number = $1 AND EXISTS (
+			SELECT 1 FROM phone_number_conflicts pc
+			WHERE pc.table_name = 'staffs' AND pc.row_id = s.id AND pc.resolved_at IS NULL
+		)
+		LIMIT 1`
+	staff := &entity.Staff{}
+
+	err := pool.QueryRow(ctx, query, phoneNumber).Scan(&staff.Id, &staff.PhoneNumber, &staff.Name, &staff.Password, &staff.IsAdmin)
 	if err != nil {
 		return nil, errors.New("Phone number not found")
 	}
@@ -0,0 +1,160 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/malikfajr/eq-store/entity"
+)
+
+var ErrInsufficientStock = errors.New("stock is not enough")
+
+// StockRepository keeps per location stock levels. Every write also refreshes
+// products.stock so it always holds the total over all locations.
+type StockRepository interface {
+	FindByProductIds(ctx context.Context, pool *pgxpool.Pool, productIds []string) map[string][]entity.ProductStock
+	FindAtLocation(ctx context.Context, pool *pgxpool.Pool, locationId string, productIds []string) map[string]entity.Quantity
+	SetTx(ctx context.Context, tx pgx.Tx, productId string, locationId string, stock entity.Quantity) error
+	IncrementTx(ctx context.Context, tx pgx.Tx, productId string, locationId string, quantity entity.Quantity) error
+	DecrementTx(ctx context.Context, tx pgx.Tx, productId string, locationId string, quantity entity.Quantity) error
+	TotalTx(ctx context.Context, tx pgx.Tx, productId string) (entity.Quantity, error)
+}
+
+type stockRepository struct{}
+
+func NewStockRepository() StockRepository {
+	return &stockRepository{}
+}
+
+func (s *stockRepository) FindByProductIds(ctx context.Context, pool *pgxpool.Pool, productIds []string) map[string][]entity.ProductStock {
+	query := `
+		SELECT ps.product_id, ps.location_id, l.name, ps.stock
+		FROM product_stocks ps
+			JOIN locations l ON l.id = ps.location_id AND l.deleted_at IS NULL
+		WHERE ps.product_id::TEXT = ANY($1)
+		ORDER BY l.is_default DESC, l.name ASC`
+
+	rows, err := pool.Query(ctx, query, productIds)
+	if err != nil {
+		panic(err)
+	}
+
+	stocks, err := pgx.CollectRows(rows, pgx.RowToStructByPos[entity.ProductStock])
+	if err != nil {
+		panic(err)
+	}
+
+	result := map[string][]entity.ProductStock{}
+	for _, stock := range stocks {
+		result[stock.ProductId] = append(result[stock.ProductId], stock)
+	}
+
+	return result
+}
+
+func (s *stockRepository) FindAtLocation(ctx context.Context, pool *pgxpool.Pool, locationId string, productIds []string) map[string]entity.Quantity {
+	query := "SELECT product_id, stock FROM product_stocks WHERE location_id = $1 AND product_id::TEXT = ANY($2)"
+
+	rows, err := pool.Query(ctx, query, locationId, productIds)
+	if err != nil {
+		panic(err)
+	}
+	defer rows.Close()
+
+	result := map[string]entity.Quantity{}
+	for rows.Next() {
+		var productId string
+		var stock entity.Quantity
+		if err := rows.Scan(&productId, &stock); err != nil {
+			panic(err)
+		}
+		result[productId] = stock
+	}
+
+	return result
+}
+
+func (s *stockRepository) SetTx(ctx context.Context, tx pgx.Tx, productId string, locationId string, stock entity.Quantity) error {
+	query := `
+		INSERT INTO product_stocks (product_id, location_id, stock) VALUES ($1, $2, $3)
+		ON CONFLICT (product_id, location_id) DO UPDATE SET stock = EXCLUDED.stock, updated_at = NOW()`
+
+	if _, err := tx.Exec(ctx, query, productId, locationId, stock); err != nil {
+		return err
+	}
+
+	if err := s.fitLotsTx(ctx, tx, productId, locationId); err != nil {
+		return err
+	}
+
+	return s.syncTotalTx(ctx, tx, productId)
+}
+
+func (s *stockRepository) IncrementTx(ctx context.Context, tx pgx.Tx, productId string, locationId string, quantity entity.Quantity) error {
+	query := `
+		INSERT INTO product_stocks (product_id, location_id, stock) VALUES ($1, $2, $3)
+		ON CONFLICT (product_id, location_id) DO UPDATE SET stock = product_stocks.stock + EXCLUDED.stock, updated_at = NOW()`
+
+	if _, err := tx.Exec(ctx, query, productId, locationId, quantity); err != nil {
+		return err
+	}
+
+	return s.syncTotalTx(ctx, tx, productId)
+}
+
+func (s *stockRepository) DecrementTx(ctx context.Context, tx pgx.Tx, productId string, locationId string, quantity entity.Quantity) error {
+	query := "UPDATE product_stocks SET stock = stock - $1, updated_at = NOW() WHERE product_id = $2 AND location_id = $3 AND stock >= $1"
+
+	tag, err := tx.Exec(ctx, query, quantity, productId, locationId)
+	if err != nil {
+		return err
+	}
+
+	if tag.RowsAffected() < 1 {
+		return ErrInsufficientStock
+	}
+
+	return s.syncTotalTx(ctx, tx, productId)
+}
+
+func (s *stockRepository) TotalTx(ctx context.Context, tx pgx.Tx, productId string) (entity.Quantity, error) {
+	var total entity.Quantity
+	query := "SELECT stock FROM products WHERE id = $1"
+
+	err := tx.QueryRow(ctx, query, productId).Scan(&total)
+
+	return total, err
+}
+
+// fitLotsTx shrinks the lots of a product at a location when a manual count
+// left less stock than they hold, the lots that expire first are emptied first.
+func (s *stockRepository) fitLotsTx(ctx context.Context, tx pgx.Tx, productId string, locationId string) error {
+	query := `
+		WITH lots AS (
+			SELECT id, quantity,
+				SUM(quantity) OVER (ORDER BY expires_at ASC NULLS LAST, created_at ASC, id ASC) - quantity AS before
+			FROM product_lots
+			WHERE product_id = $1 AND location_id = $2 AND quantity > 0
+		), excess AS (
+			SELECT COALESCE(SUM(quantity), 0)
+				- COALESCE((SELECT stock FROM product_stocks WHERE product_id = $1 AND location_id = $2), 0) AS quantity
+			FROM lots
+		)
+		UPDATE product_lots pl SET quantity = pl.quantity - LEAST(l.quantity, e.quantity - l.before)
+		FROM lots l, excess e
+		WHERE pl.id = l.id AND e.quantity > l.before`
+
+	_, err := tx.Exec(ctx, query, productId, locationId)
+
+	return err
+}
+
+func (s *stockRepository) syncTotalTx(ctx context.Context, tx pgx.Tx, productId string) error {
+	query := "UPDATE products SET stock = (SELECT COALESCE(SUM(stock), 0) FROM product_stocks WHERE product_id = $1) WHERE id = $1"
+
+	_, err := tx.Exec(ctx, query, productId)
+
+	return err
+}
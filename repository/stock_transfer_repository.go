@@ -0,0 +1,141 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/malikfajr/eq-store/entity"
+)
+
+type StockTransferRepository interface {
+	Create(ctx context.Context, tx pgx.Tx, transfer *entity.StockTransfer) error
+	InsertItems(ctx context.Context, tx pgx.Tx, transferId string, items []entity.StockTransferItem) error
+	FindOne(ctx context.Context, pool *pgxpool.Pool, ID string) (*entity.StockTransfer, error)
+	LockOneTx(ctx context.Context, tx pgx.Tx, ID string) (*entity.StockTransfer, error)
+	FindMany(ctx context.Context, pool *pgxpool.Pool, params *entity.StockTransferQueryParams) ([]entity.StockTransfer, *entity.PageMeta, error)
+	UpdateStatusTx(ctx context.Context, tx pgx.Tx, ID string, status string, staffId string) error
+}
+
+type stockTransferRepository struct{}
+
+func NewStockTransferRepository() StockTransferRepository {
+	return &stockTransferRepository{}
+}
+
+const stockTransferColumns = `
+	t.id, t.from_location_id, t.to_location_id, t.status, t.notes,
+	(SELECT JSON_AGG(json_build_object('productId', i.product_id, 'quantity', i.quantity,
+		'serials', (SELECT JSON_AGG(s.serial ORDER BY s.serial)
+			FROM product_serials s
+			WHERE s.transfer_id = t.id AND s.product_id = i.product_id)))
+		FROM stock_transfer_items i
+		WHERE i.transfer_id = t.id) AS items,
+	t.sent_by, t.received_by, t.sent_at, t.received_at`
+
+func (s *stockTransferRepository) Create(ctx context.Context, tx pgx.Tx, transfer *entity.StockTransfer) error {
+	query := `
+		INSERT INTO stock_transfers (from_location_id, to_location_id, notes, sent_by)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, status, sent_at`
+
+	return tx.QueryRow(ctx, query, transfer.FromLocationId, transfer.ToLocationId, transfer.Notes, transfer.SentBy).
+		Scan(&transfer.Id, &transfer.Status, &transfer.SentAt)
+}
+
+func (s *stockTransferRepository) InsertItems(ctx context.Context, tx pgx.Tx, transferId string, items []entity.StockTransferItem) error {
+	query := "INSERT INTO stock_transfer_items (transfer_id, product_id, quantity) VALUES ($1, $2, $3)"
+
+	for _, item := range items {
+		if _, err := tx.Exec(ctx, query, transferId, item.ProductId, item.Quantity); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *stockTransferRepository) FindOne(ctx context.Context, pool *pgxpool.Pool, ID string) (*entity.StockTransfer, error) {
+	query := "SELECT " + stockTransferColumns + " FROM stock_transfers t WHERE t.id = $1"
+
+	rows, err := pool.Query(ctx, query, ID)
+	if err != nil {
+		return nil, errors.New("transfer id not found")
+	}
+
+	transfer, err := pgx.CollectOneRow(rows, pgx.RowToStructByPos[entity.StockTransfer])
+	if err != nil {
+		return nil, errors.New("transfer id not found")
+	}
+
+	return &transfer, nil
+}
+
+func (s *stockTransferRepository) LockOneTx(ctx context.Context, tx pgx.Tx, ID string) (*entity.StockTransfer, error) {
+	query := "SELECT " + stockTransferColumns + " FROM stock_transfers t WHERE t.id = $1 FOR UPDATE"
+
+	rows, err := tx.Query(ctx, query, ID)
+	if err != nil {
+		return nil, errors.New("transfer id not found")
+	}
+
+	transfer, err := pgx.CollectOneRow(rows, pgx.RowToStructByPos[entity.StockTransfer])
+	if err != nil {
+		return nil, errors.New("transfer id not found")
+	}
+
+	return &transfer, nil
+}
+
+func (s *stockTransferRepository) FindMany(ctx context.Context, pool *pgxpool.Pool, params *entity.StockTransferQueryParams) ([]entity.StockTransfer, *entity.PageMeta, error) {
+	where := ""
+	args := pgx.NamedArgs{}
+
+	if params.Status != "" {
+		where += " AND t.status = @status"
+		args["status"] = params.Status
+	}
+
+	if params.LocationId != "" {
+		where += " AND (t.from_location_id = @locationId OR t.to_location_id = @locationId)"
+		args["locationId"] = params.LocationId
+	}
+
+	keys, sort, err := createdAtKeys("t.sent_at", "t.id", "desc", params.Cursor)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	query := pageQuery("SELECT "+stockTransferColumns+" FROM stock_transfers t WHERE 1=1"+where, keys, &params.PageParams, args)
+
+	rows, err := pool.Query(ctx, query, args)
+	if err != nil {
+		panic(err)
+	}
+
+	transfers, err := pgx.CollectRows(rows, pgx.RowToStructByPos[entity.StockTransfer])
+	if err != nil {
+		panic(err)
+	}
+
+	transfers, meta := paginate(transfers, &params.PageParams, func(transfer entity.StockTransfer) entity.Cursor {
+		return entity.Cursor{Sort: sort, CreatedAt: *transfer.SentAt, Id: transfer.Id}
+	})
+
+	if params.WithTotal {
+		if meta.Total, err = countRows(ctx, pool, "SELECT COUNT(*) FROM stock_transfers t WHERE 1=1"+where, args); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return transfers, meta, nil
+}
+
+func (s *stockTransferRepository) UpdateStatusTx(ctx context.Context, tx pgx.Tx, ID string, status string, staffId string) error {
+	query := "UPDATE stock_transfers SET status = $1, received_by = $2, received_at = NOW() WHERE id = $3"
+
+	_, err := tx.Exec(ctx, query, status, staffId, ID)
+
+	return err
+}
@@ -0,0 +1,198 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/malikfajr/eq-store/entity"
+)
+
+// ErrInsufficientBalance is returned when a movement would take an account
+// below zero.
+var ErrInsufficientBalance = errors.New("balance is not enough")
+
+// StoredValueRepository keeps gift cards, store credit and their movements.
+// A balance only changes through MoveTx, which records the movement with it.
+type StoredValueRepository interface {
+	InsertTx(ctx context.Context, tx pgx.Tx, account *entity.StoredValueAccount, staffId string) error
+	FindByCode(ctx context.Context, pool *pgxpool.Pool, code string) (*entity.StoredValueAccount, error)
+	FindByCustomer(ctx context.Context, pool *pgxpool.Pool, customerId string) (*entity.StoredValueAccount, error)
+	LockByCodeTx(ctx context.Context, tx pgx.Tx, code string) (*entity.StoredValueAccount, error)
+	LockOneTx(ctx context.Context, tx pgx.Tx, ID string) (*entity.StoredValueAccount, error)
+	LockByCustomerTx(ctx context.Context, tx pgx.Tx, customerId string) (*entity.StoredValueAccount, error)
+	LockCreditTx(ctx context.Context, tx pgx.Tx, customerId string, staffId string) (*entity.StoredValueAccount, error)
+	MoveTx(ctx context.Context, tx pgx.Tx, account *entity.StoredValueAccount, movement *entity.StoredValueMovement) error
+	FindMovements(ctx context.Context, pool *pgxpool.Pool, accountId string, params *entity.MovementQueryParams) ([]entity.StoredValueMovement, *entity.PageMeta, error)
+	FindAllMovements(ctx context.Context, pool *pgxpool.Pool, accountId string) []entity.StoredValueMovement
+	FindByTransactionTx(ctx context.Context, tx pgx.Tx, transactionId string) ([]entity.StoredValueMovement, error)
+}
+
+type storedValueRepository struct{}
+
+func NewStoredValueRepository() StoredValueRepository {
+	return &storedValueRepository{}
+}
+
+const (
+	storedValueColumns = "id, type, code, customer_id, balance, created_at"
+	movementColumns    = "id, account_id, type, amount, balance_after, transaction_id, staff_id, note, created_at"
+)
+
+// InsertTx creates an account with a zero balance, it is funded by MoveTx.
+func (s *storedValueRepository) InsertTx(ctx context.Context, tx pgx.Tx, account *entity.StoredValueAccount, staffId string) error {
+	query := `
+		INSERT INTO stored_value_accounts (type, code, customer_id, created_by)
+		VALUES ($1, $2, $3, NULLIF($4, '')::UUID)
+		RETURNING id, balance, created_at`
+
+	return tx.QueryRow(ctx, query, account.Type, account.Code, account.CustomerId, staffId).
+		Scan(&account.Id, &account.Balance, &account.CreatedAt)
+}
+
+func (s *storedValueRepository) FindByCode(ctx context.Context, pool *pgxpool.Pool, code string) (*entity.StoredValueAccount, error) {
+	query := "SELECT " + storedValueColumns + " FROM stored_value_accounts WHERE code = $1"
+
+	return s.collectOne(pool.Query(ctx, query, code))
+}
+
+func (s *storedValueRepository) FindByCustomer(ctx context.Context, pool *pgxpool.Pool, customerId string) (*entity.StoredValueAccount, error) {
+	query := "SELECT " + storedValueColumns + " FROM stored_value_accounts WHERE customer_id = $1"
+
+	return s.collectOne(pool.Query(ctx, query, customerId))
+}
+
+func (s *storedValueRepository) LockByCodeTx(ctx context.Context, tx pgx.Tx, code string) (*entity.StoredValueAccount, error) {
+	query := "SELECT " + storedValueColumns + " FROM stored_value_accounts WHERE code = $1 FOR UPDATE"
+
+	return s.collectOne(tx.Query(ctx, query, code))
+}
+
+func (s *storedValueRepository) LockOneTx(ctx context.Context, tx pgx.Tx, ID string) (*entity.StoredValueAccount, error) {
+	query := "SELECT " + storedValueColumns + " FROM stored_value_accounts WHERE id = $1 FOR UPDATE"
+
+	return s.collectOne(tx.Query(ctx, query, ID))
+}
+
+// LockByCustomerTx locks the store credit of a customer, pgx.ErrNoRows when
+// the customer has none.
+func (s *storedValueRepository) LockByCustomerTx(ctx context.Context, tx pgx.Tx, customerId string) (*entity.StoredValueAccount, error) {
+	query := "SELECT " + storedValueColumns + " FROM stored_value_accounts WHERE customer_id = $1 FOR UPDATE"
+
+	return s.collectOne(tx.Query(ctx, query, customerId))
+}
+
+// LockCreditTx locks the store credit of a customer, opening it on first use.
+func (s *storedValueRepository) LockCreditTx(ctx context.Context, tx pgx.Tx, customerId string, staffId string) (*entity.StoredValueAccount, error) {
+	insert := `
+		INSERT INTO stored_value_accounts (type, customer_id, created_by)
+		VALUES ('credit', $1, NULLIF($2, '')::UUID)
+		ON CONFLICT (customer_id) DO NOTHING`
+
+	if _, err := tx.Exec(ctx, insert, customerId, staffId); err != nil {
+		return nil, err
+	}
+
+	query := "SELECT " + storedValueColumns + " FROM stored_value_accounts WHERE customer_id = $1 FOR UPDATE"
+
+	return s.collectOne(tx.Query(ctx, query, customerId))
+}
+
+func (s *storedValueRepository) collectOne(rows pgx.Rows, err error) (*entity.StoredValueAccount, error) {
+	if err != nil {
+		return nil, err
+	}
+
+	account, err := pgx.CollectOneRow(rows, pgx.RowToStructByPos[entity.StoredValueAccount])
+	if err != nil {
+		return nil, err
+	}
+
+	return &account, nil
+}
+
+// MoveTx adds movement.Amount to the balance of a locked account and records
+// the movement. A movement taking the balance below zero is refused with
+// ErrInsufficientBalance.
+func (s *storedValueRepository) MoveTx(ctx context.Context, tx pgx.Tx, account *entity.StoredValueAccount, movement *entity.StoredValueMovement) error {
+	if account.Balance+movement.Amount < 0 {
+		return ErrInsufficientBalance
+	}
+
+	update := "UPDATE stored_value_accounts SET balance = balance + $2 WHERE id = $1 RETURNING balance"
+	if err := tx.QueryRow(ctx, update, account.Id, movement.Amount).Scan(&account.Balance); err != nil {
+		return err
+	}
+
+	movement.AccountId = account.Id
+	movement.BalanceAfter = account.Balance
+
+	query := `
+		INSERT INTO stored_value_movements (account_id, type, amount, balance_after, transaction_id, staff_id, note)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id, created_at`
+
+	return tx.QueryRow(ctx, query, movement.AccountId, movement.Type, movement.Amount, movement.BalanceAfter, movement.TransactionId, movement.StaffId, movement.Note).
+		Scan(&movement.Id, &movement.CreatedAt)
+}
+
+func (s *storedValueRepository) FindMovements(ctx context.Context, pool *pgxpool.Pool, accountId string, params *entity.MovementQueryParams) ([]entity.StoredValueMovement, *entity.PageMeta, error) {
+	query := "SELECT " + movementColumns + " FROM stored_value_movements WHERE account_id = @accountId"
+	args := pgx.NamedArgs{"accountId": accountId}
+
+	keys, sort, err := createdAtKeys("created_at", "id", params.CreatedAt, params.Cursor)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	rows, err := pool.Query(ctx, pageQuery(query, keys, &params.PageParams, args), args)
+	if err != nil {
+		panic(err)
+	}
+
+	movements, err := pgx.CollectRows(rows, pgx.RowToStructByPos[entity.StoredValueMovement])
+	if err != nil {
+		panic(err)
+	}
+
+	movements, meta := paginate(movements, &params.PageParams, func(movement entity.StoredValueMovement) entity.Cursor {
+		return entity.Cursor{Sort: sort, CreatedAt: movement.CreatedAt, Id: movement.Id}
+	})
+
+	if params.WithTotal {
+		if meta.Total, err = countRows(ctx, pool, "SELECT COUNT(*) FROM stored_value_movements WHERE account_id = @accountId", args); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return movements, meta, nil
+}
+
+// FindAllMovements returns every movement of an account, oldest first.
+func (s *storedValueRepository) FindAllMovements(ctx context.Context, pool *pgxpool.Pool, accountId string) []entity.StoredValueMovement {
+	query := "SELECT " + movementColumns + " FROM stored_value_movements WHERE account_id = $1 ORDER BY created_at ASC, id ASC"
+
+	rows, err := pool.Query(ctx, query, accountId)
+	if err != nil {
+		panic(err)
+	}
+
+	movements, err := pgx.CollectRows(rows, pgx.RowToStructByPos[entity.StoredValueMovement])
+	if err != nil {
+		panic(err)
+	}
+
+	return movements
+}
+
+func (s *storedValueRepository) FindByTransactionTx(ctx context.Context, tx pgx.Tx, transactionId string) ([]entity.StoredValueMovement, error) {
+	query := "SELECT " + movementColumns + " FROM stored_value_movements WHERE transaction_id = $1 ORDER BY created_at ASC, id ASC"
+
+	rows, err := tx.Query(ctx, query, transactionId)
+	if err != nil {
+		return nil, err
+	}
+
+	return pgx.CollectRows(rows, pgx.RowToStructByPos[entity.StoredValueMovement])
+}
@@ -11,8 +11,11 @@ import (
 type TransactionRepository interface {
 	Create(ctx context.Context, tx pgx.Tx, payload *entity.TransactionInsertRequest) string
 	InsertDetail(ctx context.Context, tx pgx.Tx, transactionId string, payload []entity.ProductDetail)
-	DecrementStock(ctx context.Context, tx pgx.Tx, payload []entity.ProductDetail)
-	FindMany(ctx context.Context, pool *pgxpool.Pool, payload *entity.TransactionQueryParams) []entity.Transaction
+	FindMany(ctx context.Context, pool *pgxpool.Pool, payload *entity.TransactionQueryParams) ([]entity.Transaction, *entity.PageMeta, error)
+	FindByCustomer(ctx context.Context, pool *pgxpool.Pool, customerId string) []entity.Transaction
+	LockOneTx(ctx context.Context, tx pgx.Tx, ID string) (*entity.Transaction, error)
+	FindStockLinesTx(ctx context.Context, tx pgx.Tx, ID string) ([]entity.ProductDetail, error)
+	RefundTx(ctx context.Context, tx pgx.Tx, ID string, staffId string) error
 }
 
 type transactionRepository struct{}
@@ -23,9 +26,11 @@ func NewTransactionRepository() TransactionRepository {
 
 func (t *transactionRepository) Create(ctx context.Context, tx pgx.Tx, payload *entity.TransactionInsertRequest) string {
 	var id string
-	query := "INSERT INTO transactions (customer_id, paid, change) VALUES ($1, $2, $3) RETURNING id"
+	query := `
+		INSERT INTO transactions (customer_id, location_id, paid, change, points_redeemed, points_discount, stored_value_paid, account_charged)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING id`
 
-	err := tx.QueryRow(ctx, query, payload.CustomerId, payload.Paid, payload.Change).Scan(&id)
+	err := tx.QueryRow(ctx, query, payload.CustomerId, payload.LocationId, payload.Paid, payload.Change, payload.RedeemPoints, payload.PointsDiscount, payload.StoredValuePaid, payload.AccountCharged).Scan(&id)
 	if err != nil {
 		panic(err)
 	}
@@ -34,63 +39,161 @@ func (t *transactionRepository) Create(ctx context.Context, tx pgx.Tx, payload *
 }
 
 func (t *transactionRepository) InsertDetail(ctx context.Context, tx pgx.Tx, transactionId string, payload []entity.ProductDetail) {
-	query := "INSERT INTO transaction_detail (transaction_id, product_id, quantity) VALUES ($1, $2, $3)"
+	// the cost is the average cost of the product at the time of sale, a
+	// bundle costs what its components cost
+	query := `
+		INSERT INTO transaction_detail (transaction_id, product_id, quantity, unit_price, total_price, unit_cost, bundle_id)
+		VALUES ($1, $2, $3, $4, $5, (
+			SELECT CASE WHEN p.is_bundle THEN
+				(SELECT ROUND(COALESCE(SUM(c.cost_price * b.quantity), 0))
+					FROM bundle_items b JOIN products c ON c.id = b.product_id
+					WHERE b.bundle_id = p.id)
+				ELSE p.cost_price END
+			FROM products p WHERE p.id = $2), $6)`
 
 	for _, pd := range payload {
-		_, err := tx.Exec(ctx, query, transactionId, pd.ProductId, pd.Quantity)
+		_, err := tx.Exec(ctx, query, transactionId, pd.ProductId, pd.Quantity, pd.Price, pd.TotalPrice, nil)
 		if err != nil {
 			panic(err)
 		}
-	}
-}
-
-func (t *transactionRepository) DecrementStock(ctx context.Context, tx pgx.Tx, payload []entity.ProductDetail) {
-	query := "UPDATE products SET stock = stock - $1 WHERE id = $2"
 
-	for _, pd := range payload {
-		_, err := tx.Exec(ctx, query, pd.Quantity, pd.ProductId)
-		if err != nil {
-			panic(err)
+		// component lines are kept for the receipt, the bundle line carries price and cost
+		for _, component := range pd.Components {
+			_, err := tx.Exec(ctx, query, transactionId, component.ProductId, component.Quantity, 0, 0, pd.ProductId)
+			if err != nil {
+				panic(err)
+			}
 		}
 	}
 }
 
-func (t *transactionRepository) FindMany(ctx context.Context, pool *pgxpool.Pool, params *entity.TransactionQueryParams) []entity.Transaction {
-	query := `
-		SELECT t.id, t.customer_id, t.paid, t.change, t.created_at, 
-			(SELECT JSON_AGG(json_build_object('productId', td.product_id, 'quantity', td.quantity)) 
+// transactionQuery reads transactions with their lines, the serials sold in
+// them and the components of their bundles.
+const transactionQuery = `
+		SELECT t.id, t.customer_id, COALESCE(t.location_id::TEXT, ''), t.paid, t.change, t.points_redeemed, t.points_discount,
+			t.stored_value_paid, t.account_charged, t.created_at, t.refunded_at,
+			(SELECT JSON_AGG(json_build_object('productId', td.product_id, 'quantity', td.quantity,
+				'serials', (SELECT JSON_AGG(s.serial ORDER BY s.serial)
+					FROM product_serials s
+					WHERE s.transaction_id = t.id AND s.product_id = td.product_id),
+				'components', (SELECT JSON_AGG(json_build_object('productId', c.product_id, 'quantity', c.quantity))
+					FROM transaction_detail c
+					WHERE c.transaction_id = t.id AND c.bundle_id = td.product_id)))
 				FROM transaction_detail td 
-				WHERE td.transaction_id = t.id) AS pd_details 
+				WHERE td.transaction_id = t.id AND td.bundle_id IS NULL) AS pd_details 
 		FROM transactions AS t WHERE 1=1`
 
+func (t *transactionRepository) FindMany(ctx context.Context, pool *pgxpool.Pool, params *entity.TransactionQueryParams) ([]entity.Transaction, *entity.PageMeta, error) {
+	query := transactionQuery
+	where := ""
 	args := pgx.NamedArgs{}
 
 	if params.CustomerId != "" {
-		query += " AND t.customer_id = @customerId"
+		where += " AND t.customer_id = @customerId"
 		args["customerId"] = params.CustomerId
 	}
 
-	if params.CreatedAt != "" {
-		query += " ORDER BY t.created_at " + params.CreatedAt
-	} else {
-		query += " ORDER BY t.created_at desc"
+	if params.LocationId != "" {
+		where += " AND t.location_id = @locationId"
+		args["locationId"] = params.LocationId
+	}
+
+	keys, sort, err := createdAtKeys("t.created_at", "t.id", params.CreatedAt, params.Cursor)
+	if err != nil {
+		return nil, nil, err
 	}
 
-	query += " LIMIT @limit OFFSET @offset"
-	args["limit"] = params.Limit
-	args["offset"] = params.Offset
+	query = pageQuery(query+where, keys, &params.PageParams, args)
 
 	rows, err := pool.Query(ctx, query, args)
 	if err != nil {
 		panic(err)
 	}
 
+	transactions := t.scan(rows)
+
+	transactions, meta := paginate(transactions, &params.PageParams, func(transaction entity.Transaction) entity.Cursor {
+		return entity.Cursor{Sort: sort, CreatedAt: *transaction.CreatedAt, Id: transaction.Id}
+	})
+
+	if params.WithTotal {
+		if meta.Total, err = countRows(ctx, pool, "SELECT COUNT(*) FROM transactions AS t WHERE 1=1"+where, args); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return transactions, meta, nil
+}
+
+// FindByCustomer returns every transaction of a customer, oldest first.
+func (t *transactionRepository) FindByCustomer(ctx context.Context, pool *pgxpool.Pool, customerId string) []entity.Transaction {
+	rows, err := pool.Query(ctx, transactionQuery+" AND t.customer_id = $1 ORDER BY t.created_at ASC, t.id ASC", customerId)
+	if err != nil {
+		panic(err)
+	}
+
+	return t.scan(rows)
+}
+
+func (t *transactionRepository) scan(rows pgx.Rows) []entity.Transaction {
+	defer rows.Close()
+
 	var transactions []entity.Transaction = []entity.Transaction{}
 	for rows.Next() {
 		transaction := &entity.Transaction{}
-		rows.Scan(&transaction.Id, &transaction.CustomerId, &transaction.Paid, &transaction.Change, &transaction.CreatedAt, &transaction.ProductDetails)
+		rows.Scan(&transaction.Id, &transaction.CustomerId, &transaction.LocationId, &transaction.Paid, &transaction.Change, &transaction.PointsRedeemed, &transaction.PointsDiscount,
+			&transaction.StoredValuePaid, &transaction.AccountCharged, &transaction.CreatedAt, &transaction.RefundedAt, &transaction.ProductDetails)
 		transactions = append(transactions, *transaction)
 	}
 
 	return transactions
 }
+
+// LockOneTx reads the head of a transaction and locks it, its lines are left out.
+func (t *transactionRepository) LockOneTx(ctx context.Context, tx pgx.Tx, ID string) (*entity.Transaction, error) {
+	transaction := &entity.Transaction{}
+	query := `
+		SELECT id, customer_id, COALESCE(location_id::TEXT, ''), paid, change, points_redeemed, points_discount, stored_value_paid,
+			account_charged, created_at, refunded_at
+		FROM transactions WHERE id = $1 FOR UPDATE`
+
+	err := tx.QueryRow(ctx, query, ID).Scan(&transaction.Id, &transaction.CustomerId, &transaction.LocationId, &transaction.Paid, &transaction.Change,
+		&transaction.PointsRedeemed, &transaction.PointsDiscount, &transaction.StoredValuePaid, &transaction.AccountCharged, &transaction.CreatedAt, &transaction.RefundedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return transaction, nil
+}
+
+// FindStockLinesTx returns the lines of a transaction that were taken from
+// stock, a bundle is listed through its component lines.
+func (t *transactionRepository) FindStockLinesTx(ctx context.Context, tx pgx.Tx, ID string) ([]entity.ProductDetail, error) {
+	query := `
+		SELECT td.product_id, td.quantity FROM transaction_detail td
+			JOIN products p ON p.id = td.product_id
+		WHERE td.transaction_id = $1 AND NOT p.is_bundle`
+
+	rows, err := tx.Query(ctx, query, ID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	lines := []entity.ProductDetail{}
+	for rows.Next() {
+		line := entity.ProductDetail{TransactionId: ID}
+		if err := rows.Scan(&line.ProductId, &line.Quantity); err != nil {
+			return nil, err
+		}
+		lines = append(lines, line)
+	}
+
+	return lines, rows.Err()
+}
+
+func (t *transactionRepository) RefundTx(ctx context.Context, tx pgx.Tx, ID string, staffId string) error {
+	_, err := tx.Exec(ctx, "UPDATE transactions SET refunded_at = NOW(), refunded_by = $2 WHERE id = $1", ID, staffId)
+
+	return err
+}
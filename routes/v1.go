@@ -7,12 +7,14 @@ import (
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/malikfajr/eq-store/controller"
 	"github.com/malikfajr/eq-store/middleware"
+	"github.com/malikfajr/eq-store/pkg"
 	"github.com/malikfajr/eq-store/repository"
 	"github.com/malikfajr/eq-store/service"
 )
 
 func NewRoutesV1(pool *pgxpool.Pool, validate *validator.Validate) *http.ServeMux {
 	Auth := middleware.Auth
+	Admin := middleware.Admin
 
 	r := http.NewServeMux()
 
@@ -23,29 +25,171 @@ func NewRoutesV1(pool *pgxpool.Pool, validate *validator.Validate) *http.ServeMu
 	r.HandleFunc("POST /staff/register", staffController.Register)
 	r.HandleFunc("POST /staff/login", staffController.Login)
 
+	locationRepository := repository.NewLocationRepository()
+	locationService := service.NewLocationService(pool, locationRepository)
+	locationController := controller.NewLocationController(validate, locationService)
+
+	r.Handle("POST /location", Auth(http.HandlerFunc(locationController.Create)))
+	r.Handle("GET /location", Auth(http.HandlerFunc(locationController.GetAll)))
+	r.Handle("PUT /location/{id}", Auth(http.HandlerFunc(locationController.Update)))
+	r.Handle("DELETE /location/{id}", Auth(http.HandlerFunc(locationController.Delete)))
+
+	categoryRepository := repository.NewCategoryRepository()
+	categoryService := service.NewCategoryService(pool, categoryRepository)
+	categoryController := controller.NewCategoryController(validate, categoryService)
+
+	r.Handle("POST /category", Auth(Admin(http.HandlerFunc(categoryController.Create))))
+	r.Handle("GET /category", Auth(http.HandlerFunc(categoryController.GetAll)))
+	r.Handle("GET /category/{id}", Auth(http.HandlerFunc(categoryController.GetOne)))
+	r.Handle("PUT /category/{id}", Auth(Admin(http.HandlerFunc(categoryController.Update))))
+	r.Handle("DELETE /category/{id}", Auth(Admin(http.HandlerFunc(categoryController.Delete))))
+
 	productRepository := repository.NewProductRepository()
-	productService := service.NewProductService(pool, productRepository)
+	stockRepository := repository.NewStockRepository()
+	lotRepository := repository.NewLotRepository()
+	serialRepository := repository.NewSerialRepository()
+	priceRepository := repository.NewPriceRepository()
+	bundleRepository := repository.NewBundleRepository()
+	productService := service.NewProductService(pool, productRepository, stockRepository, locationRepository, categoryRepository, priceRepository, bundleRepository, serialRepository)
 	productController := controller.NewProductController(productService, validate)
 
 	r.Handle("POST /product", Auth(http.HandlerFunc(productController.Create)))
 	r.Handle("GET /product", Auth(http.HandlerFunc(productController.GetAll)))
+	r.Handle("GET /product/search", Auth(http.HandlerFunc(productController.Search)))
+	r.Handle("GET /product/search/suggest", Auth(http.HandlerFunc(productController.Suggest)))
+	r.Handle("GET /product/{id}", Auth(http.HandlerFunc(productController.GetOne)))
 	r.Handle("PUT /product/{id}", Auth(http.HandlerFunc(productController.Update)))
+	r.Handle("PATCH /product/{id}", Auth(http.HandlerFunc(productController.Patch)))
 	r.Handle("DELETE /product/{id}", Auth(http.HandlerFunc(productController.Delete)))
+	r.Handle("GET /product/deleted", Auth(http.HandlerFunc(productController.GetDeleted)))
+	r.Handle("POST /product/{id}/restore", Auth(http.HandlerFunc(productController.Restore)))
+	r.Handle("DELETE /product/deleted/{id}", Auth(Admin(http.HandlerFunc(productController.Purge))))
+	r.Handle("GET /product/{id}/stock", Auth(http.HandlerFunc(productController.GetStock)))
+	r.Handle("PUT /product/{id}/stock", Auth(http.HandlerFunc(productController.UpdateStock)))
+	r.Handle("GET /product/{id}/variant", Auth(http.HandlerFunc(productController.GetVariants)))
+	r.Handle("POST /product/{id}/variant", Auth(http.HandlerFunc(productController.CreateVariant)))
+	r.Handle("PUT /product/{id}/variant/{variantId}", Auth(http.HandlerFunc(productController.UpdateVariant)))
+	r.Handle("DELETE /product/{id}/variant/{variantId}", Auth(http.HandlerFunc(productController.DeleteVariant)))
+	r.Handle("GET /product/{id}/components", Auth(http.HandlerFunc(productController.GetComponents)))
+	r.Handle("PUT /product/{id}/components", Auth(http.HandlerFunc(productController.UpdateComponents)))
 
 	r.Handle("GET /product/customer", http.HandlerFunc(productController.FindSku))
 
+	priceService := service.NewPriceService(pool, productRepository, priceRepository)
+	priceController := controller.NewPriceController(validate, priceService)
+
+	r.Handle("GET /product/{id}/price-history", Auth(http.HandlerFunc(priceController.GetHistory)))
+	r.Handle("POST /product/{id}/price-schedule", Auth(http.HandlerFunc(priceController.CreateSchedule)))
+	r.Handle("GET /product/{id}/price-schedule", Auth(http.HandlerFunc(priceController.GetSchedules)))
+	r.Handle("DELETE /product/{id}/price-schedule/{scheduleId}", Auth(http.HandlerFunc(priceController.CancelSchedule)))
+
+	imageRepository := repository.NewImageRepository()
+	imageService := service.NewImageService(pool, pkg.NewStorage(), productRepository, imageRepository)
+	imageController := controller.NewImageController(imageService)
+
+	r.Handle("POST /product/{id}/image", Auth(http.HandlerFunc(imageController.Upload)))
+	r.HandleFunc("GET /image/{key...}", imageController.Serve)
+
 	customerRepoitory := repository.NewCustomerRepository()
-	customerService := service.NewCustomerService(pool, customerRepoitory)
+	transactionRepository := repository.NewTransactionRepository()
+	loyaltyRepository := repository.NewLoyaltyRepository()
+	storedValueRepository := repository.NewStoredValueRepository()
+	segmentRepository := repository.NewSegmentRepository()
+	creditAccountRepository := repository.NewCreditAccountRepository()
+	customerService := service.NewCustomerService(pool, customerRepoitory, transactionRepository, loyaltyRepository, storedValueRepository, segmentRepository, creditAccountRepository)
 	customerController := controller.NewCustomerController(validate, customerService)
 
 	r.Handle("POST /customer/register", Auth(http.HandlerFunc(customerController.Create)))
 	r.Handle("GET /customer", Auth(http.HandlerFunc(customerController.GetAll)))
+	r.Handle("GET /customer/{id}", Auth(http.HandlerFunc(customerController.GetOne)))
+	r.Handle("PUT /customer/{id}", Auth(http.HandlerFunc(customerController.Update)))
+	r.Handle("PATCH /customer/{id}", Auth(http.HandlerFunc(customerController.Patch)))
+	r.Handle("DELETE /customer/{id}", Auth(http.HandlerFunc(customerController.Delete)))
+	r.Handle("GET /customer/{id}/transactions", Auth(http.HandlerFunc(customerController.GetTransactions)))
+	r.Handle("GET /customer/{id}/summary", Auth(http.HandlerFunc(customerController.GetSummary)))
+	r.Handle("GET /customer/duplicates", Auth(http.HandlerFunc(customerController.GetDuplicates)))
+	r.Handle("POST /customer/{id}/merge", Auth(Admin(http.HandlerFunc(customerController.Merge))))
+	r.Handle("GET /customer/{id}/export", Auth(Admin(http.HandlerFunc(customerController.Export))))
+	r.Handle("POST /customer/{id}/erase", Auth(Admin(http.HandlerFunc(customerController.Erase))))
+	r.Handle("GET /customer/{id}/tags", Auth(http.HandlerFunc(customerController.GetTags)))
+	r.Handle("PUT /customer/{id}/tags", Auth(http.HandlerFunc(customerController.SetTags)))
 
-	transactionRepository := repository.NewTransactionRepository()
-	transactionService := service.NewTransactionService(pool, customerRepoitory, productRepository, transactionRepository)
+	segmentService := service.NewSegmentService(pool, segmentRepository, customerRepoitory, categoryRepository)
+	segmentController := controller.NewSegmentController(validate, segmentService)
+
+	r.Handle("POST /segment", Auth(http.HandlerFunc(segmentController.Create)))
+	r.Handle("GET /segment", Auth(http.HandlerFunc(segmentController.GetAll)))
+	r.Handle("GET /segment/{id}", Auth(http.HandlerFunc(segmentController.GetOne)))
+	r.Handle("PUT /segment/{id}", Auth(http.HandlerFunc(segmentController.Update)))
+	r.Handle("DELETE /segment/{id}", Auth(http.HandlerFunc(segmentController.Delete)))
+	r.Handle("GET /segment/{id}/members", Auth(http.HandlerFunc(segmentController.GetMembers)))
+
+	loyaltyService := service.NewLoyaltyService(pool, customerRepoitory, categoryRepository, loyaltyRepository)
+	loyaltyController := controller.NewLoyaltyController(validate, loyaltyService)
+
+	r.Handle("GET /customer/{id}/points", Auth(http.HandlerFunc(loyaltyController.GetPoints)))
+	r.Handle("GET /loyalty/settings", Auth(http.HandlerFunc(loyaltyController.GetSettings)))
+	r.Handle("PUT /loyalty/settings", Auth(Admin(http.HandlerFunc(loyaltyController.UpdateSettings))))
+
+	storedValueService := service.NewStoredValueService(pool, customerRepoitory, storedValueRepository)
+	storedValueController := controller.NewStoredValueController(validate, storedValueService)
+
+	r.Handle("POST /gift-card", Auth(Admin(http.HandlerFunc(storedValueController.IssueGiftCard))))
+	r.Handle("GET /gift-card/{code}", Auth(http.HandlerFunc(storedValueController.GetGiftCard)))
+	r.Handle("POST /gift-card/{code}/top-up", Auth(Admin(http.HandlerFunc(storedValueController.TopUpGiftCard))))
+	r.Handle("GET /customer/{id}/credit", Auth(http.HandlerFunc(storedValueController.GetCredit)))
+	r.Handle("POST /customer/{id}/credit", Auth(Admin(http.HandlerFunc(storedValueController.AddCredit))))
+
+	creditAccountService := service.NewCreditAccountService(pool, customerRepoitory, creditAccountRepository)
+	creditAccountController := controller.NewCreditAccountController(validate, creditAccountService)
+
+	r.Handle("GET /customer/{id}/credit-account", Auth(http.HandlerFunc(creditAccountController.GetOne)))
+	r.Handle("PUT /customer/{id}/credit-account", Auth(Admin(http.HandlerFunc(creditAccountController.Save))))
+	r.Handle("POST /customer/{id}/credit-account/payment", Auth(http.HandlerFunc(creditAccountController.Pay)))
+	r.Handle("GET /customer/{id}/credit-account/statement", Auth(http.HandlerFunc(creditAccountController.GetStatement)))
+	r.Handle("GET /report/credit-aging", Auth(http.HandlerFunc(creditAccountController.Aging)))
+
+	transactionService := service.NewTransactionService(pool, customerRepoitory, productRepository, transactionRepository, locationRepository, stockRepository, bundleRepository, lotRepository, serialRepository, loyaltyRepository, storedValueRepository, creditAccountRepository)
 	transactionController := controller.NewTransactionController(validate, transactionService)
 
 	r.Handle("POST /product/checkout", Auth(http.HandlerFunc(transactionController.Create)))
 	r.Handle("GET /product/checkout/history", Auth(http.HandlerFunc(transactionController.GetAll)))
+	r.Handle("POST /product/checkout/{id}/refund", Auth(Admin(http.HandlerFunc(transactionController.Refund))))
+
+	stockTransferRepository := repository.NewStockTransferRepository()
+	stockTransferService := service.NewStockTransferService(pool, locationRepository, productRepository, stockRepository, stockTransferRepository, lotRepository, serialRepository)
+	stockTransferController := controller.NewStockTransferController(validate, stockTransferService)
+
+	r.Handle("POST /transfer", Auth(http.HandlerFunc(stockTransferController.Send)))
+	r.Handle("GET /transfer", Auth(http.HandlerFunc(stockTransferController.GetAll)))
+	r.Handle("GET /transfer/{id}", Auth(http.HandlerFunc(stockTransferController.GetOne)))
+	r.Handle("POST /transfer/{id}/receive", Auth(http.HandlerFunc(stockTransferController.Receive)))
+	r.Handle("POST /transfer/{id}/cancel", Auth(http.HandlerFunc(stockTransferController.Cancel)))
+
+	goodsReceiptRepository := repository.NewGoodsReceiptRepository()
+	goodsReceiptService := service.NewGoodsReceiptService(pool, locationRepository, productRepository, stockRepository, goodsReceiptRepository, lotRepository, serialRepository)
+	goodsReceiptController := controller.NewGoodsReceiptController(validate, goodsReceiptService)
+
+	r.Handle("POST /goods-receipt", Auth(http.HandlerFunc(goodsReceiptController.Create)))
+	r.Handle("GET /goods-receipt", Auth(http.HandlerFunc(goodsReceiptController.GetAll)))
+	r.Handle("GET /goods-receipt/{id}", Auth(http.HandlerFunc(goodsReceiptController.GetOne)))
+
+	lotService := service.NewLotService(pool, productRepository, locationRepository, lotRepository)
+	lotController := controller.NewLotController(validate, lotService)
+
+	r.Handle("GET /lot/expiring", Auth(http.HandlerFunc(lotController.GetExpiring)))
+	r.Handle("GET /product/{id}/lots", Auth(http.HandlerFunc(lotController.GetByProduct)))
+
+	serialService := service.NewSerialService(pool, serialRepository)
+	serialController := controller.NewSerialController(serialService)
+
+	r.Handle("GET /serial/{serial}", Auth(http.HandlerFunc(serialController.GetOne)))
+
+	reportRepository := repository.NewReportRepository()
+	reportService := service.NewReportService(pool, locationRepository, reportRepository)
+	reportController := controller.NewReportController(validate, reportService)
+
+	r.Handle("GET /report/margin", Auth(http.HandlerFunc(reportController.Margin)))
+	r.Handle("GET /report/inventory-valuation", Auth(http.HandlerFunc(reportController.InventoryValuation)))
 	return r
 }
@@ -0,0 +1,115 @@
+package service
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/malikfajr/eq-store/entity"
+	"github.com/malikfajr/eq-store/exception"
+	"github.com/malikfajr/eq-store/repository"
+)
+
+type CategoryService interface {
+	Create(ctx context.Context, req *entity.CategoryInsertUpdateRequest) (*entity.Category, error)
+	FindAll(ctx context.Context, page *entity.PageParams) ([]entity.Category, *entity.PageMeta, error)
+	FindOne(ctx context.Context, ID string) (*entity.Category, error)
+	Update(ctx context.Context, ID string, req *entity.CategoryInsertUpdateRequest) (*entity.Category, error)
+	Delete(ctx context.Context, ID string) error
+}
+
+type categoryService struct {
+	pool               *pgxpool.Pool
+	categoryRepository repository.CategoryRepository
+}
+
+func NewCategoryService(pool *pgxpool.Pool, categoryRepository repository.CategoryRepository) CategoryService {
+	return &categoryService{
+		pool:               pool,
+		categoryRepository: categoryRepository,
+	}
+}
+
+func (c *categoryService) Create(ctx context.Context, req *entity.CategoryInsertUpdateRequest) (*entity.Category, error) {
+	if req.ParentId != nil && !c.categoryRepository.IsExist(ctx, c.pool, *req.ParentId) {
+		return nil, exception.NewNotFound("parent category id not found")
+	}
+
+	category := &entity.Category{
+		Name:     req.Name,
+		ParentId: req.ParentId,
+	}
+
+	if err := c.categoryRepository.Insert(ctx, c.pool, category); err != nil {
+		if isUniqueViolation(err) {
+			return nil, exception.NewConflict("category name already exist")
+		}
+		return nil, err
+	}
+
+	return category, nil
+}
+
+func (c *categoryService) FindAll(ctx context.Context, page *entity.PageParams) ([]entity.Category, *entity.PageMeta, error) {
+	categories, meta, err := c.categoryRepository.FindAll(ctx, c.pool, page)
+	if err != nil {
+		return nil, nil, pageError(err)
+	}
+
+	return categories, meta, nil
+}
+
+func (c *categoryService) FindOne(ctx context.Context, ID string) (*entity.Category, error) {
+	category, err := c.categoryRepository.FindOne(ctx, c.pool, ID)
+	if err != nil {
+		return nil, exception.NewNotFound("category id not found")
+	}
+
+	return category, nil
+}
+
+// Update renames or moves a category, products follow the new name through
+// the foreign key.
+func (c *categoryService) Update(ctx context.Context, ID string, req *entity.CategoryInsertUpdateRequest) (*entity.Category, error) {
+	category, err := c.categoryRepository.FindOne(ctx, c.pool, ID)
+	if err != nil {
+		return nil, exception.NewNotFound("category id not found")
+	}
+
+	if req.ParentId != nil {
+		if !c.categoryRepository.IsExist(ctx, c.pool, *req.ParentId) {
+			return nil, exception.NewNotFound("parent category id not found")
+		}
+
+		if c.categoryRepository.IsDescendant(ctx, c.pool, *req.ParentId, ID) {
+			return nil, exception.NewBadRequest("category can not be moved under itself")
+		}
+	}
+
+	category.Name = req.Name
+	category.ParentId = req.ParentId
+
+	if err := c.categoryRepository.Update(ctx, c.pool, category); err != nil {
+		if isUniqueViolation(err) {
+			return nil, exception.NewConflict("category name already exist")
+		}
+		return nil, err
+	}
+
+	return category, nil
+}
+
+func (c *categoryService) Delete(ctx context.Context, ID string) error {
+	if !c.categoryRepository.IsExist(ctx, c.pool, ID) {
+		return exception.NewNotFound("category id not found")
+	}
+
+	if c.categoryRepository.IsUsed(ctx, c.pool, ID) {
+		return exception.NewConflict("category still has products or subcategories")
+	}
+
+	if err := c.categoryRepository.Delete(ctx, c.pool, ID); err != nil {
+		return exception.NewNotFound("category id not found")
+	}
+
+	return nil
+}
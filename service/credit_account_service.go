@@ -0,0 +1,223 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/malikfajr/eq-store/entity"
+	"github.com/malikfajr/eq-store/exception"
+	"github.com/malikfajr/eq-store/repository"
+)
+
+type CreditAccountService interface {
+	Save(ctx context.Context, staffId string, customerId string, body *entity.CreditAccountRequest) (*entity.CreditAccount, error)
+	FindOne(ctx context.Context, customerId string, params *entity.AccountQueryParams) (*entity.CreditAccount, *entity.PageMeta, error)
+	Pay(ctx context.Context, staffId string, customerId string, body *entity.AccountPaymentRequest) (*entity.CreditAccount, error)
+	Statement(ctx context.Context, customerId string, month string) (*entity.CreditStatement, error)
+	Aging(ctx context.Context, asOf time.Time) *entity.CreditAgingReport
+}
+
+type creditAccountService struct {
+	pool                    *pgxpool.Pool
+	customerRepository      repository.CustomerRepository
+	creditAccountRepository repository.CreditAccountRepository
+}
+
+func NewCreditAccountService(pool *pgxpool.Pool, customerRepository repository.CustomerRepository, creditAccountRepository repository.CreditAccountRepository) CreditAccountService {
+	return &creditAccountService{
+		pool:                    pool,
+		customerRepository:      customerRepository,
+		creditAccountRepository: creditAccountRepository,
+	}
+}
+
+// Save opens a credit account for a customer or changes its limit. A limit
+// below the balance only stops new charges.
+func (c *creditAccountService) Save(ctx context.Context, staffId string, customerId string, body *entity.CreditAccountRequest) (*entity.CreditAccount, error) {
+	if _, err := c.customerRepository.FindOne(ctx, c.pool, customerId); err != nil {
+		return nil, exception.NewNotFound("customer not found")
+	}
+
+	account := &entity.CreditAccount{CustomerId: customerId, CreditLimit: *body.CreditLimit}
+	if err := c.creditAccountRepository.Save(ctx, c.pool, account, staffId); err != nil {
+		return nil, err
+	}
+
+	account.Available = availableCredit(account)
+	return account, nil
+}
+
+// FindOne returns the account of a customer with the aging of its balance and
+// a page of its entries, newest first.
+func (c *creditAccountService) FindOne(ctx context.Context, customerId string, params *entity.AccountQueryParams) (*entity.CreditAccount, *entity.PageMeta, error) {
+	account, err := c.findAccount(ctx, customerId)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	entries, meta, err := c.creditAccountRepository.FindEntries(ctx, c.pool, customerId, params)
+	if err != nil {
+		return nil, nil, pageError(err)
+	}
+
+	aging := c.customerAging(ctx, customerId, time.Now().UTC())
+
+	account.Available = availableCredit(account)
+	account.Aging = &aging
+	account.Entries = entries
+	return account, meta, nil
+}
+
+// Pay records a payment against the balance of an account, paying more than
+// is owed is refused.
+func (c *creditAccountService) Pay(ctx context.Context, staffId string, customerId string, body *entity.AccountPaymentRequest) (*entity.CreditAccount, error) {
+	var account *entity.CreditAccount
+
+	err := runInTx(ctx, c.pool, func(tx pgx.Tx) error {
+		var err error
+		account, err = c.creditAccountRepository.LockOneTx(ctx, tx, customerId)
+		if err != nil {
+			return exception.NewNotFound("credit account not found")
+		}
+
+		entry := &entity.AccountEntry{
+			Type:    entity.AccountPayment,
+			Amount:  -body.Amount,
+			StaffId: &staffId,
+			Note:    body.Note,
+		}
+
+		err = c.creditAccountRepository.MoveTx(ctx, tx, account, entry)
+		if errors.Is(err, repository.ErrOverpaid) {
+			return exception.NewBadRequest("payment is more than the balance")
+		}
+		if err != nil {
+			return err
+		}
+
+		account.Entries = []entity.AccountEntry{*entry}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	account.Available = availableCredit(account)
+	return account, nil
+}
+
+// Statement lists the entries of an account over a calendar month, given as
+// YYYY-MM, with its opening and closing balance and the aging at its end.
+func (c *creditAccountService) Statement(ctx context.Context, customerId string, month string) (*entity.CreditStatement, error) {
+	from, err := time.Parse("2006-01", month)
+	if err != nil {
+		return nil, exception.NewBadRequest("month must be formatted as YYYY-MM")
+	}
+	to := from.AddDate(0, 1, 0)
+
+	customer, err := c.customerRepository.FindOne(ctx, c.pool, customerId)
+	if err != nil {
+		return nil, exception.NewNotFound("customer not found")
+	}
+
+	account, err := c.creditAccountRepository.FindOne(ctx, c.pool, customerId)
+	if err != nil {
+		return nil, exception.NewNotFound("credit account not found")
+	}
+
+	statement := &entity.CreditStatement{
+		CustomerId:     customerId,
+		Name:           customer.Name,
+		Month:          from.Format("2006-01"),
+		From:           from,
+		To:             to,
+		CreditLimit:    account.CreditLimit,
+		OpeningBalance: c.creditAccountRepository.BalanceAt(ctx, c.pool, customerId, from),
+		Entries:        c.creditAccountRepository.FindEntriesBetween(ctx, c.pool, customerId, from, to),
+		Aging:          c.customerAging(ctx, customerId, to),
+	}
+
+	statement.ClosingBalance = statement.OpeningBalance
+	for _, entry := range statement.Entries {
+		switch entry.Type {
+		case entity.AccountCharge:
+			statement.Charges += entry.Amount
+		case entity.AccountPayment:
+			statement.Payments -= entry.Amount
+		case entity.AccountRefund:
+			statement.Refunds -= entry.Amount
+		}
+		statement.ClosingBalance = entry.BalanceAfter
+	}
+
+	return statement, nil
+}
+
+// Aging reports the balances owed at asOf split by the age of their charges:
+// 0-30, 31-60 and over 60 days.
+func (c *creditAccountService) Aging(ctx context.Context, asOf time.Time) *entity.CreditAgingReport {
+	report := &entity.CreditAgingReport{
+		AsOf:      asOf,
+		Customers: c.creditAccountRepository.Aging(ctx, c.pool, asOf, ""),
+	}
+
+	for _, aging := range report.Customers {
+		report.Balance += aging.Balance
+		report.Days0To30 += aging.Days0To30
+		report.Days31To60 += aging.Days31To60
+		report.DaysOver60 += aging.DaysOver60
+	}
+
+	return report
+}
+
+func (c *creditAccountService) findAccount(ctx context.Context, customerId string) (*entity.CreditAccount, error) {
+	if _, err := c.customerRepository.FindOne(ctx, c.pool, customerId); err != nil {
+		return nil, exception.NewNotFound("customer not found")
+	}
+
+	account, err := c.creditAccountRepository.FindOne(ctx, c.pool, customerId)
+	if err != nil {
+		return nil, exception.NewNotFound("credit account not found")
+	}
+
+	return account, nil
+}
+
+// customerAging returns the aging of one account, an account owing nothing
+// has empty buckets.
+func (c *creditAccountService) customerAging(ctx context.Context, customerId string, asOf time.Time) entity.CreditAging {
+	if aging := c.creditAccountRepository.Aging(ctx, c.pool, asOf, customerId); len(aging) > 0 {
+		return aging[0]
+	}
+
+	return entity.CreditAging{CustomerId: customerId}
+}
+
+func availableCredit(account *entity.CreditAccount) int {
+	return max(account.CreditLimit-account.Balance, 0)
+}
+
+// chargeAccountTx puts what a checkout left unpaid on the customer's credit
+// account, the limit is checked again under the lock.
+func chargeAccountTx(ctx context.Context, tx pgx.Tx, creditAccountRepository repository.CreditAccountRepository, transactionId string, payload *entity.TransactionInsertRequest) error {
+	if payload.AccountCharged == 0 {
+		return nil
+	}
+
+	account, err := creditAccountRepository.LockOneTx(ctx, tx, payload.CustomerId)
+	if err != nil {
+		return exception.NewBadRequest("customer has no credit account")
+	}
+
+	if payload.AccountCharged > availableCredit(account) {
+		return exception.NewBadRequest("charge is over the credit limit")
+	}
+
+	entry := &entity.AccountEntry{Type: entity.AccountCharge, Amount: payload.AccountCharged, TransactionId: &transactionId}
+
+	return creditAccountRepository.MoveTx(ctx, tx, account, entry)
+}
@@ -2,35 +2,67 @@ package service
 
 import (
 	"context"
+	"errors"
+	"slices"
+	"strings"
+	"time"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/malikfajr/eq-store/entity"
 	"github.com/malikfajr/eq-store/exception"
+	"github.com/malikfajr/eq-store/pkg"
 	"github.com/malikfajr/eq-store/repository"
 )
 
 type CustomerService interface {
 	Create(ctx context.Context, customer *entity.CustomerInsertUpdateRequest) (*entity.Customer, error)
-	FindMany(ctx context.Context, params *entity.CustomerQueryParams) *[]entity.Customer
+	FindMany(ctx context.Context, params *entity.CustomerQueryParams) (*[]entity.Customer, *entity.PageMeta, error)
 	IsExist(ctx context.Context, phoneNumber string) bool
+	FindOne(ctx context.Context, ID string) (*entity.Customer, error)
+	Update(ctx context.Context, ID string, body *entity.CustomerInsertUpdateRequest) (*entity.Customer, error)
+	Delete(ctx context.Context, ID string) error
+	FindTransactions(ctx context.Context, ID string, params *entity.TransactionQueryParams) (*[]entity.Transaction, *entity.PageMeta, error)
+	Summary(ctx context.Context, ID string) (*entity.CustomerSummary, error)
+	Merge(ctx context.Context, staffId string, ID string, body *entity.CustomerMergeRequest) ([]entity.CustomerMerge, error)
+	FindDuplicates(ctx context.Context, params *entity.DuplicateQueryParams) ([]entity.DuplicateCustomer, *entity.PageMeta, error)
+	Export(ctx context.Context, staffId string, ID string) (*entity.CustomerExport, error)
+	Erase(ctx context.Context, staffId string, ID string) error
+	FindTags(ctx context.Context, ID string) ([]string, error)
+	SetTags(ctx context.Context, ID string, body *entity.CustomerTagsRequest) ([]string, error)
 }
 
 type customerService struct {
-	pool               *pgxpool.Pool
-	customerRepository repository.CustomerRepository
+	pool                    *pgxpool.Pool
+	customerRepository      repository.CustomerRepository
+	transactionRepository   repository.TransactionRepository
+	loyaltyRepository       repository.LoyaltyRepository
+	storedValueRepository   repository.StoredValueRepository
+	segmentRepository       repository.SegmentRepository
+	creditAccountRepository repository.CreditAccountRepository
 }
 
-func NewCustomerService(pool *pgxpool.Pool, service repository.CustomerRepository) CustomerService {
+func NewCustomerService(pool *pgxpool.Pool, service repository.CustomerRepository, transactionRepository repository.TransactionRepository, loyaltyRepository repository.LoyaltyRepository, storedValueRepository repository.StoredValueRepository, segmentRepository repository.SegmentRepository, creditAccountRepository repository.CreditAccountRepository) CustomerService {
 	return &customerService{
-		pool:               pool,
-		customerRepository: service,
+		pool:                    pool,
+		customerRepository:      service,
+		transactionRepository:   transactionRepository,
+		loyaltyRepository:       loyaltyRepository,
+		storedValueRepository:   storedValueRepository,
+		segmentRepository:       segmentRepository,
+		creditAccountRepository: creditAccountRepository,
 	}
 }
 
 func (c *customerService) Create(ctx context.Context, body *entity.CustomerInsertUpdateRequest) (*entity.Customer, error) {
+	phoneNumber, err := pkg.NormalizePhoneNumber(body.PhoneNumber)
+	if err != nil {
+		return nil, exception.NewBadRequest("request doesn’t pass validation")
+	}
+
 	customer := &entity.Customer{
 		Name:        body.Name,
-		PhoneNumber: body.PhoneNumber,
+		PhoneNumber: phoneNumber,
 	}
 
 	id, err := c.customerRepository.Create(ctx, c.pool, customer)
@@ -42,12 +74,357 @@ func (c *customerService) Create(ctx context.Context, body *entity.CustomerInser
 	return customer, nil
 }
 
-func (c *customerService) FindMany(ctx context.Context, params *entity.CustomerQueryParams) *[]entity.Customer {
-	customers := c.customerRepository.FindMany(ctx, c.pool, params)
+func (c *customerService) FindMany(ctx context.Context, params *entity.CustomerQueryParams) (*[]entity.Customer, *entity.PageMeta, error) {
+	params.Tag = customerTag(params.Tag)
+
+	if params.SegmentId != "" {
+		segment, err := c.segmentRepository.FindOne(ctx, c.pool, params.SegmentId)
+		if err != nil {
+			return nil, nil, exception.NewNotFound("segment id not found")
+		}
+		params.Segment = &segment.Rules
+	}
 
-	return customers
+	customers, meta, err := c.customerRepository.FindMany(ctx, c.pool, params)
+	if err != nil {
+		return nil, nil, pageError(err)
+	}
+
+	return customers, meta, nil
 }
 
 func (c *customerService) IsExist(ctx context.Context, phoneNumber string) bool {
 	return c.customerRepository.IsExist(ctx, c.pool, phoneNumber)
 }
+
+func (c *customerService) FindOne(ctx context.Context, ID string) (*entity.Customer, error) {
+	customer, err := c.customerRepository.FindOne(ctx, c.pool, ID)
+	if err != nil {
+		return nil, exception.NewNotFound("customer id not found")
+	}
+
+	return customer, nil
+}
+
+func (c *customerService) Update(ctx context.Context, ID string, body *entity.CustomerInsertUpdateRequest) (*entity.Customer, error) {
+	customer, err := c.FindOne(ctx, ID)
+	if err != nil {
+		return nil, err
+	}
+
+	phoneNumber, err := pkg.NormalizePhoneNumber(body.PhoneNumber)
+	if err != nil {
+		return nil, exception.NewBadRequest("request doesn’t pass validation")
+	}
+
+	customer.Name = body.Name
+	customer.PhoneNumber = phoneNumber
+
+	if err := c.customerRepository.Update(ctx, c.pool, customer); err != nil {
+		return nil, exception.NewConflict("phone number already exist")
+	}
+
+	return customer, nil
+}
+
+// Delete removes a customer from the customer list, its transactions stay in
+// the sales history.
+func (c *customerService) Delete(ctx context.Context, ID string) error {
+	if err := c.customerRepository.Delete(ctx, c.pool, ID); err != nil {
+		return exception.NewNotFound("customer id not found")
+	}
+
+	return nil
+}
+
+func (c *customerService) FindTransactions(ctx context.Context, ID string, params *entity.TransactionQueryParams) (*[]entity.Transaction, *entity.PageMeta, error) {
+	if _, err := c.FindOne(ctx, ID); err != nil {
+		return nil, nil, err
+	}
+
+	params.CustomerId = ID
+
+	transactions, meta, err := c.transactionRepository.FindMany(ctx, c.pool, params)
+	if err != nil {
+		return nil, nil, pageError(err)
+	}
+
+	return &transactions, meta, nil
+}
+
+// favouriteCategoryLimit is how many categories a customer summary lists.
+const favouriteCategoryLimit = 3
+
+func (c *customerService) Summary(ctx context.Context, ID string) (*entity.CustomerSummary, error) {
+	if _, err := c.FindOne(ctx, ID); err != nil {
+		return nil, err
+	}
+
+	summary := c.customerRepository.Summary(ctx, c.pool, ID)
+	summary.FavouriteCategories = c.customerRepository.FavouriteCategories(ctx, c.pool, ID, favouriteCategoryLimit)
+
+	return summary, nil
+}
+
+// Merge folds duplicates into customer ID: their transactions, points and
+// store credit move to it, they are deleted and every merge is recorded. A
+// customer with a credit account can only be the one merged into, its
+// charges stay with it.
+func (c *customerService) Merge(ctx context.Context, staffId string, ID string, body *entity.CustomerMergeRequest) ([]entity.CustomerMerge, error) {
+	if slices.Contains(body.DuplicateIds, ID) {
+		return nil, exception.NewBadRequest("a customer can not be merged into itself")
+	}
+
+	ids := slices.Clone(body.DuplicateIds)
+	slices.Sort(ids)
+	if len(slices.Compact(ids)) != len(body.DuplicateIds) {
+		return nil, exception.NewBadRequest("duplicateIds must be unique")
+	}
+
+	merges := []entity.CustomerMerge{}
+
+	err := runInTx(ctx, c.pool, func(tx pgx.Tx) error {
+		customers, err := c.customerRepository.LockManyTx(ctx, tx, append([]string{ID}, body.DuplicateIds...))
+		if err != nil {
+			return err
+		}
+
+		if len(customers) != len(body.DuplicateIds)+1 {
+			return exception.NewNotFound("one of customer ids not found")
+		}
+
+		for _, duplicateId := range body.DuplicateIds {
+			if _, err := c.creditAccountRepository.LockOneTx(ctx, tx, duplicateId); err == nil {
+				return exception.NewConflict("one of duplicates has a credit account, merge into it instead")
+			}
+
+			merge := entity.CustomerMerge{SurvivorId: ID, DuplicateId: duplicateId, StaffId: &staffId}
+
+			if merge.Transactions, err = c.customerRepository.MoveTransactionsTx(ctx, tx, duplicateId, ID); err != nil {
+				return err
+			}
+
+			if merge.Points, err = c.movePointsTx(ctx, tx, duplicateId, ID); err != nil {
+				return err
+			}
+
+			if merge.Credit, err = c.moveCreditTx(ctx, tx, staffId, duplicateId, ID); err != nil {
+				return err
+			}
+
+			if err := c.customerRepository.MarkMergedTx(ctx, tx, duplicateId, ID); err != nil {
+				return err
+			}
+
+			if err := c.customerRepository.InsertMergeTx(ctx, tx, &merge); err != nil {
+				return err
+			}
+
+			merges = append(merges, merge)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return merges, nil
+}
+
+// movePointsTx moves the usable points of a customer to another, lot by lot
+// so they keep their expiry. The ledger is append only, every lot leaves with
+// a merge entry and arrives with another.
+func (c *customerService) movePointsTx(ctx context.Context, tx pgx.Tx, fromId string, toId string) (int, error) {
+	// Merge has locked both customers in id order already, this only takes
+	// the ledger locks the way every other writer does
+	for _, id := range []string{min(fromId, toId), max(fromId, toId)} {
+		if err := c.loyaltyRepository.LockCustomerTx(ctx, tx, id); err != nil {
+			return 0, err
+		}
+	}
+
+	entries, err := c.loyaltyRepository.FindEntriesTx(ctx, tx, fromId)
+	if err != nil {
+		return 0, err
+	}
+
+	moved := 0
+	for _, lot := range usableLots(pointLots(entries), time.Now()) {
+		out := &entity.PointEntry{CustomerId: fromId, Type: entity.PointsMerge, Points: -lot.Points, SourceId: &lot.EntryId}
+		if err := c.loyaltyRepository.InsertTx(ctx, tx, out); err != nil {
+			return 0, err
+		}
+
+		in := &entity.PointEntry{CustomerId: toId, Type: entity.PointsMerge, Points: lot.Points, SourceId: &out.Id, ExpiresAt: lot.ExpiresAt}
+		if err := c.loyaltyRepository.InsertTx(ctx, tx, in); err != nil {
+			return 0, err
+		}
+
+		moved += lot.Points
+	}
+
+	return moved, nil
+}
+
+// moveCreditTx moves the store credit balance of a customer to another.
+func (c *customerService) moveCreditTx(ctx context.Context, tx pgx.Tx, staffId string, fromId string, toId string) (int, error) {
+	from, err := c.storedValueRepository.LockByCustomerTx(ctx, tx, fromId)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	balance := from.Balance
+	if balance == 0 {
+		return 0, nil
+	}
+
+	out := &entity.StoredValueMovement{Type: entity.MovementMerge, Amount: -balance, StaffId: &staffId, Note: "merged into " + toId}
+	if err := c.storedValueRepository.MoveTx(ctx, tx, from, out); err != nil {
+		return 0, err
+	}
+
+	to, err := c.storedValueRepository.LockCreditTx(ctx, tx, toId, staffId)
+	if err != nil {
+		return 0, err
+	}
+
+	in := &entity.StoredValueMovement{Type: entity.MovementMerge, Amount: balance, StaffId: &staffId, Note: "merged from " + fromId}
+	if err := c.storedValueRepository.MoveTx(ctx, tx, to, in); err != nil {
+		return 0, err
+	}
+
+	return balance, nil
+}
+
+func (c *customerService) FindDuplicates(ctx context.Context, params *entity.DuplicateQueryParams) ([]entity.DuplicateCustomer, *entity.PageMeta, error) {
+	duplicates, meta, err := c.customerRepository.FindDuplicates(ctx, c.pool, params)
+	if err != nil {
+		return nil, nil, pageError(err)
+	}
+
+	return duplicates, meta, nil
+}
+
+// Export collects everything stored about customer ID, deleted and erased
+// customers included, and logs the export.
+func (c *customerService) Export(ctx context.Context, staffId string, ID string) (*entity.CustomerExport, error) {
+	export, err := c.customerRepository.FindForExport(ctx, c.pool, ID)
+	if err != nil {
+		return nil, exception.NewNotFound("customer not found")
+	}
+
+	export.Tags = c.customerRepository.FindTags(ctx, c.pool, ID)
+	export.Transactions = c.transactionRepository.FindByCustomer(ctx, c.pool, ID)
+	export.Points = c.loyaltyRepository.FindEntries(ctx, c.pool, ID)
+	export.Merges = c.customerRepository.FindMerges(ctx, c.pool, ID)
+
+	if account, err := c.storedValueRepository.FindByCustomer(ctx, c.pool, ID); err == nil {
+		account.Movements = c.storedValueRepository.FindAllMovements(ctx, c.pool, account.Id)
+		export.StoreCredit = account
+	}
+
+	if account, err := c.creditAccountRepository.FindOne(ctx, c.pool, ID); err == nil {
+		account.Available = availableCredit(account)
+		account.Entries = c.creditAccountRepository.FindAllEntries(ctx, c.pool, ID)
+		export.CreditAccount = account
+	}
+
+	err = runInTx(ctx, c.pool, func(tx pgx.Tx) error {
+		return c.customerRepository.InsertPrivacyLogTx(ctx, tx, ID, entity.PrivacyExport, staffId)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	export.ExportedAt = time.Now()
+	return export, nil
+}
+
+// Erase anonymizes customer ID. Its transactions, points and credit stay for
+// the books, they no longer point at a person. A store credit or credit
+// account balance must be settled first.
+func (c *customerService) Erase(ctx context.Context, staffId string, ID string) error {
+	export, err := c.customerRepository.FindForExport(ctx, c.pool, ID)
+	if err != nil {
+		return exception.NewNotFound("customer not found")
+	}
+
+	if export.ErasedAt != nil {
+		return exception.NewConflict("customer is already erased")
+	}
+
+	return runInTx(ctx, c.pool, func(tx pgx.Tx) error {
+		// the money of an erased customer could no longer be paid out or
+		// collected, it is settled first
+		credit, err := c.storedValueRepository.LockByCustomerTx(ctx, tx, ID)
+		if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+			return err
+		}
+		if err == nil && credit.Balance != 0 {
+			return exception.NewConflict("customer has a store credit balance, settle it before erasing")
+		}
+
+		account, err := c.creditAccountRepository.LockOneTx(ctx, tx, ID)
+		if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+			return err
+		}
+		if err == nil && account.Balance != 0 {
+			return exception.NewConflict("customer has a credit account balance, settle it before erasing")
+		}
+
+		if err := c.customerRepository.EraseTx(ctx, tx, ID); err != nil {
+			return err
+		}
+
+		return c.customerRepository.InsertPrivacyLogTx(ctx, tx, ID, entity.PrivacyErase, staffId)
+	})
+}
+
+func (c *customerService) FindTags(ctx context.Context, ID string) ([]string, error) {
+	if _, err := c.FindOne(ctx, ID); err != nil {
+		return nil, err
+	}
+
+	return c.customerRepository.FindTags(ctx, c.pool, ID), nil
+}
+
+// SetTags replaces the tags of a customer, an empty list removes them all.
+func (c *customerService) SetTags(ctx context.Context, ID string, body *entity.CustomerTagsRequest) ([]string, error) {
+	if _, err := c.FindOne(ctx, ID); err != nil {
+		return nil, err
+	}
+
+	tags := customerTags(body.Tags)
+
+	err := runInTx(ctx, c.pool, func(tx pgx.Tx) error {
+		return c.customerRepository.SetTagsTx(ctx, tx, ID, tags)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return tags, nil
+}
+
+// customerTag returns a tag the way it is stored, tags are case insensitive.
+func customerTag(tag string) string {
+	return strings.ToLower(strings.TrimSpace(tag))
+}
+
+// customerTags returns tags the way they are stored, sorted and without
+// duplicates.
+func customerTags(tags []string) []string {
+	stored := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		if tag = customerTag(tag); tag != "" {
+			stored = append(stored, tag)
+		}
+	}
+
+	slices.Sort(stored)
+	return slices.Compact(stored)
+}
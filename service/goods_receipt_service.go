@@ -0,0 +1,193 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/malikfajr/eq-store/entity"
+	"github.com/malikfajr/eq-store/exception"
+	"github.com/malikfajr/eq-store/repository"
+)
+
+type GoodsReceiptService interface {
+	Create(ctx context.Context, staffId string, req *entity.GoodsReceiptInsertRequest) (*entity.GoodsReceipt, error)
+	FindOne(ctx context.Context, ID string) (*entity.GoodsReceipt, error)
+	FindMany(ctx context.Context, params *entity.GoodsReceiptQueryParams) ([]entity.GoodsReceipt, *entity.PageMeta, error)
+}
+
+type goodsReceiptService struct {
+	pool                   *pgxpool.Pool
+	locationRepository     repository.LocationRepository
+	productRepository      repository.ProductRepository
+	stockRepository        repository.StockRepository
+	goodsReceiptRepository repository.GoodsReceiptRepository
+	lotRepository          repository.LotRepository
+	serialRepository       repository.SerialRepository
+}
+
+func NewGoodsReceiptService(pool *pgxpool.Pool, locationRepository repository.LocationRepository, productRepository repository.ProductRepository, stockRepository repository.StockRepository, goodsReceiptRepository repository.GoodsReceiptRepository, lotRepository repository.LotRepository, serialRepository repository.SerialRepository) GoodsReceiptService {
+	return &goodsReceiptService{
+		pool:                   pool,
+		locationRepository:     locationRepository,
+		productRepository:      productRepository,
+		stockRepository:        stockRepository,
+		goodsReceiptRepository: goodsReceiptRepository,
+		lotRepository:          lotRepository,
+		serialRepository:       serialRepository,
+	}
+}
+
+// Create books received goods into a location and updates the weighted
+// average cost of every received product. Items received in the purchase unit
+// of a product are converted to the unit it is sold in.
+func (g *goodsReceiptService) Create(ctx context.Context, staffId string, req *entity.GoodsReceiptInsertRequest) (*entity.GoodsReceipt, error) {
+	if req.LocationId == "" {
+		location, err := g.locationRepository.FindDefault(ctx, g.pool)
+		if err != nil {
+			return nil, exception.NewInternalServer(err.Error())
+		}
+		req.LocationId = location.Id
+	} else if !g.locationRepository.IsExist(ctx, g.pool, req.LocationId) {
+		return nil, exception.NewNotFound("location id not found")
+	}
+
+	today := time.Now().Format(time.DateOnly)
+
+	productIds := []string{}
+	seen := map[string]bool{}
+	for _, item := range req.Items {
+		if seen[item.ProductId] {
+			return nil, exception.NewBadRequest("productId is duplicated")
+		}
+		if item.ExpiresAt != nil && *item.ExpiresAt < today {
+			return nil, exception.NewBadRequest("expiresAt must not be in the past")
+		}
+		seen[item.ProductId] = true
+		productIds = append(productIds, item.ProductId)
+	}
+
+	products := g.productRepository.FindByIds(ctx, g.pool, productIds)
+	if len(*products) != len(productIds) {
+		return nil, exception.NewNotFound("one of productId not found")
+	}
+
+	for _, product := range *products {
+		if product.HasVariants() {
+			return nil, exception.NewBadRequest("one of product has variants, use the variant id")
+		}
+
+		if product.IsBundle {
+			return nil, exception.NewBadRequest("one of product is a bundle, receive its components")
+		}
+	}
+
+	for i := range req.Items {
+		item := &req.Items[i]
+		product := findProduct(*products, item.ProductId)
+
+		switch item.Unit {
+		case "", product.Unit:
+		case product.PurchaseUnit:
+			unitCost := product.PurchaseUnitFactor.PerUnit(*item.UnitCost)
+			item.Quantity = item.Quantity.Mul(product.PurchaseUnitFactor)
+			item.UnitCost = &unitCost
+		default:
+			return nil, exception.NewBadRequest("unit must be the product unit or its purchaseUnit")
+		}
+		item.Unit = ""
+
+		if err := checkQuantity(product, item.Quantity); err != nil {
+			return nil, err
+		}
+
+		if err := checkSerials(product, item.Quantity, item.Serials); err != nil {
+			return nil, err
+		}
+
+		if len(item.Serials) == 0 {
+			continue
+		}
+
+		if existing := g.serialRepository.FindExisting(ctx, g.pool, item.ProductId, item.Serials); len(existing) > 0 {
+			return nil, exception.NewConflict("serial " + existing[0] + " is already registered")
+		}
+	}
+
+	receipt := &entity.GoodsReceipt{
+		LocationId: req.LocationId,
+		Supplier:   req.Supplier,
+		Notes:      req.Notes,
+		Items:      req.Items,
+		ReceivedBy: staffId,
+	}
+
+	err := runInTx(ctx, g.pool, func(tx pgx.Tx) error {
+		if err := g.goodsReceiptRepository.CreateTx(ctx, tx, receipt); err != nil {
+			return err
+		}
+
+		if err := g.goodsReceiptRepository.InsertItemsTx(ctx, tx, receipt.Id, receipt.Items); err != nil {
+			return err
+		}
+
+		for _, item := range receipt.Items {
+			if err := g.productRepository.UpdateAverageCostTx(ctx, tx, item.ProductId, item.Quantity, *item.UnitCost); err != nil {
+				return err
+			}
+
+			if err := g.stockRepository.IncrementTx(ctx, tx, item.ProductId, receipt.LocationId, item.Quantity); err != nil {
+				return err
+			}
+
+			if len(item.Serials) > 0 {
+				if err := g.serialRepository.InsertTx(ctx, tx, item.ProductId, receipt.LocationId, receipt.Id, item.Serials); err != nil {
+					return err
+				}
+			}
+
+			// items with a lot number or expiry date are tracked as a lot
+			if item.LotNumber == "" && item.ExpiresAt == nil {
+				continue
+			}
+
+			lot := &entity.Lot{
+				ProductId:  item.ProductId,
+				LocationId: receipt.LocationId,
+				LotNumber:  item.LotNumber,
+				ExpiresAt:  item.ExpiresAt,
+				Quantity:   item.Quantity,
+				ReceiptId:  &receipt.Id,
+			}
+			if err := g.lotRepository.InsertTx(ctx, tx, lot); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return receipt, nil
+}
+
+func (g *goodsReceiptService) FindOne(ctx context.Context, ID string) (*entity.GoodsReceipt, error) {
+	receipt, err := g.goodsReceiptRepository.FindOne(ctx, g.pool, ID)
+	if err != nil {
+		return nil, exception.NewNotFound("goods receipt id not found")
+	}
+
+	return receipt, nil
+}
+
+func (g *goodsReceiptService) FindMany(ctx context.Context, params *entity.GoodsReceiptQueryParams) ([]entity.GoodsReceipt, *entity.PageMeta, error) {
+	receipts, meta, err := g.goodsReceiptRepository.FindMany(ctx, g.pool, params)
+	if err != nil {
+		return nil, nil, pageError(err)
+	}
+
+	return receipts, meta, nil
+}
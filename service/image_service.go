@@ -0,0 +1,171 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"io"
+	"log"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/malikfajr/eq-store/entity"
+	"github.com/malikfajr/eq-store/exception"
+	"github.com/malikfajr/eq-store/pkg"
+	"github.com/malikfajr/eq-store/repository"
+)
+
+// imagePrefix is the only part of the storage served back through the api.
+const imagePrefix = "products/"
+
+type ImageService interface {
+	Upload(ctx context.Context, staffId string, productId string, data []byte, version *int) (*entity.Product, error)
+	Open(ctx context.Context, key string) (io.ReadCloser, string, error)
+}
+
+type imageService struct {
+	pool              *pgxpool.Pool
+	storage           pkg.Storage
+	productRepository repository.ProductRepository
+	imageRepository   repository.ImageRepository
+}
+
+func NewImageService(pool *pgxpool.Pool, storage pkg.Storage, productRepository repository.ProductRepository, imageRepository repository.ImageRepository) ImageService {
+	return &imageService{
+		pool:              pool,
+		storage:           storage,
+		productRepository: productRepository,
+		imageRepository:   imageRepository,
+	}
+}
+
+// Upload stores data as the image of product productId together with a
+// thumbnail, and replaces the product imageUrl and thumbnailUrl with links
+// served by the api.
+func (i *imageService) Upload(ctx context.Context, staffId string, productId string, data []byte, version *int) (*entity.Product, error) {
+	product, err := i.productRepository.FindOne(ctx, i.pool, productId)
+	if err != nil {
+		return nil, exception.NewNotFound("product id not found")
+	}
+
+	if version != nil && *version != product.Version {
+		return nil, exception.NewPreconditionFailed("product has been changed, reload it and try again")
+	}
+
+	image, decoded, err := pkg.DecodeImage(data)
+	if errors.Is(err, pkg.ErrUnsupportedImage) {
+		return nil, exception.NewUnsupportedMediaType(err.Error())
+	}
+	if err != nil {
+		return nil, exception.NewBadRequest(err.Error())
+	}
+
+	thumbnail, err := pkg.Thumbnail(decoded, image.ContentType)
+	if err != nil {
+		return nil, exception.NewInternalServer(err.Error())
+	}
+
+	name, err := i.randomName()
+	if err != nil {
+		return nil, exception.NewInternalServer(err.Error())
+	}
+
+	record := &entity.ProductImage{
+		ProductId:    productId,
+		ImageKey:     imagePrefix + productId + "/" + name + image.Extension,
+		ThumbnailKey: imagePrefix + productId + "/" + name + "_thumb" + thumbnail.Extension,
+		ContentType:  image.ContentType,
+		Size:         len(data),
+		Width:        image.Width,
+		Height:       image.Height,
+	}
+	if staffId != "" {
+		record.UploadedBy = &staffId
+	}
+
+	if err := i.storage.Put(ctx, record.ImageKey, image.Data, image.ContentType); err != nil {
+		return nil, exception.NewInternalServer(err.Error())
+	}
+
+	if err := i.storage.Put(ctx, record.ThumbnailKey, thumbnail.Data, thumbnail.ContentType); err != nil {
+		i.remove(record.ImageKey)
+		return nil, exception.NewInternalServer(err.Error())
+	}
+
+	var previous *entity.ProductImage
+
+	err = runInTx(ctx, i.pool, func(tx pgx.Tx) error {
+		var err error
+		if previous, err = i.imageRepository.LockOneTx(ctx, tx, productId); err != nil {
+			return err
+		}
+
+		if err := i.imageRepository.UpsertTx(ctx, tx, record); err != nil {
+			return err
+		}
+
+		product.ImageUrl = i.url(record.ImageKey)
+		product.ThumbnailUrl = i.url(record.ThumbnailKey)
+		product.Version, err = i.productRepository.SetImageTx(ctx, tx, productId, product.ImageUrl, product.ThumbnailUrl, product.Version)
+
+		return err
+	})
+	if err != nil {
+		i.remove(record.ImageKey, record.ThumbnailKey)
+
+		if errors.Is(err, repository.ErrVersionConflict) {
+			return nil, exception.NewPreconditionFailed("product has been changed, reload it and try again")
+		}
+
+		return nil, exception.NewInternalServer(err.Error())
+	}
+
+	// the replaced objects are no longer referenced once the update committed
+	if previous != nil {
+		i.remove(previous.ImageKey, previous.ThumbnailKey)
+	}
+
+	return product, nil
+}
+
+func (i *imageService) Open(ctx context.Context, key string) (io.ReadCloser, string, error) {
+	if !strings.HasPrefix(key, imagePrefix) {
+		return nil, "", exception.NewNotFound("image not found")
+	}
+
+	body, contentType, err := i.storage.Get(ctx, key)
+	if errors.Is(err, pkg.ErrObjectNotFound) {
+		return nil, "", exception.NewNotFound("image not found")
+	}
+	if err != nil {
+		return nil, "", exception.NewInternalServer(err.Error())
+	}
+
+	return body, contentType, nil
+}
+
+func (i *imageService) url(key string) string {
+	return pkg.PUBLIC_URL + "/v1/image/" + key
+}
+
+// randomName makes every upload a new key, so served images can be cached forever.
+func (i *imageService) randomName() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(b), nil
+}
+
+// remove deletes objects on a best effort basis, a leftover object only
+// wastes space.
+func (i *imageService) remove(keys ...string) {
+	for _, key := range keys {
+		if err := i.storage.Delete(context.Background(), key); err != nil {
+			log.Println("image: delete", key, err)
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package service
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/malikfajr/eq-store/entity"
+	"github.com/malikfajr/eq-store/exception"
+	"github.com/malikfajr/eq-store/repository"
+)
+
+type LocationService interface {
+	Create(ctx context.Context, req *entity.LocationInsertUpdateRequest) (*entity.Location, error)
+	FindAll(ctx context.Context, page *entity.PageParams) ([]entity.Location, *entity.PageMeta, error)
+	Update(ctx context.Context, ID string, req *entity.LocationInsertUpdateRequest) (*entity.Location, error)
+	Delete(ctx context.Context, ID string) error
+}
+
+type locationService struct {
+	pool               *pgxpool.Pool
+	locationRepository repository.LocationRepository
+}
+
+func NewLocationService(pool *pgxpool.Pool, locationRepository repository.LocationRepository) LocationService {
+	return &locationService{
+		pool:               pool,
+		locationRepository: locationRepository,
+	}
+}
+
+func (l *locationService) Create(ctx context.Context, req *entity.LocationInsertUpdateRequest) (*entity.Location, error) {
+	location := &entity.Location{
+		Name:      req.Name,
+		Address:   req.Address,
+		IsDefault: req.IsDefault,
+	}
+
+	err := runInTx(ctx, l.pool, func(tx pgx.Tx) error {
+		if location.IsDefault {
+			if err := l.locationRepository.ClearDefaultTx(ctx, tx); err != nil {
+				return err
+			}
+		}
+
+		if err := l.locationRepository.Insert(ctx, tx, location); err != nil {
+			return exception.NewConflict("location name already exist")
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return location, nil
+}
+
+func (l *locationService) FindAll(ctx context.Context, page *entity.PageParams) ([]entity.Location, *entity.PageMeta, error) {
+	locations, meta, err := l.locationRepository.FindAll(ctx, l.pool, page)
+	if err != nil {
+		return nil, nil, pageError(err)
+	}
+
+	return locations, meta, nil
+}
+
+func (l *locationService) Update(ctx context.Context, ID string, req *entity.LocationInsertUpdateRequest) (*entity.Location, error) {
+	location, err := l.locationRepository.FindOne(ctx, l.pool, ID)
+	if err != nil {
+		return nil, exception.NewNotFound("location id not found")
+	}
+
+	if location.IsDefault && !req.IsDefault {
+		return nil, exception.NewBadRequest("set another location as default instead")
+	}
+
+	location.Name = req.Name
+	location.Address = req.Address
+	location.IsDefault = req.IsDefault
+
+	err = runInTx(ctx, l.pool, func(tx pgx.Tx) error {
+		if location.IsDefault {
+			if err := l.locationRepository.ClearDefaultTx(ctx, tx); err != nil {
+				return err
+			}
+		}
+
+		if err := l.locationRepository.UpdateTx(ctx, tx, location); err != nil {
+			return exception.NewConflict("location name already exist")
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return location, nil
+}
+
+func (l *locationService) Delete(ctx context.Context, ID string) error {
+	location, err := l.locationRepository.FindOne(ctx, l.pool, ID)
+	if err != nil {
+		return exception.NewNotFound("location id not found")
+	}
+
+	if location.IsDefault {
+		return exception.NewBadRequest("default location can not be deleted")
+	}
+
+	if l.locationRepository.HasStock(ctx, l.pool, ID) {
+		return exception.NewBadRequest("location still has stock")
+	}
+
+	if err := l.locationRepository.Delete(ctx, l.pool, ID); err != nil {
+		return exception.NewNotFound("location id not found")
+	}
+
+	return nil
+}
@@ -0,0 +1,63 @@
+package service
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/malikfajr/eq-store/entity"
+	"github.com/malikfajr/eq-store/exception"
+	"github.com/malikfajr/eq-store/repository"
+)
+
+type LotService interface {
+	FindByProduct(ctx context.Context, productId string) ([]entity.Lot, error)
+	FindExpiring(ctx context.Context, params *entity.ExpiringLotQueryParams) ([]entity.Lot, *entity.PageMeta, error)
+}
+
+type lotService struct {
+	pool               *pgxpool.Pool
+	productRepository  repository.ProductRepository
+	locationRepository repository.LocationRepository
+	lotRepository      repository.LotRepository
+}
+
+func NewLotService(pool *pgxpool.Pool, productRepository repository.ProductRepository, locationRepository repository.LocationRepository, lotRepository repository.LotRepository) LotService {
+	return &lotService{
+		pool:               pool,
+		productRepository:  productRepository,
+		locationRepository: locationRepository,
+		lotRepository:      lotRepository,
+	}
+}
+
+func (l *lotService) FindByProduct(ctx context.Context, productId string) ([]entity.Lot, error) {
+	if !l.productRepository.IsExists(ctx, l.pool, productId) {
+		return nil, exception.NewNotFound("product id not found")
+	}
+
+	return l.lotRepository.FindByProduct(ctx, l.pool, productId), nil
+}
+
+func (l *lotService) FindExpiring(ctx context.Context, params *entity.ExpiringLotQueryParams) ([]entity.Lot, *entity.PageMeta, error) {
+	if params.LocationId != "" && !l.locationRepository.IsExist(ctx, l.pool, params.LocationId) {
+		return nil, nil, exception.NewNotFound("location id not found")
+	}
+
+	lots, meta, err := l.lotRepository.FindExpiring(ctx, l.pool, params)
+	if err != nil {
+		return nil, nil, pageError(err)
+	}
+
+	return lots, meta, nil
+}
+
+// takeStockTx removes quantity from the stock at a location and from its
+// lots, first expiry first out. Expired lots are never taken.
+func takeStockTx(ctx context.Context, tx pgx.Tx, stockRepository repository.StockRepository, lotRepository repository.LotRepository, productId string, locationId string, quantity entity.Quantity) ([]entity.LotAllocation, error) {
+	if err := stockRepository.DecrementTx(ctx, tx, productId, locationId, quantity); err != nil {
+		return nil, err
+	}
+
+	return lotRepository.ConsumeTx(ctx, tx, productId, locationId, quantity)
+}
@@ -0,0 +1,320 @@
+package service
+
+import (
+	"context"
+	"log"
+	"slices"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/malikfajr/eq-store/entity"
+	"github.com/malikfajr/eq-store/exception"
+	"github.com/malikfajr/eq-store/repository"
+)
+
+type LoyaltyService interface {
+	GetSettings(ctx context.Context) *entity.LoyaltySettings
+	UpdateSettings(ctx context.Context, body *entity.LoyaltySettings) (*entity.LoyaltySettings, error)
+	FindPoints(ctx context.Context, customerId string, params *entity.PointQueryParams) (*entity.CustomerPoints, *entity.PageMeta, error)
+	ExpireDue(ctx context.Context) (int, error)
+	RunExpiry(ctx context.Context, interval time.Duration)
+}
+
+type loyaltyService struct {
+	pool               *pgxpool.Pool
+	customerRepository repository.CustomerRepository
+	categoryRepository repository.CategoryRepository
+	loyaltyRepository  repository.LoyaltyRepository
+}
+
+func NewLoyaltyService(pool *pgxpool.Pool, customerRepository repository.CustomerRepository, categoryRepository repository.CategoryRepository, loyaltyRepository repository.LoyaltyRepository) LoyaltyService {
+	return &loyaltyService{
+		pool:               pool,
+		customerRepository: customerRepository,
+		categoryRepository: categoryRepository,
+		loyaltyRepository:  loyaltyRepository,
+	}
+}
+
+func (l *loyaltyService) GetSettings(ctx context.Context) *entity.LoyaltySettings {
+	return l.loyaltyRepository.FindSettings(ctx, l.pool)
+}
+
+func (l *loyaltyService) UpdateSettings(ctx context.Context, body *entity.LoyaltySettings) (*entity.LoyaltySettings, error) {
+	seen := map[string]bool{}
+	for _, rate := range body.CategoryRates {
+		if seen[rate.Category] {
+			return nil, exception.NewBadRequest("category rates must be unique")
+		}
+		seen[rate.Category] = true
+
+		if !l.categoryRepository.IsExistByName(ctx, l.pool, rate.Category) {
+			return nil, exception.NewNotFound("category not found")
+		}
+	}
+
+	if body.CategoryRates == nil {
+		body.CategoryRates = []entity.LoyaltyCategoryRate{}
+	}
+
+	err := runInTx(ctx, l.pool, func(tx pgx.Tx) error {
+		return l.loyaltyRepository.UpdateSettingsTx(ctx, tx, body)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return body, nil
+}
+
+func (l *loyaltyService) FindPoints(ctx context.Context, customerId string, params *entity.PointQueryParams) (*entity.CustomerPoints, *entity.PageMeta, error) {
+	if _, err := l.customerRepository.FindOne(ctx, l.pool, customerId); err != nil {
+		return nil, nil, exception.NewNotFound("customer not found")
+	}
+
+	entries, meta, err := l.loyaltyRepository.FindPage(ctx, l.pool, customerId, params)
+	if err != nil {
+		return nil, nil, pageError(err)
+	}
+
+	now := time.Now()
+	settings := l.loyaltyRepository.FindSettings(ctx, l.pool)
+	lots := usableLots(pointLots(l.loyaltyRepository.FindEntries(ctx, l.pool, customerId)), now)
+
+	points := &entity.CustomerPoints{
+		CustomerId: customerId,
+		Balance:    pointBalance(lots),
+		Lots:       lots,
+		Entries:    entries,
+	}
+	points.Value = points.Balance * settings.PointValue
+
+	return points, meta, nil
+}
+
+// pointExpiryBatch is how many customers ExpireDue handles per round.
+const pointExpiryBatch = 100
+
+// ExpireDue closes the lots that expired with an expire entry taking out
+// what was left of them, and returns how many customers were handled.
+func (l *loyaltyService) ExpireDue(ctx context.Context) (int, error) {
+	total := 0
+
+	for {
+		customers := l.loyaltyRepository.FindExpiredCustomers(ctx, l.pool, pointExpiryBatch)
+
+		for _, customerId := range customers {
+			err := runInTx(ctx, l.pool, func(tx pgx.Tx) error {
+				return l.expireTx(ctx, tx, customerId)
+			})
+			if err != nil {
+				return total, err
+			}
+		}
+
+		total += len(customers)
+		if len(customers) < pointExpiryBatch {
+			return total, nil
+		}
+	}
+}
+
+func (l *loyaltyService) expireTx(ctx context.Context, tx pgx.Tx, customerId string) error {
+	if err := l.loyaltyRepository.LockCustomerTx(ctx, tx, customerId); err != nil {
+		return err
+	}
+
+	entries, err := l.loyaltyRepository.FindEntriesTx(ctx, tx, customerId)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for _, lot := range pointLots(entries) {
+		if !lot.Expired(now) {
+			continue
+		}
+
+		entry := &entity.PointEntry{
+			CustomerId: customerId,
+			Type:       entity.PointsExpire,
+			Points:     -lot.Points,
+			SourceId:   &lot.EntryId,
+		}
+
+		if err := l.loyaltyRepository.InsertTx(ctx, tx, entry); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// pointsExpiryLock is the advisory lock key of the points expiry job.
+const pointsExpiryLock int64 = 4627002
+
+// RunExpiry calls ExpireDue every interval until ctx is done. With several
+// servers running, only the one holding the advisory lock expires points.
+func (l *loyaltyService) RunExpiry(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		l.tick(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (l *loyaltyService) tick(ctx context.Context) {
+	defer func() {
+		if err := recover(); err != nil {
+			log.Println("points expiry:", err)
+		}
+	}()
+
+	var n int
+	_, err := runExclusive(ctx, l.pool, pointsExpiryLock, func() (err error) {
+		n, err = l.ExpireDue(ctx)
+		return err
+	})
+	if err != nil {
+		log.Println("points expiry:", err)
+		return
+	}
+
+	if n > 0 {
+		log.Println("points expiry: handled", n, "customers")
+	}
+}
+
+// pointLots replays a ledger, oldest entry first, and returns what is left of
+// every lot not closed by an expire entry. A negative entry takes from the
+// lot it names first, the rest comes from the lots still usable at that time,
+// the first to expire first.
+func pointLots(entries []entity.PointEntry) []entity.PointLot {
+	lots := []*entity.PointLot{}
+	closed := map[string]bool{}
+
+	for _, entry := range entries {
+		if entry.Points > 0 {
+			lots = append(lots, &entity.PointLot{EntryId: entry.Id, Points: entry.Points, ExpiresAt: entry.ExpiresAt})
+			continue
+		}
+
+		need := -entry.Points
+
+		if entry.SourceId != nil {
+			for _, lot := range lots {
+				if lot.EntryId == *entry.SourceId {
+					taken := min(lot.Points, need)
+					lot.Points -= taken
+					need -= taken
+				}
+			}
+
+			if entry.Type == entity.PointsExpire {
+				closed[*entry.SourceId] = true
+			}
+		}
+
+		for _, lot := range byExpiry(lots) {
+			if need == 0 {
+				break
+			}
+
+			if lot.Expired(entry.CreatedAt) {
+				continue
+			}
+
+			taken := min(lot.Points, need)
+			lot.Points -= taken
+			need -= taken
+		}
+	}
+
+	result := []entity.PointLot{}
+	for _, lot := range lots {
+		if !closed[lot.EntryId] {
+			result = append(result, *lot)
+		}
+	}
+
+	return result
+}
+
+// byExpiry orders lots by expiry, lots that never expire come last.
+func byExpiry(lots []*entity.PointLot) []*entity.PointLot {
+	sorted := slices.Clone(lots)
+
+	slices.SortStableFunc(sorted, func(a, b *entity.PointLot) int {
+		switch {
+		case a.ExpiresAt == nil && b.ExpiresAt == nil:
+			return 0
+		case a.ExpiresAt == nil:
+			return 1
+		case b.ExpiresAt == nil:
+			return -1
+		}
+
+		return a.ExpiresAt.Compare(*b.ExpiresAt)
+	})
+
+	return sorted
+}
+
+// usableLots returns the lots holding points that can still be spent at t.
+func usableLots(lots []entity.PointLot, t time.Time) []entity.PointLot {
+	usable := []entity.PointLot{}
+	for _, lot := range lots {
+		if lot.Points > 0 && !lot.Expired(t) {
+			usable = append(usable, lot)
+		}
+	}
+
+	return usable
+}
+
+func pointBalance(lots []entity.PointLot) int {
+	balance := 0
+	for _, lot := range lots {
+		balance += lot.Points
+	}
+
+	return balance
+}
+
+// pointsExpiry returns when points earned at t expire, nil when they never do.
+func pointsExpiry(settings *entity.LoyaltySettings, t time.Time) *time.Time {
+	if settings.ExpiryDays == 0 {
+		return nil
+	}
+
+	expiresAt := t.AddDate(0, 0, settings.ExpiryDays)
+	return &expiresAt
+}
+
+// earnedPoints returns the points a checkout earns. Every line earns at the
+// rate of its category, and points redeemed in the checkout shrink what the
+// lines earn by the share of the total they paid for.
+func earnedPoints(settings *entity.LoyaltySettings, products []entity.Product, details []entity.ProductDetail, totalPrice int, discount int) int {
+	if totalPrice <= 0 {
+		return 0
+	}
+
+	// millionths of a point, so a rate does not round away small lines
+	var micro int64
+	for _, pd := range details {
+		rate := settings.EarnRate(findProduct(products, pd.ProductId).Category)
+		if rate > 0 {
+			micro += int64(pd.TotalPrice) * 1_000_000 / int64(rate)
+		}
+	}
+
+	return int(micro * int64(totalPrice-discount) / int64(totalPrice) / 1_000_000)
+}
@@ -0,0 +1,18 @@
+package service
+
+import (
+	"errors"
+
+	"github.com/malikfajr/eq-store/exception"
+	"github.com/malikfajr/eq-store/repository"
+)
+
+// pageError turns a rejected cursor into a bad request, anything else is
+// returned as is.
+func pageError(err error) error {
+	if errors.Is(err, repository.ErrInvalidCursor) {
+		return exception.NewBadRequest("invalid cursor")
+	}
+
+	return err
+}
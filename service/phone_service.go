@@ -0,0 +1,131 @@
+package service
+
+import (
+	"context"
+	"log"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/malikfajr/eq-store/entity"
+	"github.com/malikfajr/eq-store/pkg"
+	"github.com/malikfajr/eq-store/repository"
+)
+
+type PhoneService interface {
+	NormalizeAll(ctx context.Context) error
+}
+
+type phoneService struct {
+	pool            *pgxpool.Pool
+	phoneRepository repository.PhoneRepository
+}
+
+func NewPhoneService(pool *pgxpool.Pool, phoneRepo repository.PhoneRepository) PhoneService {
+	return &phoneService{
+		pool:            pool,
+		phoneRepository: phoneRepo,
+	}
+}
+
+// phoneNormalizeLock keeps servers starting together from normalizing the
+// same numbers twice.
+const phoneNormalizeLock int64 = 4627003
+
+// NormalizeAll brings the staff and customer phone numbers that are not in
+// E.164 yet to E.164, the way the API reads numbers. Numbers that are not
+// valid, or that another row already got, are listed in
+// phone_number_conflicts and kept as they are. Numbers already handled are
+// skipped, so it runs on every start.
+func (p *phoneService) NormalizeAll(ctx context.Context) error {
+	var n int
+	_, err := runExclusive(ctx, p.pool, phoneNormalizeLock, func() error {
+		for _, table := range []string{repository.PhoneTableStaffs, repository.PhoneTableCustomers} {
+			err := runInTx(ctx, p.pool, func(tx pgx.Tx) error {
+				count, err := p.normalizeTx(ctx, tx, table)
+				n += count
+				return err
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if n > 0 {
+		log.Println("phone numbers: normalized", n, "numbers")
+	}
+
+	return nil
+}
+
+func (p *phoneService) normalizeTx(ctx context.Context, tx pgx.Tx, table string) (int, error) {
+	rows, err := p.phoneRepository.FindUnnormalizedTx(ctx, tx, table)
+	if err != nil || len(rows) == 0 {
+		return 0, err
+	}
+
+	normalized := map[string]string{}
+	numbers := []string{}
+	for _, row := range rows {
+		number, err := normalizeStoredPhone(row.PhoneNumber)
+		if err != nil {
+			conflict := &entity.PhoneNumberConflict{TableName: table, RowId: row.Id, PhoneNumber: row.PhoneNumber}
+			if err := p.phoneRepository.InsertConflictTx(ctx, tx, conflict); err != nil {
+				return 0, err
+			}
+			continue
+		}
+
+		normalized[row.Id] = number
+		numbers = append(numbers, number)
+	}
+
+	// a row already in E.164 keeps its number, else the oldest row gets it
+	holders, err := p.phoneRepository.FindHoldersTx(ctx, tx, table, numbers)
+	if err != nil {
+		return 0, err
+	}
+
+	n := 0
+	for _, row := range rows {
+		number, ok := normalized[row.Id]
+		if !ok {
+			continue
+		}
+
+		if row.IsActive {
+			if holder, taken := holders[number]; taken {
+				conflict := &entity.PhoneNumberConflict{TableName: table, RowId: row.Id, PhoneNumber: row.PhoneNumber, Normalized: &number, DuplicateOf: &holder}
+				if err := p.phoneRepository.InsertConflictTx(ctx, tx, conflict); err != nil {
+					return 0, err
+				}
+				continue
+			}
+			holders[number] = row.Id
+		}
+
+		if err := p.phoneRepository.UpdateTx(ctx, tx, table, row.Id, number); err != nil {
+			return 0, err
+		}
+		n++
+	}
+
+	return n, nil
+}
+
+// normalizeStoredPhone reads a stored number like the API does. Numbers were
+// only checked for a leading "+", so "+0812..." is read as "0812...".
+func normalizeStoredPhone(phone string) (string, error) {
+	number, err := pkg.NormalizePhoneNumber(phone)
+	if err != nil && strings.HasPrefix(phone, "+0") {
+		return pkg.NormalizePhoneNumber(phone[1:])
+	}
+
+	return number, err
+}
@@ -0,0 +1,32 @@
+package service
+
+import "testing"
+
+func TestNormalizeStoredPhone(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    string
+		wantErr bool
+	}{
+		{in: "+6281234567890", want: "+6281234567890"},
+		{in: "+62 812-3456-7890", want: "+6281234567890"},
+		{in: "+081234567890", want: "+6281234567890"},
+		{in: "081234567890", want: "+6281234567890"},
+		{in: "+12345", wantErr: true},
+		{in: "+0812", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := normalizeStoredPhone(tt.in)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("normalizeStoredPhone(%q) = %q, want an error", tt.in, got)
+			}
+			continue
+		}
+
+		if err != nil || got != tt.want {
+			t.Errorf("normalizeStoredPhone(%q) = %q, %v, want %q", tt.in, got, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,242 @@
+package service
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/malikfajr/eq-store/entity"
+	"github.com/malikfajr/eq-store/exception"
+	"github.com/malikfajr/eq-store/repository"
+)
+
+// priceScheduleBatch is the number of due schedules handled per transaction.
+const priceScheduleBatch = 100
+
+type PriceService interface {
+	GetHistory(ctx context.Context, productId string, params *entity.PriceHistoryQueryParams) ([]entity.PriceHistory, *entity.PageMeta, error)
+	CreateSchedule(ctx context.Context, staffId string, productId string, req *entity.PriceScheduleInsertRequest) (*entity.PriceSchedule, error)
+	GetSchedules(ctx context.Context, productId string) ([]entity.PriceSchedule, error)
+	CancelSchedule(ctx context.Context, productId string, scheduleId string) error
+	RunDue(ctx context.Context) (int, error)
+	RunScheduler(ctx context.Context, interval time.Duration)
+}
+
+type priceService struct {
+	pool              *pgxpool.Pool
+	productRepository repository.ProductRepository
+	priceRepository   repository.PriceRepository
+}
+
+func NewPriceService(pool *pgxpool.Pool, productRepository repository.ProductRepository, priceRepository repository.PriceRepository) PriceService {
+	return &priceService{
+		pool:              pool,
+		productRepository: productRepository,
+		priceRepository:   priceRepository,
+	}
+}
+
+func (p *priceService) GetHistory(ctx context.Context, productId string, params *entity.PriceHistoryQueryParams) ([]entity.PriceHistory, *entity.PageMeta, error) {
+	if !p.productRepository.IsExists(ctx, p.pool, productId) {
+		return nil, nil, exception.NewNotFound("product id not found")
+	}
+
+	history, meta, err := p.priceRepository.FindHistory(ctx, p.pool, productId, params)
+	if err != nil {
+		return nil, nil, pageError(err)
+	}
+
+	return history, meta, nil
+}
+
+func (p *priceService) CreateSchedule(ctx context.Context, staffId string, productId string, req *entity.PriceScheduleInsertRequest) (*entity.PriceSchedule, error) {
+	if !p.productRepository.IsExists(ctx, p.pool, productId) {
+		return nil, exception.NewNotFound("product id not found")
+	}
+
+	if !req.StartsAt.After(time.Now()) {
+		return nil, exception.NewBadRequest("startsAt must be in the future")
+	}
+
+	schedule := &entity.PriceSchedule{
+		ProductId: productId,
+		Price:     req.Price,
+		StartsAt:  req.StartsAt.UTC(),
+		CreatedBy: &staffId,
+	}
+
+	if req.EndsAt != nil {
+		endsAt := req.EndsAt.UTC()
+		schedule.EndsAt = &endsAt
+	}
+
+	if p.priceRepository.IsOverlapping(ctx, p.pool, productId, schedule.StartsAt, schedule.EndsAt) {
+		return nil, exception.NewConflict("product already has a price schedule at that time")
+	}
+
+	if err := p.priceRepository.CreateSchedule(ctx, p.pool, schedule); err != nil {
+		panic(err)
+	}
+
+	return schedule, nil
+}
+
+func (p *priceService) GetSchedules(ctx context.Context, productId string) ([]entity.PriceSchedule, error) {
+	if !p.productRepository.IsExists(ctx, p.pool, productId) {
+		return nil, exception.NewNotFound("product id not found")
+	}
+
+	return p.priceRepository.FindSchedules(ctx, p.pool, productId), nil
+}
+
+// CancelSchedule cancels a schedule that has not started yet.
+func (p *priceService) CancelSchedule(ctx context.Context, productId string, scheduleId string) error {
+	schedule, err := p.priceRepository.FindSchedule(ctx, p.pool, scheduleId)
+	if err != nil || schedule.ProductId != productId {
+		return exception.NewNotFound("schedule id not found")
+	}
+
+	if err := p.priceRepository.CancelSchedule(ctx, p.pool, scheduleId); err != nil {
+		return exception.NewConflict("only a pending schedule can be cancelled")
+	}
+
+	return nil
+}
+
+// RunDue applies the schedules that have started and reverts the ones that
+// have ended, and returns how many schedules were handled.
+func (p *priceService) RunDue(ctx context.Context) (int, error) {
+	total := 0
+
+	for {
+		handled := 0
+
+		err := runInTx(ctx, p.pool, func(tx pgx.Tx) error {
+			schedules, err := p.priceRepository.LockDueTx(ctx, tx, priceScheduleBatch)
+			if err != nil {
+				return err
+			}
+
+			for i := range schedules {
+				if err := p.runScheduleTx(ctx, tx, &schedules[i]); err != nil {
+					return err
+				}
+			}
+
+			handled = len(schedules)
+			return nil
+		})
+		if err != nil {
+			return total, err
+		}
+
+		total += handled
+		if handled < priceScheduleBatch {
+			return total, nil
+		}
+	}
+}
+
+// priceSchedulerLock is the advisory lock key of the price scheduler.
+const priceSchedulerLock int64 = 4627001
+
+// RunScheduler calls RunDue every interval until ctx is done. With several
+// servers running, only the one holding the advisory lock applies schedules.
+func (p *priceService) RunScheduler(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		p.tick(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (p *priceService) tick(ctx context.Context) {
+	defer func() {
+		if err := recover(); err != nil {
+			log.Println("price scheduler:", err)
+		}
+	}()
+
+	var n int
+	_, err := runExclusive(ctx, p.pool, priceSchedulerLock, func() (err error) {
+		n, err = p.RunDue(ctx)
+		return err
+	})
+	if err != nil {
+		log.Println("price scheduler:", err)
+		return
+	}
+
+	if n > 0 {
+		log.Println("price scheduler: handled", n, "schedules")
+	}
+}
+
+func (p *priceService) runScheduleTx(ctx context.Context, tx pgx.Tx, schedule *entity.PriceSchedule) error {
+	now := time.Now()
+
+	price, err := p.productRepository.LockPriceTx(ctx, tx, schedule.ProductId)
+	if err != nil {
+		// the product was deleted in the meantime
+		schedule.Status = entity.PriceScheduleCancelled
+		return p.priceRepository.UpdateScheduleTx(ctx, tx, schedule)
+	}
+
+	if schedule.Status == entity.PriceSchedulePending {
+		schedule.PreviousPrice = &price
+		schedule.AppliedAt = &now
+		schedule.Status = entity.PriceScheduleDone
+		if schedule.EndsAt != nil {
+			schedule.Status = entity.PriceScheduleActive
+		}
+
+		if err := p.changePriceTx(ctx, tx, schedule, price, schedule.Price); err != nil {
+			return err
+		}
+
+		return p.priceRepository.UpdateScheduleTx(ctx, tx, schedule)
+	}
+
+	// a price changed by hand while the schedule was active is kept
+	if price == schedule.Price && schedule.PreviousPrice != nil {
+		if err := p.changePriceTx(ctx, tx, schedule, price, *schedule.PreviousPrice); err != nil {
+			return err
+		}
+		schedule.RevertedAt = &now
+	}
+	schedule.Status = entity.PriceScheduleDone
+
+	return p.priceRepository.UpdateScheduleTx(ctx, tx, schedule)
+}
+
+func (p *priceService) changePriceTx(ctx context.Context, tx pgx.Tx, schedule *entity.PriceSchedule, oldPrice int, newPrice int) error {
+	if oldPrice == newPrice {
+		return nil
+	}
+
+	err := p.priceRepository.InsertHistoryTx(ctx, tx, &entity.PriceHistory{
+		ProductId:  schedule.ProductId,
+		OldPrice:   &oldPrice,
+		NewPrice:   newPrice,
+		ChangedBy:  schedule.CreatedBy,
+		ScheduleId: &schedule.Id,
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := p.priceRepository.InsertVariantsHistoryTx(ctx, tx, schedule.ProductId, newPrice, schedule.CreatedBy, &schedule.Id); err != nil {
+		return err
+	}
+
+	return p.productRepository.UpdatePriceTx(ctx, tx, schedule.ProductId, newPrice)
+}
@@ -2,6 +2,8 @@ package service
 
 import (
 	"context"
+	"errors"
+	"strings"
 
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
@@ -11,27 +13,57 @@ import (
 )
 
 type ProductService interface {
-	Create(ctx context.Context, req *entity.ProductInsertRequest) (*entity.Product, error)
+	Create(ctx context.Context, staffId string, req *entity.ProductInsertRequest) (*entity.Product, error)
 	IsExists(ctx context.Context, productId string) bool
-	GetAll(ctx context.Context, req *entity.ProductQueryParams) (*[]entity.Product, error)
-	FindSku(ctx context.Context, req *entity.ProductQueryParams) (*[]entity.ProductSKU, error)
-	Update(ctx context.Context, ID string, req *entity.ProductUpdateRequest) (*entity.Product, error)
+	GetAll(ctx context.Context, req *entity.ProductQueryParams) (*[]entity.Product, *entity.PageMeta, error)
+	FindSku(ctx context.Context, req *entity.ProductQueryParams) (*[]entity.ProductSKU, *entity.PageMeta, error)
+	FindOne(ctx context.Context, ID string) (*entity.Product, error)
+	Update(ctx context.Context, staffId string, ID string, req *entity.ProductUpdateRequest, version *int) (*entity.Product, error)
 	Delete(ctx context.Context, ID string) error
+	GetDeleted(ctx context.Context, page *entity.PageParams) ([]entity.DeletedProduct, *entity.PageMeta, error)
+	Restore(ctx context.Context, ID string) (*entity.Product, error)
+	Purge(ctx context.Context, ID string) error
+	GetStocks(ctx context.Context, ID string) ([]entity.ProductStock, error)
+	UpdateStock(ctx context.Context, ID string, req *entity.ProductStockUpdateRequest, version *int) ([]entity.ProductStock, int, error)
+	GetVariants(ctx context.Context, ID string) ([]entity.Product, error)
+	CreateVariant(ctx context.Context, staffId string, ID string, req *entity.ProductVariantInsertUpdateRequest) (*entity.Product, error)
+	UpdateVariant(ctx context.Context, staffId string, ID string, variantId string, req *entity.ProductVariantInsertUpdateRequest) (*entity.Product, error)
+	DeleteVariant(ctx context.Context, ID string, variantId string) error
+	GetComponents(ctx context.Context, ID string) ([]entity.BundleComponent, error)
+	UpdateComponents(ctx context.Context, ID string, req *entity.BundleComponentsUpdateRequest, version *int) (*entity.Product, error)
+	Search(ctx context.Context, params *entity.ProductSearchParams) ([]entity.ProductSearchResult, *entity.PageMeta, error)
+	Suggest(ctx context.Context, q string, limit int) ([]entity.ProductSuggestion, error)
 }
 
 type productService struct {
-	pool              *pgxpool.Pool
-	productRepository repository.ProductRepository
+	pool               *pgxpool.Pool
+	productRepository  repository.ProductRepository
+	stockRepository    repository.StockRepository
+	locationRepository repository.LocationRepository
+	categoryRepository repository.CategoryRepository
+	priceRepository    repository.PriceRepository
+	bundleRepository   repository.BundleRepository
+	serialRepository   repository.SerialRepository
 }
 
-func NewProductService(pool *pgxpool.Pool, productRepo repository.ProductRepository) ProductService {
+func NewProductService(pool *pgxpool.Pool, productRepo repository.ProductRepository, stockRepo repository.StockRepository, locationRepo repository.LocationRepository, categoryRepo repository.CategoryRepository, priceRepo repository.PriceRepository, bundleRepo repository.BundleRepository, serialRepo repository.SerialRepository) ProductService {
 	return &productService{
-		pool:              pool,
-		productRepository: productRepo,
+		pool:               pool,
+		productRepository:  productRepo,
+		stockRepository:    stockRepo,
+		locationRepository: locationRepo,
+		categoryRepository: categoryRepo,
+		priceRepository:    priceRepo,
+		bundleRepository:   bundleRepo,
+		serialRepository:   serialRepo,
 	}
 }
 
-func (p *productService) Create(ctx context.Context, req *entity.ProductInsertRequest) (*entity.Product, error) {
+func (p *productService) Create(ctx context.Context, staffId string, req *entity.ProductInsertRequest) (*entity.Product, error) {
+	if !p.categoryRepository.IsExistByName(ctx, p.pool, req.Category) {
+		return nil, exception.NewBadRequest("request doesn’t pass validation")
+	}
+
 	product := &entity.Product{
 		Name:        req.Name,
 		SKU:         req.SKU,
@@ -42,14 +74,81 @@ func (p *productService) Create(ctx context.Context, req *entity.ProductInsertRe
 		Stock:       *req.Stock,
 		Location:    req.Location,
 		IsAvailable: *req.IsAvailable,
+
+		VariantAttributes: req.VariantAttributes,
+		Tags:              p.normalizeTags(req.Tags),
+		Attributes:        req.Attributes,
+
+		Unit:               entity.UnitPiece,
+		PurchaseUnitFactor: entity.Units(1),
+	}
+
+	if req.CostPrice != nil {
+		product.CostPrice = *req.CostPrice
+	}
+
+	if req.TrackSerials != nil {
+		product.TrackSerials = *req.TrackSerials
+	}
+
+	// a product created with components is a bundle, its stock comes from them
+	if len(req.Components) > 0 {
+		if len(req.VariantAttributes) > 0 {
+			return nil, exception.NewBadRequest("a bundle can not have variantAttributes")
+		}
+
+		if product.TrackSerials {
+			return nil, exception.NewBadRequest("a bundle can not track serials, its components do")
+		}
+
+		if err := p.validateComponents(ctx, "", req.Components); err != nil {
+			return nil, err
+		}
+
+		product.IsBundle = true
+	}
+
+	if err := p.setUnit(product, req.Unit, req.QuantityPrecision, &req.PurchaseUnit, req.PurchaseUnitFactor); err != nil {
+		return nil, err
 	}
 
-	data, err := p.productRepository.Insert(ctx, p.pool, product)
+	if err := checkQuantity(product, product.Stock); err != nil {
+		return nil, err
+	}
+
+	if err := checkSerialStock(product, product.Stock, 0); err != nil {
+		return nil, err
+	}
+
+	locationId, err := p.resolveLocation(ctx, req.LocationId)
 	if err != nil {
-		panic(exception.NewInternalServer(err.Error()))
+		return nil, err
 	}
 
-	return data, nil
+	err = runInTx(ctx, p.pool, func(tx pgx.Tx) error {
+		if _, err := p.productRepository.InsertTx(ctx, tx, product); err != nil {
+			return err
+		}
+
+		if err := p.recordPriceTx(ctx, tx, staffId, product.Id, nil, product.Price); err != nil {
+			return err
+		}
+
+		if product.IsBundle {
+			return p.bundleRepository.ReplaceComponentsTx(ctx, tx, product.Id, req.Components)
+		}
+
+		return p.stockRepository.SetTx(ctx, tx, product.Id, locationId, product.Stock)
+	})
+	if err != nil {
+		return nil, p.writeError(err)
+	}
+
+	if product.IsBundle {
+		return p.FindOne(ctx, product.Id)
+	}
+
+	return product, nil
 }
 
 func (p *productService) IsExists(ctx context.Context, productId string) bool {
@@ -58,60 +157,209 @@ func (p *productService) IsExists(ctx context.Context, productId string) bool {
 	return exists
 }
 
-func (p *productService) GetAll(ctx context.Context, req *entity.ProductQueryParams) (*[]entity.Product, error) {
-	products, err := p.productRepository.FindMany(ctx, p.pool, req)
+func (p *productService) GetAll(ctx context.Context, req *entity.ProductQueryParams) (*[]entity.Product, *entity.PageMeta, error) {
+	// unknown category is ignored, the same as any other invalid filter
+	if req.Category != "" && !p.categoryRepository.IsExistByName(ctx, p.pool, req.Category) {
+		req.Category = ""
+	}
+	req.Tags = p.normalizeTags(req.Tags)
 
-	return products, err
+	products, meta, err := p.productRepository.FindMany(ctx, p.pool, req)
+	if err != nil {
+		return nil, nil, pageError(err)
+	}
+
+	if len(*products) == 0 {
+		return products, meta, nil
+	}
+
+	if req.GroupVariants {
+		parentIds := []string{}
+		for _, product := range *products {
+			if product.HasVariants() {
+				parentIds = append(parentIds, product.Id)
+			}
+		}
+
+		if len(parentIds) > 0 {
+			variants := p.productRepository.FindVariants(ctx, p.pool, parentIds)
+			for i := range *products {
+				(*products)[i].Variants = variants[(*products)[i].Id]
+			}
+		}
+	}
+
+	if req.WithStocks {
+		productIds := []string{}
+		for _, product := range *products {
+			productIds = append(productIds, product.Id)
+			for _, variant := range product.Variants {
+				productIds = append(productIds, variant.Id)
+			}
+		}
+
+		stocks := p.stockRepository.FindByProductIds(ctx, p.pool, productIds)
+		for i := range *products {
+			product := &(*products)[i]
+			product.Stocks = stocks[product.Id]
+			for j := range product.Variants {
+				product.Variants[j].Stocks = stocks[product.Variants[j].Id]
+			}
+		}
+	}
+
+	return products, meta, nil
 }
 
-func (p *productService) FindSku(ctx context.Context, req *entity.ProductQueryParams) (*[]entity.ProductSKU, error) {
-	productSKU, err := p.productRepository.FindSku(ctx, p.pool, req)
+func (p *productService) FindSku(ctx context.Context, req *entity.ProductQueryParams) (*[]entity.ProductSKU, *entity.PageMeta, error) {
+	if req.Category != "" && !p.categoryRepository.IsExistByName(ctx, p.pool, req.Category) {
+		req.Category = ""
+	}
+	req.Tags = p.normalizeTags(req.Tags)
+
+	productSKU, meta, err := p.productRepository.FindSku(ctx, p.pool, req)
+	if err != nil {
+		return nil, nil, pageError(err)
+	}
 
-	return productSKU, err
+	return productSKU, meta, nil
 }
 
-func (p *productService) Update(ctx context.Context, ID string, req *entity.ProductUpdateRequest) (*entity.Product, error) {
+func (p *productService) FindOne(ctx context.Context, ID string) (*entity.Product, error) {
+	product, err := p.productRepository.FindOne(ctx, p.pool, ID)
+	if err != nil {
+		return nil, exception.NewNotFound("product id not found")
+	}
+
+	if product.IsBundle {
+		product.Components = p.bundleRepository.FindComponents(ctx, p.pool, []string{ID})[ID]
+	}
+
+	return product, nil
+}
+
+// Update replaces the product fields with req. When version is set the update
+// is refused unless the product is still at that version. The stock is only
+// changed when req.Stock is set.
+func (p *productService) Update(ctx context.Context, staffId string, ID string, req *entity.ProductUpdateRequest, version *int) (*entity.Product, error) {
 	product, err := p.productRepository.FindOne(ctx, p.pool, ID)
 	if err != nil {
 		e := exception.NewNotFound("ID not found")
 		return nil, e
 	}
 
+	if version != nil && *version != product.Version {
+		return nil, exception.NewPreconditionFailed("product has been changed, reload it and try again")
+	}
+
+	if product.ParentId != nil {
+		return nil, exception.NewBadRequest("product is a variant, update it through its parent")
+	}
+
+	if product.IsBundle && len(req.VariantAttributes) > 0 {
+		return nil, exception.NewBadRequest("a bundle can not have variantAttributes")
+	}
+
+	if product.IsBundle && req.TrackSerials != nil && *req.TrackSerials {
+		return nil, exception.NewBadRequest("a bundle can not track serials, its components do")
+	}
+
+	if !p.categoryRepository.IsExistByName(ctx, p.pool, req.Category) {
+		return nil, exception.NewBadRequest("request doesn’t pass validation")
+	}
+
+	if req.TrackSerials != nil && *req.TrackSerials && !product.TrackSerials {
+		if err := p.checkSerialsOnHand(ctx, product); err != nil {
+			return nil, err
+		}
+	}
+
+	if product.HasVariants() && !p.sameAttributes(product.VariantAttributes, req.VariantAttributes) {
+		if variants := p.productRepository.FindVariants(ctx, p.pool, []string{ID})[ID]; len(variants) > 0 {
+			return nil, exception.NewBadRequest("variantAttributes can not be changed while the product has variants")
+		}
+	}
+
+	oldPrice := product.Price
+
 	product.Name = req.Name
 	product.SKU = req.SKU
 	product.Category = req.Category
 	product.Notes = req.Notes
 	product.ImageUrl = req.ImageUrl
 	product.Price = req.Price
-	product.Stock = *req.Stock
 	product.Location = req.Location
 	product.IsAvailable = *req.IsAvailable
+	product.VariantAttributes = req.VariantAttributes
+	product.Tags = p.normalizeTags(req.Tags)
+	product.Attributes = req.Attributes
+	if req.CostPrice != nil {
+		product.CostPrice = *req.CostPrice
+	}
+	if req.TrackSerials != nil {
+		product.TrackSerials = *req.TrackSerials
+	}
 
-	tx, err := p.pool.BeginTx(ctx, pgx.TxOptions{
-		IsoLevel: pgx.ReadUncommitted,
-	})
+	if err := p.setUnit(product, req.Unit, req.QuantityPrecision, req.PurchaseUnit, req.PurchaseUnitFactor); err != nil {
+		return nil, err
+	}
+
+	// a parent product has no stock of its own, its variants do
+	if req.Stock != nil && product.HasVariants() {
+		return nil, exception.NewBadRequest("product has variants, update the stock of its variants")
+	}
+
+	if req.Stock != nil && !product.IsBundle {
+		if err := checkQuantity(product, *req.Stock); err != nil {
+			return nil, err
+		}
+	}
+
+	locationId, err := p.resolveLocation(ctx, req.LocationId)
 	if err != nil {
-		panic(err)
+		return nil, err
 	}
-	defer func() {
-		err := recover()
-		if err != nil {
-			e := tx.Rollback(ctx)
-			if e != nil {
-				panic(e)
+
+	if req.Stock != nil && !product.IsBundle {
+		current := p.stockRepository.FindAtLocation(ctx, p.pool, locationId, []string{product.Id})[product.Id]
+		if err := checkSerialStock(product, *req.Stock, current); err != nil {
+			return nil, err
+		}
+	}
+
+	err = runInTx(ctx, p.pool, func(tx pgx.Tx) error {
+		if err := p.productRepository.UpdateTx(ctx, tx, product); err != nil {
+			return err
+		}
+
+		if oldPrice != product.Price {
+			if err := p.recordPriceTx(ctx, tx, staffId, product.Id, &oldPrice, product.Price); err != nil {
+				return err
 			}
-		} else {
-			e := tx.Commit(ctx)
-			if e != nil {
-				panic(e)
+
+			if err := p.priceRepository.InsertVariantsHistoryTx(ctx, tx, product.Id, product.Price, p.staff(staffId), nil); err != nil {
+				return err
 			}
 		}
-	}()
 
-	err = p.productRepository.UpdateTx(ctx, tx, product)
+		if err := p.productRepository.UpdateVariantsTx(ctx, tx, product); err != nil {
+			return err
+		}
+
+		// the stock of a bundle is derived from its components
+		if req.Stock == nil || product.IsBundle {
+			return nil
+		}
+
+		if err := p.stockRepository.SetTx(ctx, tx, product.Id, locationId, *req.Stock); err != nil {
+			return err
+		}
 
+		product.Stock, err = p.stockRepository.TotalTx(ctx, tx, product.Id)
+		return err
+	})
 	if err != nil {
-		panic(exception.NewInternalServer(err.Error()))
+		return nil, p.writeError(err)
 	}
 
 	return product, nil
@@ -122,3 +370,584 @@ func (p *productService) Delete(ctx context.Context, ID string) error {
 
 	return err
 }
+
+func (p *productService) GetDeleted(ctx context.Context, page *entity.PageParams) ([]entity.DeletedProduct, *entity.PageMeta, error) {
+	products, meta, err := p.productRepository.FindDeleted(ctx, p.pool, page)
+	if err != nil {
+		return nil, nil, pageError(err)
+	}
+
+	return products, meta, nil
+}
+
+func (p *productService) Restore(ctx context.Context, ID string) (*entity.Product, error) {
+	product, err := p.productRepository.FindDeletedOne(ctx, p.pool, ID)
+	if err != nil {
+		return nil, exception.NewNotFound("deleted product id not found")
+	}
+
+	if product.ParentId != nil && !p.productRepository.IsExists(ctx, p.pool, *product.ParentId) {
+		return nil, exception.NewBadRequest("parent product is deleted, restore it first")
+	}
+
+	if err := p.productRepository.Restore(ctx, p.pool, ID); err != nil {
+		return nil, exception.NewConflict("variant with the same variantOptions already exist")
+	}
+
+	return p.FindOne(ctx, ID)
+}
+
+// Purge permanently removes a deleted product. Products with sales or
+// transfers are kept, their history refers to them.
+func (p *productService) Purge(ctx context.Context, ID string) error {
+	product, err := p.productRepository.FindDeletedOne(ctx, p.pool, ID)
+	if err != nil {
+		return exception.NewNotFound("deleted product id not found")
+	}
+
+	if p.productRepository.IsSold(ctx, p.pool, product.Id) {
+		return exception.NewConflict("product has transactions and can not be purged")
+	}
+
+	if p.productRepository.IsTransferred(ctx, p.pool, product.Id) {
+		return exception.NewConflict("product has stock transfers and can not be purged")
+	}
+
+	err = runInTx(ctx, p.pool, func(tx pgx.Tx) error {
+		return p.productRepository.PurgeTx(ctx, tx, product.Id)
+	})
+	if err != nil {
+		// a sale or transfer recorded since the checks above
+		return exception.NewConflict("product is referenced and can not be purged")
+	}
+
+	return nil
+}
+
+func (p *productService) GetStocks(ctx context.Context, ID string) ([]entity.ProductStock, error) {
+	if !p.productRepository.IsExists(ctx, p.pool, ID) {
+		return nil, exception.NewNotFound("product id not found")
+	}
+
+	stocks := p.stockRepository.FindByProductIds(ctx, p.pool, []string{ID})[ID]
+	if stocks == nil {
+		stocks = []entity.ProductStock{}
+	}
+
+	return stocks, nil
+}
+
+// UpdateStock sets the stock of a product at one location. A manual adjustment
+// bumps the product version like any other update, checkout and transfers do not.
+func (p *productService) UpdateStock(ctx context.Context, ID string, req *entity.ProductStockUpdateRequest, version *int) ([]entity.ProductStock, int, error) {
+	product, err := p.productRepository.FindOne(ctx, p.pool, ID)
+	if err != nil {
+		return nil, 0, exception.NewNotFound("product id not found")
+	}
+
+	if version != nil && *version != product.Version {
+		return nil, 0, exception.NewPreconditionFailed("product has been changed, reload it and try again")
+	}
+
+	if product.IsBundle {
+		return nil, 0, exception.NewBadRequest("the stock of a bundle is derived from its components")
+	}
+
+	if product.HasVariants() {
+		return nil, 0, exception.NewBadRequest("product has variants, update the stock of its variants")
+	}
+
+	if err := checkQuantity(product, *req.Stock); err != nil {
+		return nil, 0, err
+	}
+
+	if !p.locationRepository.IsExist(ctx, p.pool, req.LocationId) {
+		return nil, 0, exception.NewNotFound("location id not found")
+	}
+
+	current := p.stockRepository.FindAtLocation(ctx, p.pool, req.LocationId, []string{ID})[ID]
+	if err := checkSerialStock(product, *req.Stock, current); err != nil {
+		return nil, 0, err
+	}
+
+	err = runInTx(ctx, p.pool, func(tx pgx.Tx) error {
+		if product.Version, err = p.productRepository.BumpVersionTx(ctx, tx, ID, product.Version); err != nil {
+			return err
+		}
+
+		return p.stockRepository.SetTx(ctx, tx, ID, req.LocationId, *req.Stock)
+	})
+	if err != nil {
+		return nil, 0, p.writeError(err)
+	}
+
+	stocks, err := p.GetStocks(ctx, ID)
+
+	return stocks, product.Version, err
+}
+
+func (p *productService) Search(ctx context.Context, params *entity.ProductSearchParams) ([]entity.ProductSearchResult, *entity.PageMeta, error) {
+	if params.Category != "" && !p.categoryRepository.IsExistByName(ctx, p.pool, params.Category) {
+		params.Category = ""
+	}
+
+	results, meta, err := p.productRepository.Search(ctx, p.pool, params)
+	if err != nil {
+		return nil, nil, pageError(err)
+	}
+
+	return results, meta, nil
+}
+
+func (p *productService) Suggest(ctx context.Context, q string, limit int) ([]entity.ProductSuggestion, error) {
+	return p.productRepository.Suggest(ctx, p.pool, q, limit)
+}
+
+func (p *productService) GetVariants(ctx context.Context, ID string) ([]entity.Product, error) {
+	if _, err := p.findParent(ctx, ID); err != nil {
+		return nil, err
+	}
+
+	variants := p.productRepository.FindVariants(ctx, p.pool, []string{ID})[ID]
+	if variants == nil {
+		variants = []entity.Product{}
+	}
+
+	return variants, nil
+}
+
+// CreateVariant adds a sellable variant under a parent product. The variant
+// shares name, category, notes and location with its parent, and follows the
+// parent price unless req.Price is set.
+func (p *productService) CreateVariant(ctx context.Context, staffId string, ID string, req *entity.ProductVariantInsertUpdateRequest) (*entity.Product, error) {
+	parent, err := p.findParent(ctx, ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !p.sameAttributes(parent.VariantAttributes, p.optionKeys(req.VariantOptions)) {
+		return nil, exception.NewBadRequest("variantOptions must match the product variantAttributes")
+	}
+
+	locationId, err := p.resolveLocation(ctx, req.LocationId)
+	if err != nil {
+		return nil, err
+	}
+
+	variant := &entity.Product{
+		Name:           parent.Name,
+		SKU:            req.SKU,
+		Category:       parent.Category,
+		ImageUrl:       parent.ImageUrl,
+		Notes:          parent.Notes,
+		Price:          parent.Price,
+		Stock:          *req.Stock,
+		Location:       parent.Location,
+		IsAvailable:    *req.IsAvailable,
+		ParentId:       &parent.Id,
+		VariantOptions: req.VariantOptions,
+		InheritPrice:   req.Price == nil,
+		Tags:           parent.Tags,
+		Attributes:     parent.Attributes,
+		CostPrice:      parent.CostPrice,
+		TrackSerials:   parent.TrackSerials,
+
+		Unit:               parent.Unit,
+		QuantityPrecision:  parent.QuantityPrecision,
+		PurchaseUnit:       parent.PurchaseUnit,
+		PurchaseUnitFactor: parent.PurchaseUnitFactor,
+	}
+
+	if err := checkQuantity(variant, variant.Stock); err != nil {
+		return nil, err
+	}
+
+	if err := checkSerialStock(variant, variant.Stock, 0); err != nil {
+		return nil, err
+	}
+
+	if req.Price != nil {
+		variant.Price = *req.Price
+	}
+
+	if req.CostPrice != nil {
+		variant.CostPrice = *req.CostPrice
+	}
+
+	err = runInTx(ctx, p.pool, func(tx pgx.Tx) error {
+		if _, err := p.productRepository.InsertTx(ctx, tx, variant); err != nil {
+			return exception.NewConflict("variant with the same variantOptions already exist")
+		}
+
+		if err := p.recordPriceTx(ctx, tx, staffId, variant.Id, nil, variant.Price); err != nil {
+			return err
+		}
+
+		return p.stockRepository.SetTx(ctx, tx, variant.Id, locationId, variant.Stock)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return variant, nil
+}
+
+func (p *productService) UpdateVariant(ctx context.Context, staffId string, ID string, variantId string, req *entity.ProductVariantInsertUpdateRequest) (*entity.Product, error) {
+	parent, err := p.findParent(ctx, ID)
+	if err != nil {
+		return nil, err
+	}
+
+	variant, err := p.productRepository.FindOne(ctx, p.pool, variantId)
+	if err != nil || variant.ParentId == nil || *variant.ParentId != parent.Id {
+		return nil, exception.NewNotFound("variant id not found")
+	}
+
+	if !p.sameAttributes(parent.VariantAttributes, p.optionKeys(req.VariantOptions)) {
+		return nil, exception.NewBadRequest("variantOptions must match the product variantAttributes")
+	}
+
+	if err := checkQuantity(variant, *req.Stock); err != nil {
+		return nil, err
+	}
+
+	locationId, err := p.resolveLocation(ctx, req.LocationId)
+	if err != nil {
+		return nil, err
+	}
+
+	current := p.stockRepository.FindAtLocation(ctx, p.pool, locationId, []string{variant.Id})[variant.Id]
+	if err := checkSerialStock(variant, *req.Stock, current); err != nil {
+		return nil, err
+	}
+
+	oldPrice := variant.Price
+
+	variant.SKU = req.SKU
+	variant.VariantOptions = req.VariantOptions
+	variant.IsAvailable = *req.IsAvailable
+	variant.InheritPrice = req.Price == nil
+	variant.Price = parent.Price
+	if req.Price != nil {
+		variant.Price = *req.Price
+	}
+	if req.CostPrice != nil {
+		variant.CostPrice = *req.CostPrice
+	}
+
+	err = runInTx(ctx, p.pool, func(tx pgx.Tx) error {
+		if err := p.productRepository.UpdateTx(ctx, tx, variant); err != nil {
+			if errors.Is(err, repository.ErrVersionConflict) {
+				return p.writeError(err)
+			}
+			return exception.NewConflict("variant with the same variantOptions already exist")
+		}
+
+		if oldPrice != variant.Price {
+			if err := p.recordPriceTx(ctx, tx, staffId, variant.Id, &oldPrice, variant.Price); err != nil {
+				return err
+			}
+		}
+
+		if err := p.stockRepository.SetTx(ctx, tx, variant.Id, locationId, *req.Stock); err != nil {
+			return err
+		}
+
+		variant.Stock, err = p.stockRepository.TotalTx(ctx, tx, variant.Id)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return variant, nil
+}
+
+func (p *productService) DeleteVariant(ctx context.Context, ID string, variantId string) error {
+	variant, err := p.productRepository.FindOne(ctx, p.pool, variantId)
+	if err != nil || variant.ParentId == nil || *variant.ParentId != ID {
+		return exception.NewNotFound("variant id not found")
+	}
+
+	return p.productRepository.Delete(ctx, p.pool, variantId)
+}
+
+func (p *productService) GetComponents(ctx context.Context, ID string) ([]entity.BundleComponent, error) {
+	product, err := p.FindOne(ctx, ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !product.IsBundle {
+		return nil, exception.NewBadRequest("product is not a bundle")
+	}
+
+	return product.Components, nil
+}
+
+// UpdateComponents replaces what one bundle contains, the bundle price is
+// left as it is.
+func (p *productService) UpdateComponents(ctx context.Context, ID string, req *entity.BundleComponentsUpdateRequest, version *int) (*entity.Product, error) {
+	product, err := p.productRepository.FindOne(ctx, p.pool, ID)
+	if err != nil {
+		return nil, exception.NewNotFound("product id not found")
+	}
+
+	if version != nil && *version != product.Version {
+		return nil, exception.NewPreconditionFailed("product has been changed, reload it and try again")
+	}
+
+	if !product.IsBundle {
+		return nil, exception.NewBadRequest("product is not a bundle")
+	}
+
+	if err := p.validateComponents(ctx, ID, req.Components); err != nil {
+		return nil, err
+	}
+
+	err = runInTx(ctx, p.pool, func(tx pgx.Tx) error {
+		if _, err := p.productRepository.BumpVersionTx(ctx, tx, ID, product.Version); err != nil {
+			return err
+		}
+
+		return p.bundleRepository.ReplaceComponentsTx(ctx, tx, ID, req.Components)
+	})
+	if err != nil {
+		return nil, p.writeError(err)
+	}
+
+	return p.FindOne(ctx, ID)
+}
+
+// validateComponents checks the components of bundle bundleId, only plain
+// products and variants can be put in a bundle.
+func (p *productService) validateComponents(ctx context.Context, bundleId string, components []entity.BundleComponent) error {
+	productIds := []string{}
+	for _, component := range components {
+		if component.ProductId == bundleId {
+			return exception.NewBadRequest("a bundle can not contain itself")
+		}
+		productIds = append(productIds, component.ProductId)
+	}
+
+	products := p.productRepository.FindByIds(ctx, p.pool, productIds)
+	if len(*products) != len(productIds) {
+		return exception.NewNotFound("one of component productId not found")
+	}
+
+	for _, component := range components {
+		if err := checkQuantity(findProduct(*products, component.ProductId), component.Quantity); err != nil {
+			return err
+		}
+	}
+
+	for _, product := range *products {
+		if product.IsBundle {
+			return exception.NewBadRequest("a bundle can not contain another bundle")
+		}
+
+		if product.HasVariants() {
+			return exception.NewBadRequest("one of product has variants, use the variant id")
+		}
+
+		if product.TrackSerials {
+			return exception.NewBadRequest("a bundle can not contain serial tracked products")
+		}
+	}
+
+	return nil
+}
+
+// setUnit applies the unit fields of a create or update request to product.
+// A new unit without a precision gets the default precision of that unit.
+func (p *productService) setUnit(product *entity.Product, unit string, precision *int, purchaseUnit *string, factor *entity.Quantity) error {
+	if unit != "" && unit != product.Unit {
+		product.Unit = unit
+		product.QuantityPrecision = entity.UnitPrecisions[unit]
+	}
+
+	if precision != nil {
+		product.QuantityPrecision = *precision
+	}
+
+	if purchaseUnit != nil {
+		product.PurchaseUnit = *purchaseUnit
+	}
+
+	if factor != nil {
+		product.PurchaseUnitFactor = *factor
+	}
+
+	if product.PurchaseUnit == "" {
+		product.PurchaseUnitFactor = entity.Units(1)
+	} else if product.PurchaseUnit == product.Unit {
+		return exception.NewBadRequest("purchaseUnit must differ from unit")
+	}
+
+	if product.IsBundle && (product.Unit != entity.UnitPiece || product.QuantityPrecision > 0) {
+		return exception.NewBadRequest("a bundle is counted in whole pcs")
+	}
+
+	return nil
+}
+
+// checkQuantity rejects a quantity with more decimals than product is counted in.
+func checkQuantity(product *entity.Product, quantity entity.Quantity) error {
+	if !product.AllowsQuantity(quantity) {
+		return exception.NewBadRequest("one of quantity has more decimals than its product unit allows")
+	}
+
+	return nil
+}
+
+// checkSerialStock refuses a manual stock change of a serial tracked product,
+// its stock arrives with serials through goods receipts. Setting the stock at
+// a location to 0, or to what it already is, is allowed.
+func checkSerialStock(product *entity.Product, stock entity.Quantity, current entity.Quantity) error {
+	if product.TrackSerials && stock != 0 && stock != current {
+		return exception.NewBadRequest("product tracks serials, receive its stock through a goods receipt")
+	}
+
+	return nil
+}
+
+// checkSerialsOnHand refuses to turn serial tracking on while the stock of the
+// product, or of its variants, is not covered by serials in stock. Checkout
+// would otherwise refuse every sale of it.
+func (p *productService) checkSerialsOnHand(ctx context.Context, product *entity.Product) error {
+	products := []entity.Product{*product}
+	if product.HasVariants() {
+		products = p.productRepository.FindVariants(ctx, p.pool, []string{product.Id})[product.Id]
+	}
+
+	ids := make([]string, len(products))
+	for i, product := range products {
+		ids[i] = product.Id
+	}
+
+	counts := p.serialRepository.CountInStock(ctx, p.pool, ids)
+	for _, product := range products {
+		if product.Stock > entity.Units(counts[product.Id]) {
+			return exception.NewBadRequest("product has stock without serials, set its stock to 0 before tracking serials")
+		}
+	}
+
+	return nil
+}
+
+func (p *productService) findParent(ctx context.Context, ID string) (*entity.Product, error) {
+	parent, err := p.productRepository.FindOne(ctx, p.pool, ID)
+	if err != nil {
+		return nil, exception.NewNotFound("product id not found")
+	}
+
+	if !parent.HasVariants() {
+		return nil, exception.NewBadRequest("product has no variantAttributes")
+	}
+
+	return parent, nil
+}
+
+// normalizeTags lowercases tags and drops duplicates so filtering is case insensitive.
+func (p *productService) normalizeTags(tags []string) []string {
+	result := []string{}
+	seen := map[string]bool{}
+
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		result = append(result, tag)
+	}
+
+	return result
+}
+
+func (p *productService) optionKeys(options map[string]string) []string {
+	keys := []string{}
+	for key := range options {
+		keys = append(keys, key)
+	}
+
+	return keys
+}
+
+// sameAttributes compares two attribute lists regardless of their order.
+func (p *productService) sameAttributes(a []string, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	set := map[string]bool{}
+	for _, attribute := range a {
+		set[attribute] = true
+	}
+
+	for _, attribute := range b {
+		if !set[attribute] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// resolveLocation falls back to the default location when locationId is empty.
+func (p *productService) resolveLocation(ctx context.Context, locationId string) (string, error) {
+	if locationId == "" {
+		location, err := p.locationRepository.FindDefault(ctx, p.pool)
+		if err != nil {
+			return "", exception.NewInternalServer(err.Error())
+		}
+		return location.Id, nil
+	}
+
+	if !p.locationRepository.IsExist(ctx, p.pool, locationId) {
+		return "", exception.NewNotFound("location id not found")
+	}
+
+	return locationId, nil
+}
+
+// writeError maps the errors of a product write, a version conflict means the
+// product was changed by someone else since it was read. A category, location
+// or component removed while the product was written is a client error.
+func (p *productService) writeError(err error) error {
+	if errors.Is(err, repository.ErrVersionConflict) {
+		return exception.NewPreconditionFailed("product has been changed, reload it and try again")
+	}
+
+	if isForeignKeyViolation(err) {
+		return exception.NewBadRequest("category, location or component of the product no longer exists")
+	}
+
+	if isUniqueViolation(err) {
+		return exception.NewConflict("product conflicts with an existing one")
+	}
+
+	if _, ok := err.(*exception.CustomError); ok {
+		return err
+	}
+
+	return exception.NewInternalServer(err.Error())
+}
+
+func (p *productService) recordPriceTx(ctx context.Context, tx pgx.Tx, staffId string, productId string, oldPrice *int, newPrice int) error {
+	return p.priceRepository.InsertHistoryTx(ctx, tx, &entity.PriceHistory{
+		ProductId: productId,
+		OldPrice:  oldPrice,
+		NewPrice:  newPrice,
+		ChangedBy: p.staff(staffId),
+	})
+}
+
+// staff returns nil for an empty staff id so it is stored as NULL.
+func (p *productService) staff(staffId string) *string {
+	if staffId == "" {
+		return nil
+	}
+
+	return &staffId
+}
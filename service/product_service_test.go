@@ -0,0 +1,178 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/malikfajr/eq-store/entity"
+	"github.com/malikfajr/eq-store/exception"
+	"github.com/malikfajr/eq-store/repository"
+)
+
+// The fakes embed the repository interfaces and only implement what the
+// checks below read, the tests stop before anything is written.
+
+type fakeProductRepository struct {
+	repository.ProductRepository
+	product  *entity.Product
+	variants []entity.Product
+}
+
+func (f *fakeProductRepository) FindOne(ctx context.Context, pool *pgxpool.Pool, ID string) (*entity.Product, error) {
+	product := *f.product
+	return &product, nil
+}
+
+func (f *fakeProductRepository) FindVariants(ctx context.Context, pool *pgxpool.Pool, parentIds []string) map[string][]entity.Product {
+	return map[string][]entity.Product{f.product.Id: f.variants}
+}
+
+type fakeCategoryRepository struct {
+	repository.CategoryRepository
+}
+
+func (f *fakeCategoryRepository) IsExistByName(ctx context.Context, pool *pgxpool.Pool, name string) bool {
+	return true
+}
+
+type fakeLocationRepository struct {
+	repository.LocationRepository
+}
+
+func (f *fakeLocationRepository) FindDefault(ctx context.Context, pool *pgxpool.Pool) (*entity.Location, error) {
+	return &entity.Location{Id: "location"}, nil
+}
+
+func (f *fakeLocationRepository) IsExist(ctx context.Context, pool *pgxpool.Pool, ID string) bool {
+	return true
+}
+
+type fakeStockRepository struct {
+	repository.StockRepository
+	stock entity.Quantity
+}
+
+func (f *fakeStockRepository) FindAtLocation(ctx context.Context, pool *pgxpool.Pool, locationId string, productIds []string) map[string]entity.Quantity {
+	return map[string]entity.Quantity{productIds[0]: f.stock}
+}
+
+type fakeSerialRepository struct {
+	repository.SerialRepository
+	counts map[string]int
+}
+
+func (f *fakeSerialRepository) CountInStock(ctx context.Context, pool *pgxpool.Pool, productIds []string) map[string]int {
+	return f.counts
+}
+
+func newFakeProductService(product *entity.Product, variants []entity.Product, stock entity.Quantity, serials map[string]int) ProductService {
+	return NewProductService(nil, &fakeProductRepository{product: product, variants: variants}, &fakeStockRepository{stock: stock},
+		&fakeLocationRepository{}, &fakeCategoryRepository{}, nil, nil, &fakeSerialRepository{counts: serials})
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+func assertBadRequest(t *testing.T, err error) {
+	t.Helper()
+
+	var e *exception.CustomError
+	if !errors.As(err, &e) || e.StatusCode != http.StatusBadRequest {
+		t.Errorf("got error %v, want a bad request", err)
+	}
+}
+
+func TestCreateSerialTrackedWithStock(t *testing.T) {
+	service := newFakeProductService(nil, nil, 0, nil)
+
+	_, err := service.Create(context.Background(), "staff", &entity.ProductInsertRequest{
+		Name:         "Phone",
+		Category:     "Electronics",
+		Stock:        ptr(entity.Units(3)),
+		IsAvailable:  ptr(true),
+		TrackSerials: ptr(true),
+	})
+
+	assertBadRequest(t, err)
+}
+
+func TestUpdateStockSerialTracked(t *testing.T) {
+	product := &entity.Product{Id: "product", TrackSerials: true, Unit: entity.UnitPiece}
+	service := newFakeProductService(product, nil, entity.Units(2), nil)
+
+	_, _, err := service.UpdateStock(context.Background(), product.Id, &entity.ProductStockUpdateRequest{
+		LocationId: "location",
+		Stock:      ptr(entity.Units(5)),
+	}, nil)
+
+	assertBadRequest(t, err)
+}
+
+func TestUpdateEnableSerialsWithStock(t *testing.T) {
+	tests := []struct {
+		name     string
+		product  *entity.Product
+		variants []entity.Product
+		serials  map[string]int
+	}{
+		{
+			name:    "stock without serials",
+			product: &entity.Product{Id: "product", Stock: entity.Units(4), Unit: entity.UnitPiece},
+		},
+		{
+			name:    "stock with fewer serials",
+			product: &entity.Product{Id: "product", Stock: entity.Units(4), Unit: entity.UnitPiece},
+			serials: map[string]int{"product": 3},
+		},
+		{
+			name:     "variant stock without serials",
+			product:  &entity.Product{Id: "product", VariantAttributes: []string{"color"}, Unit: entity.UnitPiece},
+			variants: []entity.Product{{Id: "variant", Stock: entity.Units(1)}},
+		},
+	}
+
+	for _, tt := range tests {
+		service := newFakeProductService(tt.product, tt.variants, 0, tt.serials)
+
+		_, err := service.Update(context.Background(), "staff", tt.product.Id, &entity.ProductUpdateRequest{
+			Name:              "Phone",
+			Category:          "Electronics",
+			IsAvailable:       ptr(true),
+			VariantAttributes: tt.product.VariantAttributes,
+			TrackSerials:      ptr(true),
+		}, nil)
+
+		t.Run(tt.name, func(t *testing.T) {
+			assertBadRequest(t, err)
+		})
+	}
+}
+
+func TestCheckSerialStock(t *testing.T) {
+	tracked := &entity.Product{TrackSerials: true}
+	untracked := &entity.Product{}
+
+	tests := []struct {
+		product *entity.Product
+		stock   entity.Quantity
+		current entity.Quantity
+		wantErr bool
+	}{
+		{product: untracked, stock: entity.Units(5), current: 0},
+		{product: tracked, stock: 0, current: entity.Units(5)},
+		{product: tracked, stock: entity.Units(5), current: entity.Units(5)},
+		{product: tracked, stock: entity.Units(5), current: 0, wantErr: true},
+		{product: tracked, stock: entity.Units(6), current: entity.Units(5), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		err := checkSerialStock(tt.product, tt.stock, tt.current)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("checkSerialStock(tracked=%v, %s, %s) = %v, want error %v", tt.product.TrackSerials, tt.stock, tt.current, err, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package service
+
+import (
+	"context"
+	"math"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/malikfajr/eq-store/entity"
+	"github.com/malikfajr/eq-store/exception"
+	"github.com/malikfajr/eq-store/repository"
+)
+
+type ReportService interface {
+	Margin(ctx context.Context, params *entity.MarginReportParams) ([]entity.MarginReportRow, error)
+	InventoryValuation(ctx context.Context, locationId string) (*entity.InventoryValuation, error)
+}
+
+type reportService struct {
+	pool               *pgxpool.Pool
+	locationRepository repository.LocationRepository
+	reportRepository   repository.ReportRepository
+}
+
+func NewReportService(pool *pgxpool.Pool, locationRepository repository.LocationRepository, reportRepository repository.ReportRepository) ReportService {
+	return &reportService{
+		pool:               pool,
+		locationRepository: locationRepository,
+		reportRepository:   reportRepository,
+	}
+}
+
+func (r *reportService) Margin(ctx context.Context, params *entity.MarginReportParams) ([]entity.MarginReportRow, error) {
+	if params.LocationId != "" && !r.locationRepository.IsExist(ctx, r.pool, params.LocationId) {
+		return nil, exception.NewNotFound("location id not found")
+	}
+
+	report := r.reportRepository.Margin(ctx, r.pool, params)
+
+	for i := range report {
+		row := &report[i]
+		row.GrossMargin = row.Revenue - row.Cost
+		if row.Revenue > 0 {
+			row.MarginPercent = math.Round(float64(row.GrossMargin)/float64(row.Revenue)*10000) / 100
+		}
+	}
+
+	return report, nil
+}
+
+func (r *reportService) InventoryValuation(ctx context.Context, locationId string) (*entity.InventoryValuation, error) {
+	if locationId != "" && !r.locationRepository.IsExist(ctx, r.pool, locationId) {
+		return nil, exception.NewNotFound("location id not found")
+	}
+
+	valuation := &entity.InventoryValuation{
+		Items: r.reportRepository.InventoryValuation(ctx, r.pool, locationId),
+	}
+
+	for _, item := range valuation.Items {
+		valuation.TotalStock += item.Stock
+		valuation.TotalCostValue += item.CostValue
+		valuation.TotalRetailValue += item.RetailValue
+	}
+
+	return valuation, nil
+}
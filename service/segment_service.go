@@ -0,0 +1,143 @@
+package service
+
+import (
+	"context"
+	"slices"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/malikfajr/eq-store/entity"
+	"github.com/malikfajr/eq-store/exception"
+	"github.com/malikfajr/eq-store/repository"
+)
+
+type SegmentService interface {
+	Create(ctx context.Context, req *entity.SegmentInsertUpdateRequest) (*entity.Segment, error)
+	FindAll(ctx context.Context) []entity.Segment
+	FindOne(ctx context.Context, ID string) (*entity.Segment, error)
+	Update(ctx context.Context, ID string, req *entity.SegmentInsertUpdateRequest) (*entity.Segment, error)
+	Delete(ctx context.Context, ID string) error
+	FindMembers(ctx context.Context, ID string, params *entity.CustomerQueryParams) (*[]entity.Customer, *entity.PageMeta, error)
+}
+
+type segmentService struct {
+	pool               *pgxpool.Pool
+	segmentRepository  repository.SegmentRepository
+	customerRepository repository.CustomerRepository
+	categoryRepository repository.CategoryRepository
+}
+
+func NewSegmentService(pool *pgxpool.Pool, segmentRepository repository.SegmentRepository, customerRepository repository.CustomerRepository, categoryRepository repository.CategoryRepository) SegmentService {
+	return &segmentService{
+		pool:               pool,
+		segmentRepository:  segmentRepository,
+		customerRepository: customerRepository,
+		categoryRepository: categoryRepository,
+	}
+}
+
+func (s *segmentService) Create(ctx context.Context, req *entity.SegmentInsertUpdateRequest) (*entity.Segment, error) {
+	if err := s.checkRules(ctx, &req.Rules); err != nil {
+		return nil, err
+	}
+
+	segment := &entity.Segment{
+		Name:        req.Name,
+		Description: req.Description,
+		Rules:       req.Rules,
+	}
+
+	if err := s.segmentRepository.Insert(ctx, s.pool, segment); err != nil {
+		return nil, exception.NewConflict("segment name already exist")
+	}
+
+	return segment, nil
+}
+
+func (s *segmentService) FindAll(ctx context.Context) []entity.Segment {
+	return s.segmentRepository.FindAll(ctx, s.pool)
+}
+
+func (s *segmentService) FindOne(ctx context.Context, ID string) (*entity.Segment, error) {
+	segment, err := s.segmentRepository.FindOne(ctx, s.pool, ID)
+	if err != nil {
+		return nil, exception.NewNotFound("segment id not found")
+	}
+
+	return segment, nil
+}
+
+func (s *segmentService) Update(ctx context.Context, ID string, req *entity.SegmentInsertUpdateRequest) (*entity.Segment, error) {
+	segment, err := s.FindOne(ctx, ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.checkRules(ctx, &req.Rules); err != nil {
+		return nil, err
+	}
+
+	segment.Name = req.Name
+	segment.Description = req.Description
+	segment.Rules = req.Rules
+
+	if err := s.segmentRepository.Update(ctx, s.pool, segment); err != nil {
+		return nil, exception.NewConflict("segment name already exist")
+	}
+
+	return segment, nil
+}
+
+func (s *segmentService) Delete(ctx context.Context, ID string) error {
+	if err := s.segmentRepository.Delete(ctx, s.pool, ID); err != nil {
+		return exception.NewNotFound("segment id not found")
+	}
+
+	return nil
+}
+
+// FindMembers lists the customers matching the rules of a segment now, the
+// members change as customers buy.
+func (s *segmentService) FindMembers(ctx context.Context, ID string, params *entity.CustomerQueryParams) (*[]entity.Customer, *entity.PageMeta, error) {
+	segment, err := s.FindOne(ctx, ID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	params.Segment = &segment.Rules
+
+	customers, meta, err := s.customerRepository.FindMany(ctx, s.pool, params)
+	if err != nil {
+		return nil, nil, pageError(err)
+	}
+
+	return customers, meta, nil
+}
+
+// checkRules refuses a segment without rules or with ranges that can not
+// match, and stores its tags the way customer tags are stored.
+func (s *segmentService) checkRules(ctx context.Context, rules *entity.SegmentRules) error {
+	if rules.MinSpend == nil && rules.MaxSpend == nil && rules.MinVisits == nil && rules.MaxVisits == nil &&
+		len(rules.Categories) == 0 && len(rules.OnlyCategories) == 0 && rules.InactiveDays == nil && len(rules.Tags) == 0 {
+		return exception.NewBadRequest("segment needs at least one rule")
+	}
+
+	if rules.MinSpend != nil && rules.MaxSpend != nil && *rules.MinSpend > *rules.MaxSpend {
+		return exception.NewBadRequest("minSpend can not be more than maxSpend")
+	}
+
+	if rules.MinVisits != nil && rules.MaxVisits != nil && *rules.MinVisits > *rules.MaxVisits {
+		return exception.NewBadRequest("minVisits can not be more than maxVisits")
+	}
+
+	for _, category := range slices.Concat(rules.Categories, rules.OnlyCategories) {
+		if !s.categoryRepository.IsExistByName(ctx, s.pool, category) {
+			return exception.NewNotFound("category " + category + " not found")
+		}
+	}
+
+	if len(rules.Tags) > 0 {
+		rules.Tags = customerTags(rules.Tags)
+	}
+
+	return nil
+}
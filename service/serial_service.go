@@ -0,0 +1,61 @@
+package service
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/malikfajr/eq-store/entity"
+	"github.com/malikfajr/eq-store/exception"
+	"github.com/malikfajr/eq-store/repository"
+)
+
+type SerialService interface {
+	FindBySerial(ctx context.Context, serial string) ([]entity.Serial, error)
+}
+
+type serialService struct {
+	pool             *pgxpool.Pool
+	serialRepository repository.SerialRepository
+}
+
+func NewSerialService(pool *pgxpool.Pool, serialRepository repository.SerialRepository) SerialService {
+	return &serialService{
+		pool:             pool,
+		serialRepository: serialRepository,
+	}
+}
+
+// FindBySerial returns every unit registered with serial, different products
+// may share a serial number.
+func (s *serialService) FindBySerial(ctx context.Context, serial string) ([]entity.Serial, error) {
+	serials := s.serialRepository.FindBySerial(ctx, s.pool, serial)
+	if len(serials) == 0 {
+		return nil, exception.NewNotFound("serial not found")
+	}
+
+	return serials, nil
+}
+
+// checkSerials requires one serial per unit of a serial tracked product, and
+// none for other products.
+func checkSerials(product *entity.Product, quantity entity.Quantity, serials []string) error {
+	if product.TrackSerials && (!quantity.IsWhole() || len(serials) != quantity.Whole()) {
+		return exception.NewBadRequest("one of product needs a serial for every unit")
+	}
+
+	if !product.TrackSerials && len(serials) > 0 {
+		return exception.NewBadRequest("one of product does not track serials")
+	}
+
+	return nil
+}
+
+func findProduct(products []entity.Product, ID string) *entity.Product {
+	for i := range products {
+		if products[i].Id == ID {
+			return &products[i]
+		}
+	}
+
+	return nil
+}
@@ -37,7 +37,22 @@ type StaffResponse struct {
 
 // Login implements iStaffService.
 func (i *staffService) Login(ctx context.Context, req *entity.StaffLoginRequest) (*StaffResponse, error) {
-	staff, err := i.staffRepository.Login(context.Background(), i.pool, req.PhoneNumber)
+	var staff *entity.Staff
+	phoneNumber, invalid := pkg.NormalizePhoneNumber(req.PhoneNumber)
+	err := invalid
+	if invalid == nil {
+		staff, err = i.staffRepository.Login(context.Background(), i.pool, phoneNumber)
+	}
+
+	// a number left as it is by a phone number conflict is matched as stored
+	if err != nil {
+		staff, err = i.staffRepository.LoginConflict(context.Background(), i.pool, req.PhoneNumber)
+	}
+
+	if err != nil && invalid != nil {
+		return nil, exception.NewBadRequest("request doesn’t pass validation")
+	}
+
 	if err != nil {
 		return nil, exception.NewNotFound("user is not found")
 	}
@@ -46,11 +61,11 @@ func (i *staffService) Login(ctx context.Context, req *entity.StaffLoginRequest)
 		return nil, exception.NewBadRequest("password is wrong")
 	}
 
-	token := pkg.CreateToken(staff.Id, staff.Name)
+	token := pkg.CreateToken(staff.Id, staff.Name, staff.IsAdmin)
 
 	data := &StaffResponse{
 		UserId:      staff.Id,
-		PhoneNumber: req.PhoneNumber,
+		PhoneNumber: staff.PhoneNumber,
 		Name:        staff.Name,
 		AccessToken: token,
 	}
@@ -60,6 +75,12 @@ func (i *staffService) Login(ctx context.Context, req *entity.StaffLoginRequest)
 
 // Register implements iStaffService.
 func (s *staffService) Register(ctx context.Context, req *entity.StaffRegisterRequest) (*StaffResponse, error) {
+	phoneNumber, err := pkg.NormalizePhoneNumber(req.PhoneNumber)
+	if err != nil {
+		return nil, exception.NewBadRequest("request doesn’t pass validation")
+	}
+	req.PhoneNumber = phoneNumber
+
 	hashPassword := pkg.HashPassword(req.Password)
 	req.Password = hashPassword
 
@@ -68,7 +89,8 @@ func (s *staffService) Register(ctx context.Context, req *entity.StaffRegisterRe
 		return nil, err
 	}
 
-	token := pkg.CreateToken(staffId, req.Name)
+	// new staff are never admins, promotion is done in the database
+	token := pkg.CreateToken(staffId, req.Name, false)
 
 	data := &StaffResponse{
 		UserId:      staffId,
@@ -80,6 +102,11 @@ func (s *staffService) Register(ctx context.Context, req *entity.StaffRegisterRe
 	return data, nil
 }
 
+// PhoneIsExist reports whether phoneNumber, in any format, belongs to a staff.
 func (s *staffService) PhoneIsExist(ctx context.Context, phoneNumber string) bool {
+	if normalized, err := pkg.NormalizePhoneNumber(phoneNumber); err == nil {
+		phoneNumber = normalized
+	}
+
 	return s.staffRepository.PhoneIsExist(ctx, s.pool, phoneNumber)
 }
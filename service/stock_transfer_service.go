@@ -0,0 +1,220 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/malikfajr/eq-store/entity"
+	"github.com/malikfajr/eq-store/exception"
+	"github.com/malikfajr/eq-store/repository"
+)
+
+type StockTransferService interface {
+	Send(ctx context.Context, staffId string, req *entity.StockTransferInsertRequest) (*entity.StockTransfer, error)
+	Receive(ctx context.Context, staffId string, ID string) (*entity.StockTransfer, error)
+	Cancel(ctx context.Context, staffId string, ID string) (*entity.StockTransfer, error)
+	FindOne(ctx context.Context, ID string) (*entity.StockTransfer, error)
+	FindMany(ctx context.Context, params *entity.StockTransferQueryParams) ([]entity.StockTransfer, *entity.PageMeta, error)
+}
+
+type stockTransferService struct {
+	pool                    *pgxpool.Pool
+	locationRepository      repository.LocationRepository
+	productRepository       repository.ProductRepository
+	stockRepository         repository.StockRepository
+	stockTransferRepository repository.StockTransferRepository
+	lotRepository           repository.LotRepository
+	serialRepository        repository.SerialRepository
+}
+
+func NewStockTransferService(pool *pgxpool.Pool, locationRepository repository.LocationRepository, productRepository repository.ProductRepository, stockRepository repository.StockRepository, stockTransferRepository repository.StockTransferRepository, lotRepository repository.LotRepository, serialRepository repository.SerialRepository) StockTransferService {
+	return &stockTransferService{
+		pool:                    pool,
+		locationRepository:      locationRepository,
+		productRepository:       productRepository,
+		stockRepository:         stockRepository,
+		stockTransferRepository: stockTransferRepository,
+		lotRepository:           lotRepository,
+		serialRepository:        serialRepository,
+	}
+}
+
+// Send takes the items out of the source location, they are counted nowhere
+// until the transfer is received or cancelled.
+func (s *stockTransferService) Send(ctx context.Context, staffId string, req *entity.StockTransferInsertRequest) (*entity.StockTransfer, error) {
+	if !s.locationRepository.IsExist(ctx, s.pool, req.FromLocationId) || !s.locationRepository.IsExist(ctx, s.pool, req.ToLocationId) {
+		return nil, exception.NewNotFound("location id not found")
+	}
+
+	productIds := []string{}
+	quantities := map[string]entity.Quantity{}
+	for _, item := range req.Items {
+		if _, ok := quantities[item.ProductId]; ok {
+			return nil, exception.NewBadRequest("productId is duplicated")
+		}
+		quantities[item.ProductId] = item.Quantity
+		productIds = append(productIds, item.ProductId)
+	}
+
+	products := s.productRepository.FindByIds(ctx, s.pool, productIds)
+	if len(*products) != len(productIds) {
+		return nil, exception.NewNotFound("one of productId not found")
+	}
+
+	for _, product := range *products {
+		if product.IsBundle {
+			return nil, exception.NewBadRequest("one of product is a bundle, transfer its components")
+		}
+
+		if product.HasVariants() {
+			return nil, exception.NewBadRequest("one of product has variants, use the variant id")
+		}
+	}
+
+	for _, item := range req.Items {
+		if err := checkQuantity(findProduct(*products, item.ProductId), item.Quantity); err != nil {
+			return nil, err
+		}
+
+		if err := checkSerials(findProduct(*products, item.ProductId), item.Quantity, item.Serials); err != nil {
+			return nil, err
+		}
+	}
+
+	transfer := &entity.StockTransfer{
+		FromLocationId: req.FromLocationId,
+		ToLocationId:   req.ToLocationId,
+		Notes:          req.Notes,
+		Items:          req.Items,
+		SentBy:         staffId,
+	}
+
+	err := runInTx(ctx, s.pool, func(tx pgx.Tx) error {
+		if err := s.stockTransferRepository.Create(ctx, tx, transfer); err != nil {
+			return err
+		}
+
+		if err := s.stockTransferRepository.InsertItems(ctx, tx, transfer.Id, transfer.Items); err != nil {
+			return err
+		}
+
+		for _, item := range transfer.Items {
+			allocations, err := takeStockTx(ctx, tx, s.stockRepository, s.lotRepository, item.ProductId, transfer.FromLocationId, item.Quantity)
+			if errors.Is(err, repository.ErrInsufficientStock) {
+				return exception.NewBadRequest("one of productIds stock is not enough")
+			}
+			if errors.Is(err, repository.ErrExpiredStock) {
+				return exception.NewBadRequest("one of productIds stock is expired")
+			}
+			if err != nil {
+				return err
+			}
+
+			// the lots travel with the items
+			if err := s.lotRepository.InsertTransferLotsTx(ctx, tx, transfer.Id, allocations); err != nil {
+				return err
+			}
+
+			if len(item.Serials) == 0 {
+				continue
+			}
+
+			err = s.serialRepository.SendTx(ctx, tx, item.ProductId, transfer.FromLocationId, transfer.Id, item.Serials)
+			if errors.Is(err, repository.ErrSerialUnavailable) {
+				return exception.NewBadRequest("one of serials is not in stock")
+			}
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return transfer, nil
+}
+
+func (s *stockTransferService) Receive(ctx context.Context, staffId string, ID string) (*entity.StockTransfer, error) {
+	return s.close(ctx, staffId, ID, entity.TransferReceived)
+}
+
+func (s *stockTransferService) Cancel(ctx context.Context, staffId string, ID string) (*entity.StockTransfer, error) {
+	return s.close(ctx, staffId, ID, entity.TransferCancelled)
+}
+
+// close moves in transit items into the destination when received, or back
+// into the source when cancelled.
+func (s *stockTransferService) close(ctx context.Context, staffId string, ID string, status string) (*entity.StockTransfer, error) {
+	var transfer *entity.StockTransfer
+
+	err := runInTx(ctx, s.pool, func(tx pgx.Tx) error {
+		var err error
+		transfer, err = s.stockTransferRepository.LockOneTx(ctx, tx, ID)
+		if err != nil {
+			return exception.NewNotFound("transfer id not found")
+		}
+
+		if transfer.Status != entity.TransferInTransit {
+			return exception.NewConflict("transfer is already " + transfer.Status)
+		}
+
+		locationId := transfer.ToLocationId
+		if status == entity.TransferCancelled {
+			locationId = transfer.FromLocationId
+		}
+
+		for _, item := range transfer.Items {
+			if err := s.stockRepository.IncrementTx(ctx, tx, item.ProductId, locationId, item.Quantity); err != nil {
+				return err
+			}
+		}
+
+		allocations, err := s.lotRepository.FindTransferLotsTx(ctx, tx, ID)
+		if err != nil {
+			return err
+		}
+
+		if status == entity.TransferCancelled {
+			err = s.lotRepository.RestoreTx(ctx, tx, allocations)
+		} else {
+			err = s.lotRepository.MoveTx(ctx, tx, allocations, locationId)
+		}
+		if err != nil {
+			return err
+		}
+
+		if err := s.serialRepository.CloseTransferTx(ctx, tx, ID, locationId); err != nil {
+			return err
+		}
+
+		return s.stockTransferRepository.UpdateStatusTx(ctx, tx, ID, status, staffId)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.stockTransferRepository.FindOne(ctx, s.pool, ID)
+}
+
+func (s *stockTransferService) FindOne(ctx context.Context, ID string) (*entity.StockTransfer, error) {
+	transfer, err := s.stockTransferRepository.FindOne(ctx, s.pool, ID)
+	if err != nil {
+		return nil, exception.NewNotFound("transfer id not found")
+	}
+
+	return transfer, nil
+}
+
+func (s *stockTransferService) FindMany(ctx context.Context, params *entity.StockTransferQueryParams) ([]entity.StockTransfer, *entity.PageMeta, error) {
+	transfers, meta, err := s.stockTransferRepository.FindMany(ctx, s.pool, params)
+	if err != nil {
+		return nil, nil, pageError(err)
+	}
+
+	return transfers, meta, nil
+}
@@ -0,0 +1,217 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/malikfajr/eq-store/entity"
+	"github.com/malikfajr/eq-store/exception"
+	"github.com/malikfajr/eq-store/repository"
+)
+
+type StoredValueService interface {
+	IssueGiftCard(ctx context.Context, staffId string, body *entity.GiftCardInsertRequest) (*entity.StoredValueAccount, error)
+	TopUpGiftCard(ctx context.Context, staffId string, code string, body *entity.StoredValueTopUpRequest) (*entity.StoredValueAccount, error)
+	FindGiftCard(ctx context.Context, code string, params *entity.MovementQueryParams) (*entity.StoredValueAccount, *entity.PageMeta, error)
+	AddCredit(ctx context.Context, staffId string, customerId string, body *entity.StoredValueTopUpRequest) (*entity.StoredValueAccount, error)
+	FindCredit(ctx context.Context, customerId string, params *entity.MovementQueryParams) (*entity.StoredValueAccount, *entity.PageMeta, error)
+}
+
+type storedValueService struct {
+	pool                  *pgxpool.Pool
+	customerRepository    repository.CustomerRepository
+	storedValueRepository repository.StoredValueRepository
+}
+
+func NewStoredValueService(pool *pgxpool.Pool, customerRepository repository.CustomerRepository, storedValueRepository repository.StoredValueRepository) StoredValueService {
+	return &storedValueService{
+		pool:                  pool,
+		customerRepository:    customerRepository,
+		storedValueRepository: storedValueRepository,
+	}
+}
+
+func (s *storedValueService) IssueGiftCard(ctx context.Context, staffId string, body *entity.GiftCardInsertRequest) (*entity.StoredValueAccount, error) {
+	code := giftCardCode(body.Code)
+	if code == "" {
+		var err error
+		if code, err = randomGiftCardCode(); err != nil {
+			return nil, err
+		}
+	}
+
+	if _, err := s.storedValueRepository.FindByCode(ctx, s.pool, code); err == nil {
+		return nil, exception.NewConflict("gift card code already exist")
+	}
+
+	account := &entity.StoredValueAccount{Type: entity.StoredValueGiftCard, Code: &code}
+
+	err := runInTx(ctx, s.pool, func(tx pgx.Tx) error {
+		if err := s.storedValueRepository.InsertTx(ctx, tx, account, staffId); err != nil {
+			return exception.NewConflict("gift card code already exist")
+		}
+
+		return s.moveTx(ctx, tx, account, entity.MovementIssue, staffId, body.Amount, body.Note)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return account, nil
+}
+
+func (s *storedValueService) TopUpGiftCard(ctx context.Context, staffId string, code string, body *entity.StoredValueTopUpRequest) (*entity.StoredValueAccount, error) {
+	var account *entity.StoredValueAccount
+
+	err := runInTx(ctx, s.pool, func(tx pgx.Tx) error {
+		var err error
+		account, err = s.storedValueRepository.LockByCodeTx(ctx, tx, giftCardCode(code))
+		if err != nil {
+			return exception.NewNotFound("gift card not found")
+		}
+
+		return s.moveTx(ctx, tx, account, entity.MovementTopUp, staffId, body.Amount, body.Note)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return account, nil
+}
+
+func (s *storedValueService) FindGiftCard(ctx context.Context, code string, params *entity.MovementQueryParams) (*entity.StoredValueAccount, *entity.PageMeta, error) {
+	account, err := s.storedValueRepository.FindByCode(ctx, s.pool, giftCardCode(code))
+	if err != nil {
+		return nil, nil, exception.NewNotFound("gift card not found")
+	}
+
+	return s.withMovements(ctx, account, params)
+}
+
+// AddCredit tops up the store credit of a customer, the first credit opens it.
+func (s *storedValueService) AddCredit(ctx context.Context, staffId string, customerId string, body *entity.StoredValueTopUpRequest) (*entity.StoredValueAccount, error) {
+	if _, err := s.customerRepository.FindOne(ctx, s.pool, customerId); err != nil {
+		return nil, exception.NewNotFound("customer not found")
+	}
+
+	movement := entity.MovementTopUp
+	if _, err := s.storedValueRepository.FindByCustomer(ctx, s.pool, customerId); err != nil {
+		movement = entity.MovementIssue
+	}
+
+	var account *entity.StoredValueAccount
+
+	err := runInTx(ctx, s.pool, func(tx pgx.Tx) error {
+		var err error
+		account, err = s.storedValueRepository.LockCreditTx(ctx, tx, customerId, staffId)
+		if err != nil {
+			return err
+		}
+
+		return s.moveTx(ctx, tx, account, movement, staffId, body.Amount, body.Note)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return account, nil
+}
+
+func (s *storedValueService) FindCredit(ctx context.Context, customerId string, params *entity.MovementQueryParams) (*entity.StoredValueAccount, *entity.PageMeta, error) {
+	if _, err := s.customerRepository.FindOne(ctx, s.pool, customerId); err != nil {
+		return nil, nil, exception.NewNotFound("customer not found")
+	}
+
+	account, err := s.storedValueRepository.FindByCustomer(ctx, s.pool, customerId)
+	if err != nil {
+		// a customer without credit has an empty balance
+		account = &entity.StoredValueAccount{Type: entity.StoredValueCredit, CustomerId: &customerId}
+		return account, &entity.PageMeta{Limit: params.Limit}, nil
+	}
+
+	return s.withMovements(ctx, account, params)
+}
+
+func (s *storedValueService) withMovements(ctx context.Context, account *entity.StoredValueAccount, params *entity.MovementQueryParams) (*entity.StoredValueAccount, *entity.PageMeta, error) {
+	movements, meta, err := s.storedValueRepository.FindMovements(ctx, s.pool, account.Id, params)
+	if err != nil {
+		return nil, nil, pageError(err)
+	}
+
+	account.Movements = movements
+	return account, meta, nil
+}
+
+func (s *storedValueService) moveTx(ctx context.Context, tx pgx.Tx, account *entity.StoredValueAccount, movementType string, staffId string, amount int, note string) error {
+	movement := &entity.StoredValueMovement{
+		Type:    movementType,
+		Amount:  amount,
+		StaffId: &staffId,
+		Note:    note,
+	}
+
+	return s.storedValueRepository.MoveTx(ctx, tx, account, movement)
+}
+
+// giftCardCode returns a code the way it is stored, codes are case insensitive.
+func giftCardCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// giftCardAlphabet leaves out letters and digits that are easily mistaken
+// for each other.
+const giftCardAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+
+func randomGiftCardCode() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	for i := range b {
+		b[i] = giftCardAlphabet[int(b[i])%len(giftCardAlphabet)]
+	}
+
+	return string(b), nil
+}
+
+// redeemStoredValueTx takes the stored value paying for a checkout out of the
+// gift cards and the customer's store credit.
+func redeemStoredValueTx(ctx context.Context, tx pgx.Tx, storedValueRepository repository.StoredValueRepository, transactionId string, payload *entity.TransactionInsertRequest) error {
+	for _, payment := range payload.GiftCards {
+		account, err := storedValueRepository.LockByCodeTx(ctx, tx, payment.Code)
+		if err != nil {
+			return exception.NewNotFound("gift card not found")
+		}
+
+		movement := &entity.StoredValueMovement{Type: entity.MovementRedeem, Amount: -payment.Amount, TransactionId: &transactionId}
+		err = storedValueRepository.MoveTx(ctx, tx, account, movement)
+		if errors.Is(err, repository.ErrInsufficientBalance) {
+			return exception.NewBadRequest("gift card balance is not enough")
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	if payload.StoreCredit == 0 {
+		return nil
+	}
+
+	account, err := storedValueRepository.LockCreditTx(ctx, tx, payload.CustomerId, "")
+	if err != nil {
+		return err
+	}
+
+	movement := &entity.StoredValueMovement{Type: entity.MovementRedeem, Amount: -payload.StoreCredit, TransactionId: &transactionId}
+	err = storedValueRepository.MoveTx(ctx, tx, account, movement)
+	if errors.Is(err, repository.ErrInsufficientBalance) {
+		return exception.NewBadRequest("store credit balance is not enough")
+	}
+
+	return err
+}
@@ -2,7 +2,12 @@ package service
 
 import (
 	"context"
+	"errors"
+	"slices"
+	"strings"
+	"time"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/malikfajr/eq-store/entity"
 	"github.com/malikfajr/eq-store/exception"
@@ -11,69 +16,388 @@ import (
 
 type TransactionService interface {
 	Create(ctx context.Context, payload *entity.TransactionInsertRequest) error
-	FindMany(ctx context.Context, params *entity.TransactionQueryParams) (*[]entity.Transaction, error)
+	FindMany(ctx context.Context, params *entity.TransactionQueryParams) (*[]entity.Transaction, *entity.PageMeta, error)
+	Refund(ctx context.Context, staffId string, ID string, toStoreCredit bool) error
 }
 
 type transactionService struct {
-	pool                  *pgxpool.Pool
-	customerRepository    repository.CustomerRepository
-	productRepository     repository.ProductRepository
-	transactionRepository repository.TransactionRepository
+	pool                    *pgxpool.Pool
+	customerRepository      repository.CustomerRepository
+	productRepository       repository.ProductRepository
+	transactionRepository   repository.TransactionRepository
+	locationRepository      repository.LocationRepository
+	stockRepository         repository.StockRepository
+	bundleRepository        repository.BundleRepository
+	lotRepository           repository.LotRepository
+	serialRepository        repository.SerialRepository
+	loyaltyRepository       repository.LoyaltyRepository
+	storedValueRepository   repository.StoredValueRepository
+	creditAccountRepository repository.CreditAccountRepository
 }
 
-func NewTransactionService(pool *pgxpool.Pool, customerRepository repository.CustomerRepository, productRepository repository.ProductRepository, transactionRepository repository.TransactionRepository) TransactionService {
+func NewTransactionService(pool *pgxpool.Pool, customerRepository repository.CustomerRepository, productRepository repository.ProductRepository, transactionRepository repository.TransactionRepository, locationRepository repository.LocationRepository, stockRepository repository.StockRepository, bundleRepository repository.BundleRepository, lotRepository repository.LotRepository, serialRepository repository.SerialRepository, loyaltyRepository repository.LoyaltyRepository, storedValueRepository repository.StoredValueRepository, creditAccountRepository repository.CreditAccountRepository) TransactionService {
 	return &transactionService{
-		pool:                  pool,
-		customerRepository:    customerRepository,
-		productRepository:     productRepository,
-		transactionRepository: transactionRepository,
+		pool:                    pool,
+		customerRepository:      customerRepository,
+		productRepository:       productRepository,
+		transactionRepository:   transactionRepository,
+		locationRepository:      locationRepository,
+		stockRepository:         stockRepository,
+		bundleRepository:        bundleRepository,
+		lotRepository:           lotRepository,
+		serialRepository:        serialRepository,
+		loyaltyRepository:       loyaltyRepository,
+		storedValueRepository:   storedValueRepository,
+		creditAccountRepository: creditAccountRepository,
 	}
 }
 
 func (t *transactionService) Create(ctx context.Context, payload *entity.TransactionInsertRequest) error {
-	if err := t.isValidPayload(ctx, payload); err != nil {
+	return runInTx(ctx, t.pool, func(tx pgx.Tx) error {
+		if err := t.isValidPayload(ctx, tx, payload); err != nil {
+			return err
+		}
+
+		id := t.transactionRepository.Create(ctx, tx, payload)
+		t.transactionRepository.InsertDetail(ctx, tx, id, payload.ProductDetails)
+
+		for _, pd := range payload.ProductDetails {
+			// a bundle is taken from stock through its components
+			lines := []entity.ProductDetail{pd}
+			if len(pd.Components) > 0 {
+				lines = pd.Components
+			}
+
+			for _, line := range lines {
+				allocations, err := takeStockTx(ctx, tx, t.stockRepository, t.lotRepository, line.ProductId, payload.LocationId, line.Quantity)
+				if errors.Is(err, repository.ErrInsufficientStock) {
+					return exception.NewBadRequest("one of productIds stock is not enough")
+				}
+				if errors.Is(err, repository.ErrExpiredStock) {
+					return exception.NewBadRequest("one of productIds stock is expired")
+				}
+				if err != nil {
+					return err
+				}
+
+				if err := t.lotRepository.InsertTransactionLotsTx(ctx, tx, id, allocations); err != nil {
+					return err
+				}
+
+				if len(line.Serials) == 0 {
+					continue
+				}
+
+				err = t.serialRepository.SellTx(ctx, tx, line.ProductId, payload.LocationId, id, line.Serials)
+				if errors.Is(err, repository.ErrSerialUnavailable) {
+					return exception.NewBadRequest("one of serials is not in stock")
+				}
+				if err != nil {
+					return err
+				}
+			}
+		}
+
+		if err := redeemStoredValueTx(ctx, tx, t.storedValueRepository, id, payload); err != nil {
+			return err
+		}
+
+		if err := chargeAccountTx(ctx, tx, t.creditAccountRepository, id, payload); err != nil {
+			return err
+		}
+
+		return t.pointsTx(ctx, tx, id, payload)
+	})
+}
+
+// pointsTx takes the redeemed points out of the customer's balance and
+// credits the points the checkout earned.
+func (t *transactionService) pointsTx(ctx context.Context, tx pgx.Tx, transactionId string, payload *entity.TransactionInsertRequest) error {
+	if payload.RedeemPoints == 0 && payload.PointsEarned == 0 {
+		return nil
+	}
+
+	if err := t.loyaltyRepository.LockCustomerTx(ctx, tx, payload.CustomerId); err != nil {
 		return err
 	}
 
-	tx, err := t.pool.Begin(ctx)
+	if payload.RedeemPoints > 0 {
+		entries, err := t.loyaltyRepository.FindEntriesTx(ctx, tx, payload.CustomerId)
+		if err != nil {
+			return err
+		}
+
+		if pointBalance(usableLots(pointLots(entries), time.Now())) < payload.RedeemPoints {
+			return exception.NewBadRequest("points balance is not enough")
+		}
+
+		redeem := &entity.PointEntry{
+			CustomerId:    payload.CustomerId,
+			TransactionId: &transactionId,
+			Type:          entity.PointsRedeem,
+			Points:        -payload.RedeemPoints,
+		}
+
+		if err := t.loyaltyRepository.InsertTx(ctx, tx, redeem); err != nil {
+			return err
+		}
+	}
+
+	if payload.PointsEarned == 0 {
+		return nil
+	}
+
+	settings, err := t.loyaltyRepository.FindSettingsTx(ctx, tx)
 	if err != nil {
-		panic(err)
+		return err
 	}
-	defer func() {
-		err := recover()
+
+	earn := &entity.PointEntry{
+		CustomerId:    payload.CustomerId,
+		TransactionId: &transactionId,
+		Type:          entity.PointsEarn,
+		Points:        payload.PointsEarned,
+		ExpiresAt:     pointsExpiry(settings, time.Now()),
+	}
+
+	return t.loyaltyRepository.InsertTx(ctx, tx, earn)
+}
+
+// Refund takes a whole transaction back: what it took from stock, lots and
+// serials is returned to its location, its points are reversed, stored
+// value is paid back and what was charged to account is taken off it. With
+// toStoreCredit what was paid in cash goes to the customer's store credit.
+func (t *transactionService) Refund(ctx context.Context, staffId string, ID string, toStoreCredit bool) error {
+	return runInTx(ctx, t.pool, func(tx pgx.Tx) error {
+		transaction, err := t.transactionRepository.LockOneTx(ctx, tx, ID)
 		if err != nil {
-			e := tx.Rollback(ctx)
-			if e != nil {
-				panic(e)
+			return exception.NewNotFound("transaction id not found")
+		}
+
+		if transaction.RefundedAt != nil {
+			return exception.NewConflict("transaction is already refunded")
+		}
+
+		// transactions made before locations were sold from the default one
+		locationId := transaction.LocationId
+		if locationId == "" {
+			location, err := t.locationRepository.FindDefault(ctx, t.pool)
+			if err != nil {
+				return exception.NewInternalServer(err.Error())
 			}
-		} else {
-			e := tx.Commit(ctx)
-			if e != nil {
-				panic(e)
+			locationId = location.Id
+		}
+
+		lines, err := t.transactionRepository.FindStockLinesTx(ctx, tx, ID)
+		if err != nil {
+			return err
+		}
+
+		for _, line := range lines {
+			if err := t.stockRepository.IncrementTx(ctx, tx, line.ProductId, locationId, line.Quantity); err != nil {
+				return err
 			}
 		}
-	}()
 
-	id := t.transactionRepository.Create(ctx, tx, payload)
-	t.transactionRepository.InsertDetail(ctx, tx, id, payload.ProductDetails)
-	t.transactionRepository.DecrementStock(ctx, tx, payload.ProductDetails)
+		allocations, err := t.lotRepository.FindTransactionLotsTx(ctx, tx, ID)
+		if err != nil {
+			return err
+		}
+
+		if err := t.lotRepository.RestoreTx(ctx, tx, allocations); err != nil {
+			return err
+		}
+
+		if err := t.serialRepository.ReturnTx(ctx, tx, ID, locationId); err != nil {
+			return err
+		}
+
+		if err := t.reversePointsTx(ctx, tx, transaction); err != nil {
+			return err
+		}
+
+		if err := t.refundStoredValueTx(ctx, tx, staffId, transaction, toStoreCredit); err != nil {
+			return err
+		}
+
+		if err := t.refundAccountTx(ctx, tx, staffId, transaction); err != nil {
+			return err
+		}
+
+		return t.transactionRepository.RefundTx(ctx, tx, ID, staffId)
+	})
+}
+
+// reversePointsTx takes back the points a transaction earned and gives back
+// the points redeemed in it. Earned points the customer already spent can not
+// be taken back, the balance never goes below zero.
+func (t *transactionService) reversePointsTx(ctx context.Context, tx pgx.Tx, transaction *entity.Transaction) error {
+	points, err := t.loyaltyRepository.FindByTransactionTx(ctx, tx, transaction.Id)
+	if err != nil {
+		return err
+	}
+
+	if len(points) == 0 {
+		return nil
+	}
+
+	if err := t.loyaltyRepository.LockCustomerTx(ctx, tx, transaction.CustomerId); err != nil {
+		return err
+	}
+
+	entries, err := t.loyaltyRepository.FindEntriesTx(ctx, tx, transaction.CustomerId)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	lots := pointLots(entries)
+	settings, err := t.loyaltyRepository.FindSettingsTx(ctx, tx)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range points {
+		reverse := &entity.PointEntry{
+			CustomerId:    transaction.CustomerId,
+			TransactionId: &transaction.Id,
+			Type:          entity.PointsReverse,
+			SourceId:      &entry.Id,
+		}
+
+		switch entry.Type {
+		case entity.PointsEarn:
+			available := pointBalance(usableLots(lots, now))
+			for _, lot := range lots {
+				if lot.EntryId == entry.Id && lot.Expired(now) {
+					available += lot.Points
+				}
+			}
+
+			reverse.Points = -min(entry.Points, available)
+		case entity.PointsRedeem:
+			reverse.Points = -entry.Points
+			reverse.ExpiresAt = pointsExpiry(settings, now)
+		default:
+			continue
+		}
+
+		if reverse.Points == 0 {
+			continue
+		}
+
+		if err := t.loyaltyRepository.InsertTx(ctx, tx, reverse); err != nil {
+			return err
+		}
+	}
 
 	return nil
 }
 
-func (t *transactionService) FindMany(ctx context.Context, params *entity.TransactionQueryParams) (*[]entity.Transaction, error) {
-	transactions := t.transactionRepository.FindMany(ctx, t.pool, params)
-	return &transactions, nil
+func (t *transactionService) refundStoredValueTx(ctx context.Context, tx pgx.Tx, staffId string, transaction *entity.Transaction, toStoreCredit bool) error {
+	movements, err := t.storedValueRepository.FindByTransactionTx(ctx, tx, transaction.Id)
+	if err != nil {
+		return err
+	}
+
+	for _, redeemed := range movements {
+		if redeemed.Type != entity.MovementRedeem {
+			continue
+		}
+
+		account, err := t.storedValueRepository.LockOneTx(ctx, tx, redeemed.AccountId)
+		if err != nil {
+			return err
+		}
+
+		movement := &entity.StoredValueMovement{Type: entity.MovementRefund, Amount: -redeemed.Amount, TransactionId: &transaction.Id, StaffId: &staffId}
+		if err := t.storedValueRepository.MoveTx(ctx, tx, account, movement); err != nil {
+			return err
+		}
+	}
+
+	cash := transaction.Paid - transaction.Change
+	if !toStoreCredit || cash <= 0 {
+		return nil
+	}
+
+	account, err := t.storedValueRepository.LockCreditTx(ctx, tx, transaction.CustomerId, staffId)
+	if err != nil {
+		return err
+	}
+
+	movement := &entity.StoredValueMovement{Type: entity.MovementRefund, Amount: cash, TransactionId: &transaction.Id, StaffId: &staffId}
+
+	return t.storedValueRepository.MoveTx(ctx, tx, account, movement)
 }
 
-func (t *transactionService) isValidPayload(ctx context.Context, payload *entity.TransactionInsertRequest) error {
+// refundAccountTx takes the charge of a transaction off the customer's credit
+// account. What the customer already paid of it is given back as store
+// credit, the balance never goes below zero.
+func (t *transactionService) refundAccountTx(ctx context.Context, tx pgx.Tx, staffId string, transaction *entity.Transaction) error {
+	if transaction.AccountCharged == 0 {
+		return nil
+	}
+
+	account, err := t.creditAccountRepository.LockOneTx(ctx, tx, transaction.CustomerId)
+	if err != nil {
+		return err
+	}
+
+	refund := min(transaction.AccountCharged, account.Balance)
+	if refund > 0 {
+		entry := &entity.AccountEntry{Type: entity.AccountRefund, Amount: -refund, TransactionId: &transaction.Id, StaffId: &staffId}
+		if err := t.creditAccountRepository.MoveTx(ctx, tx, account, entry); err != nil {
+			return err
+		}
+	}
+
+	paid := transaction.AccountCharged - refund
+	if paid == 0 {
+		return nil
+	}
+
+	credit, err := t.storedValueRepository.LockCreditTx(ctx, tx, transaction.CustomerId, staffId)
+	if err != nil {
+		return err
+	}
+
+	movement := &entity.StoredValueMovement{Type: entity.MovementRefund, Amount: paid, TransactionId: &transaction.Id, StaffId: &staffId}
+
+	return t.storedValueRepository.MoveTx(ctx, tx, credit, movement)
+}
+
+func (t *transactionService) FindMany(ctx context.Context, params *entity.TransactionQueryParams) (*[]entity.Transaction, *entity.PageMeta, error) {
+	transactions, meta, err := t.transactionRepository.FindMany(ctx, t.pool, params)
+	if err != nil {
+		return nil, nil, pageError(err)
+	}
+
+	return &transactions, meta, nil
+}
+
+// isValidPayload checks a checkout and fills in its prices and points. It runs
+// in the checkout transaction, which keeps the loyalty settings it priced
+// with until the points are booked.
+func (t *transactionService) isValidPayload(ctx context.Context, tx pgx.Tx, payload *entity.TransactionInsertRequest) error {
 	// 0. customer id exists - 404
 	if exists := t.customerRepository.IsExist(ctx, t.pool, payload.CustomerId); !exists {
 		return exception.NewNotFound("Customer id not found")
 	}
 
+	// checkout takes stock from the terminal location, or the default one
+	if payload.LocationId == "" {
+		location, err := t.locationRepository.FindDefault(ctx, t.pool)
+		if err != nil {
+			return exception.NewInternalServer(err.Error())
+		}
+		payload.LocationId = location.Id
+	} else if exists := t.locationRepository.IsExist(ctx, t.pool, payload.LocationId); !exists {
+		return exception.NewNotFound("location id not found")
+	}
+
 	// 1. product id exists - 404
-	var productDetails map[string]int = map[string]int{}
+	var productDetails map[string]entity.Quantity = map[string]entity.Quantity{}
 	productIds := []string{}
 
 	for _, product := range payload.ProductDetails {
@@ -86,28 +410,219 @@ func (t *transactionService) isValidPayload(ctx context.Context, payload *entity
 		return exception.NewNotFound("one of productId not found")
 	}
 
+	bundleIds := []string{}
+	for _, product := range *products {
+		if product.IsBundle {
+			bundleIds = append(bundleIds, product.Id)
+		}
+	}
+
+	components := map[string][]entity.BundleComponent{}
+	if len(bundleIds) > 0 {
+		components = t.bundleRepository.FindComponents(ctx, t.pool, bundleIds)
+
+		componentIds := map[string]bool{}
+		for _, bundleComponents := range components {
+			for _, component := range bundleComponents {
+				componentIds[component.ProductId] = true
+			}
+		}
+
+		ids := []string{}
+		for id := range componentIds {
+			ids = append(ids, id)
+		}
+
+		// a component deleted after the bundle was made can not be sold
+		found := t.productRepository.FindByIds(ctx, t.pool, ids)
+		if len(*found) != len(ids) {
+			return exception.NewBadRequest("one of product not available")
+		}
+
+		for _, component := range *found {
+			if component.TrackSerials {
+				return exception.NewBadRequest("one of bundle components needs serials, sell it separately")
+			}
+		}
+	}
+
 	// 2. paid is enought - 400
 	totalPrice := 0
+	prices := map[string]int{}
+	quantities := map[string]entity.Quantity{} // taken from stock, bundles take their components
 
 	for _, product := range *products {
 		if product.IsAvailable == false { // 5. one of product isAvailable false - 400
 			return exception.NewBadRequest("one of product not available")
 		}
 
-		if product.Stock < productDetails[product.Id] { // 4. product stock is enought - 400
+		if product.HasVariants() { // parent products are sold through their variant ids
+			return exception.NewBadRequest("one of product has variants, use the variant id")
+		}
+
+		if product.IsBundle {
+			if len(components[product.Id]) == 0 {
+				return exception.NewBadRequest("one of product not available")
+			}
+
+			for _, component := range components[product.Id] {
+				quantities[component.ProductId] += component.Quantity.Mul(productDetails[product.Id])
+			}
+		} else {
+			quantities[product.Id] += productDetails[product.Id]
+		}
+
+		// every line is rounded once, the total is the sum of the rounded lines
+		totalPrice += productDetails[product.Id].Amount(product.Price)
+		prices[product.Id] = product.Price
+	}
+
+	for _, pd := range payload.ProductDetails {
+		if err := checkQuantity(findProduct(*products, pd.ProductId), pd.Quantity); err != nil {
+			return err
+		}
+
+		if err := checkSerials(findProduct(*products, pd.ProductId), pd.Quantity, pd.Serials); err != nil {
+			return err
+		}
+	}
+
+	stockIds := []string{}
+	for id := range quantities {
+		stockIds = append(stockIds, id)
+	}
+
+	stocks := t.stockRepository.FindAtLocation(ctx, t.pool, payload.LocationId, stockIds)
+	expired := t.lotRepository.ExpiredAt(ctx, t.pool, payload.LocationId, stockIds)
+	for id, quantity := range quantities {
+		if stocks[id] < quantity { // 4. product stock is enought - 400
 			return exception.NewBadRequest("one of productIds stock is not enough")
 		}
-		totalPrice += (product.Price * productDetails[product.Id])
+
+		if stocks[id]-expired[id] < quantity { // expired lots are not sold
+			return exception.NewBadRequest("one of productIds stock is expired")
+		}
+	}
+
+	// lines keep the price they were sold for, bundle lines list what they took
+	for i := range payload.ProductDetails {
+		pd := &payload.ProductDetails[i]
+		pd.Price = prices[pd.ProductId]
+		pd.TotalPrice = pd.Quantity.Amount(pd.Price)
+		pd.Components = nil
+
+		for _, component := range components[pd.ProductId] {
+			pd.Components = append(pd.Components, entity.ProductDetail{
+				ProductId: component.ProductId,
+				Quantity:  component.Quantity.Mul(pd.Quantity),
+			})
+		}
+	}
+
+	// loyalty points pay part of the total, the lines earn on what is left
+	settings, err := t.loyaltyRepository.FindSettingsTx(ctx, tx)
+	if err != nil {
+		return err
+	}
+
+	if payload.RedeemPoints > 0 {
+		if settings.PointValue == 0 {
+			return exception.NewBadRequest("points can not be redeemed")
+		}
+
+		payload.PointsDiscount = payload.RedeemPoints * settings.PointValue
+		if payload.PointsDiscount > totalPrice {
+			return exception.NewBadRequest("redeemed points are worth more than the total")
+		}
+	}
+
+	payload.PointsEarned = earnedPoints(settings, *products, payload.ProductDetails, totalPrice, payload.PointsDiscount)
+	due := totalPrice - payload.PointsDiscount
+
+	if err := t.checkStoredValue(ctx, payload); err != nil {
+		return err
+	}
+
+	if payload.StoredValuePaid > due {
+		return exception.NewBadRequest("stored value pays more than the total")
+	}
+	due -= payload.StoredValuePaid
+
+	// a customer with a credit account can leave what is not paid on it
+	if payload.ChargeToAccount && due > payload.Paid {
+		payload.AccountCharged = due - payload.Paid
+		if err := t.checkAccount(ctx, payload); err != nil {
+			return err
+		}
+		due = payload.Paid
 	}
 
-	if totalPrice > payload.Paid {
+	if due > payload.Paid {
 		return exception.NewBadRequest("paid is not enough based on all bought product")
 	}
 
 	// 3. change is right - 400
-	if change := payload.Paid - totalPrice; change != *payload.Change {
+	if change := payload.Paid - due; change != *payload.Change {
 		return exception.NewBadRequest("change is not right")
 	}
 
 	return nil
 }
+
+// checkStoredValue checks the gift cards paying for a checkout exist and hold
+// enough, the balances are taken in the checkout transaction.
+func (t *transactionService) checkStoredValue(ctx context.Context, payload *entity.TransactionInsertRequest) error {
+	payload.StoredValuePaid = payload.StoreCredit
+
+	for i := range payload.GiftCards {
+		payload.GiftCards[i].Code = giftCardCode(payload.GiftCards[i].Code)
+	}
+
+	// cards are locked in code order so checkouts sharing cards do not deadlock
+	slices.SortFunc(payload.GiftCards, func(a, b entity.StoredValuePayment) int {
+		return strings.Compare(a.Code, b.Code)
+	})
+
+	for i, payment := range payload.GiftCards {
+		if i > 0 && payload.GiftCards[i-1].Code == payment.Code {
+			return exception.NewBadRequest("gift card codes must be unique")
+		}
+
+		account, err := t.storedValueRepository.FindByCode(ctx, t.pool, payment.Code)
+		if err != nil {
+			return exception.NewNotFound("gift card not found")
+		}
+
+		if account.Balance < payment.Amount {
+			return exception.NewBadRequest("gift card balance is not enough")
+		}
+
+		payload.StoredValuePaid += payment.Amount
+	}
+
+	if payload.StoreCredit == 0 {
+		return nil
+	}
+
+	account, err := t.storedValueRepository.FindByCustomer(ctx, t.pool, payload.CustomerId)
+	if err != nil || account.Balance < payload.StoreCredit {
+		return exception.NewBadRequest("store credit balance is not enough")
+	}
+
+	return nil
+}
+
+// checkAccount checks the customer has a credit account with room for the
+// charge, the balance is charged in the checkout transaction.
+func (t *transactionService) checkAccount(ctx context.Context, payload *entity.TransactionInsertRequest) error {
+	account, err := t.creditAccountRepository.FindOne(ctx, t.pool, payload.CustomerId)
+	if err != nil {
+		return exception.NewBadRequest("customer has no credit account")
+	}
+
+	if payload.AccountCharged > availableCredit(account) {
+		return exception.NewBadRequest("charge is over the credit limit")
+	}
+
+	return nil
+}
@@ -0,0 +1,80 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// runInTx runs fn inside a database transaction. The transaction is committed
+// when fn returns nil, and rolled back when fn returns an error or panics.
+func runInTx(ctx context.Context, pool *pgxpool.Pool, fn func(tx pgx.Tx) error) error {
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		panic(err)
+	}
+	defer func() {
+		if err := recover(); err != nil {
+			tx.Rollback(ctx)
+			panic(err)
+		}
+	}()
+
+	if err := fn(tx); err != nil {
+		if e := tx.Rollback(ctx); e != nil {
+			panic(e)
+		}
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		panic(err)
+	}
+
+	return nil
+}
+
+// isUniqueViolation reports whether err is a unique constraint violation.
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505"
+}
+
+// isForeignKeyViolation reports whether err is a foreign key violation, a row
+// referred to was removed in the meantime.
+func isForeignKeyViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23503"
+}
+
+// runExclusive runs fn while holding the session advisory lock key, so a
+// background job runs on one server at a time. When another server holds the
+// lock fn is skipped and ran is false.
+func runExclusive(ctx context.Context, pool *pgxpool.Pool, key int64, fn func() error) (ran bool, err error) {
+	conn, err := pool.Acquire(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer conn.Release()
+
+	var locked bool
+	if err := conn.QueryRow(ctx, "SELECT pg_try_advisory_lock($1)", key).Scan(&locked); err != nil {
+		return false, err
+	}
+
+	if !locked {
+		return false, nil
+	}
+
+	defer func() {
+		// a connection still holding the lock must not go back to the pool
+		if _, err := conn.Exec(context.Background(), "SELECT pg_advisory_unlock($1)", key); err != nil {
+			conn.Conn().Close(context.Background())
+		}
+	}()
+
+	return true, fn()
+}